@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"os/user"
+	"strings"
+
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/database"
+	"interactive-scraper/internal/service"
+)
+
+/*Bu fonksiyon, komut satırı alt komutları için veritabanına bağlanır. Sunucunun aksine
+bekleyen migration'lar otomatik uygulanmaz; şema bu build'in beklediği sürümde değilse
+komut çalışmadan durur ve "migrate up" çalıştırılması istenir.
+*/
+func openDB(cfg *config.Config) *sql.DB {
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := database.CheckSchemaVersion(db); err != nil {
+		log.Fatal(err)
+	}
+	current, err := database.SchemaVersion(db)
+	if err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+	if latest := database.LatestVersion(); current < latest {
+		log.Fatalf("database is at schema version %d but this build needs %d; run 'migrate up' first", current, latest)
+	}
+	return db
+}
+
+// recordCLI writes an audit entry for a change made from the command line, noting the OS account that ran it
+func recordCLI(recorder *audit.Recorder, action, targetType, targetID string, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	if u, err := user.Current(); err == nil {
+		details["os_user"] = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		details["host"] = host
+	}
+	recorder.Record(audit.Entry{
+		Actor:      audit.ActorCLI,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    audit.Snapshot(details),
+		Success:    true,
+	})
+}
+
+// readPasswordLine reads a password from the first line of stdin, so it never appears in shell history or ps output
+func readPasswordLine() string {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Fatalf("Failed to read password from stdin: %v", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		log.Fatal("no password given on stdin")
+	}
+	return password
+}
+
+// generatedPasswordAlphabet covers every class a password policy can require
+const generatedPasswordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789!#%+-.:=?@_"
+
+// generatePassword returns a random password that satisfies the policy for username
+func generatePassword(policy service.PasswordPolicy, username string) string {
+	length := policy.MinLength
+	if length < 20 {
+		length = 20
+	}
+	max := big.NewInt(int64(len(generatedPasswordAlphabet)))
+	for {
+		b := make([]byte, length)
+		for i := range b {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				log.Fatalf("Failed to generate password: %v", err)
+			}
+			b[i] = generatedPasswordAlphabet[n.Int64()]
+		}
+		if policy.Validate(username, string(b)) == nil {
+			return string(b)
+		}
+	}
+}
+
+// openOutput returns stdout for "" or "-", otherwise creates the file
+func openOutput(path string) io.WriteCloser {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", path, err)
+	}
+	return f
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func fatalUsage(usage string) {
+	fmt.Fprintln(os.Stderr, usage)
+	os.Exit(2)
+}
@@ -2,12 +2,17 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
-	"os"
+	"net/url"
 	"time"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/tracing"
 )
 
 /*Bu kod parçası, uygulama içerisinde yapay zekâ (AI) servisleriyle iletişimi yönetecek olan bir
@@ -51,38 +56,46 @@ type AnalysisResponse struct {
 	Error    string `json:"error,omitempty"`
 }
 
-/*Bu fonksiyon, AIService yapısını başlatmak ve yapılandırmak için kullanılır. Öncelikle
-ortam değişkenlerinden (AI_SERVICE_URL) yapay zekâ servisinin adresi alınır; eğer bu
-adres tanımlı değilse AI servisi devre dışı bırakılır (enabled: false). Uygulama Docker
-içinde çalışıyorsa ve servis adresi localhost olarak verilmişse, container’ın ana makinedeki
-AI servisine erişebilmesi için adres otomatik olarak host.docker.internal şeklinde
-güncellenir. Son olarak, 30 saniyelik zaman aşımına sahip bir HTTP istemcisi oluşturularak
-AI servisi aktif (enabled: true) olacak şekilde AIService nesnesi döndürülür.
+/*Bu fonksiyon, AIService yapısını başlatmak ve yapılandırmak için kullanılır. Yapay zekâ
+servisinin adresi ai.service_url ayarından (AI_SERVICE_URL) alınır; eğer bu adres tanımlı
+değilse AI servisi devre dışı bırakılır (enabled: false). Uygulama Docker içinde çalışıyorsa
+ve servis adresi localhost olarak verilmişse, container’ın ana makinedeki AI servisine
+erişebilmesi için adres otomatik olarak host.docker.internal şeklinde güncellenir. Son
+olarak, ai.timeout (varsayılan 30 saniye) zaman aşımına sahip bir HTTP istemcisi
+oluşturularak AI servisi aktif (enabled: true) olacak şekilde AIService nesnesi döndürülür.
 */
-func NewAIService() *AIService {
-	baseURL := os.Getenv("AI_SERVICE_URL")
-	if baseURL == "" {
+func NewAIService(cfg config.AIConfig) *AIService {
+	if cfg.ServiceURL == "" {
 		return &AIService{
 			enabled: false,
 		}
 	}
 
-	
-	if baseURL == "http://localhost:11434" || baseURL == "http://127.0.0.1:11434" {
-		if os.Getenv("DB_HOST") != "" {
-			baseURL = "http://host.docker.internal:11434"
-		}
-	}
-
 	return &AIService{
-		baseURL: baseURL,
+		baseURL: dockerHostURL(cfg.ServiceURL, cfg.DockerHost),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second, 
+			Timeout: cfg.Timeout.Duration,
 		},
 		enabled: true,
 	}
 }
 
+// dockerHostURL points a localhost AI address at ai.docker_host, e.g. host.docker.internal under compose
+func dockerHostURL(baseURL, dockerHost string) string {
+	if dockerHost == "" {
+		return baseURL
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1") {
+		return baseURL
+	}
+	u.Host = dockerHost
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(dockerHost, port)
+	}
+	return u.String()
+}
+
 /*Bu fonksiyon, yapay zekâ servisinin aktif olup olmadığını kontrol etmek için kullanılır.
 AIService yapısındaki enabled alanını döndürerek, uygulamanın AI servisine istek
 gönderip göndermeyeceğine karar vermesini sağlar.
@@ -91,6 +104,23 @@ func (s *AIService) IsEnabled() bool {
 	return s.enabled
 }
 
+// Ping checks that the AI service answers HTTP at its base URL; any status below 500 counts
+func (s *AIService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("AI service unreachable: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("AI service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 /*Bu fonksiyon, verilen başlık, içerik, kategori ve kritik seviye bilgilerini kullanarak yapay zekâ
 servisine analiz isteği göndermek için tasarlanmıştır. Öncelikle AI servisinin aktif olup
 olmadığı kontrol edilir; eğer servis kapalıysa sistemin normal akışını bozmamak için hata
@@ -101,11 +131,30 @@ cevap AnalysisResponse yapısına ayrıştırılır ve varsa hata mesajları kon
 olarak, AI tarafından üretilen analiz metni elde edilerek çağıran fonksiyona döndürülür;
 analiz üretilmemişse bu durum hara olarak değerlendirilmez
 */
-func (s *AIService) AnalyzeEntry(title, content, category string, criticalityScore int) (string, error) {
+func (s *AIService) AnalyzeEntry(ctx context.Context, title, content, category string, criticalityScore int) (string, error) {
 	if !s.enabled {
 		return "", nil 
 	}
 
+	ctx, span := tracing.Start(ctx, "ai.analyze", tracing.KindClient,
+		tracing.String("http.request.method", "POST"), tracing.String("url.full", s.baseURL+"/analyze"),
+		tracing.String("entry.category", category))
+	defer span.End()
+
+	started := time.Now()
+	analysis, err := s.analyzeEntry(ctx, title, content, category, criticalityScore)
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		analysisFailuresTotal.Inc()
+	}
+	analysisDuration.Observe(time.Since(started).Seconds(), outcome)
+	span.SetAttributes(tracing.Int("ai.analysis_bytes", len(analysis)))
+	span.RecordError(err)
+	return analysis, err
+}
+
+func (s *AIService) analyzeEntry(ctx context.Context, title, content, category string, criticalityScore int) (string, error) {
 	req := AnalysisRequest{
 		Title:            title,
 		Content:          content,
@@ -119,18 +168,20 @@ func (s *AIService) AnalyzeEntry(title, content, category string, criticalitySco
 	}
 
 	
-	httpReq, err := http.NewRequest("POST", s.baseURL+"/analyze", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/analyze", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, httpReq.Header)
 
 	resp, err := s.httpClient.Do(httpReq)
 	if err != nil {
 		return "", fmt.Errorf("AI service request failed: %v", err)
 	}
 	defer resp.Body.Close()
+	tracing.FromContext(ctx).SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
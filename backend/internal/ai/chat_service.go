@@ -2,16 +2,20 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"os"
 	"strings"
-	"time"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/tracing"
 )
 
+var logger = logging.For("ai")
+
 /* Bu yapı, sohbet tabanlı bir yapay zeka modeliyle iletişimi yöneten servis katmanını temsil
 eser. ChatService struct'ı; sohbet isteklerinin gönderileceği servis adresini (baseURL),
 kullanılacak yapay zeka modelini, HTTP isteklerini yöneten istemciyi (httpClient)
@@ -67,39 +71,26 @@ type OllamaGenerateResponse struct {
 	Error    string `json:"error,omitempty"`
 }
 
-/*Bu fonksiyon, ChatService yapısını başlatmak ve yapılandırmak için kullanılır. Öncelikle
-ortam değişkenlerinden (AI_SERVICE_URL) sohbet servisinin adresi ve (AI_MODEL)
-kullanılacak yapay zekâ modeli alınır; eğer tanımlı değillerse varsayılan olarak
-http://localhost:11434 adresi ve mistral modeli kullanılır. Uygulama Docker içinde
+/*Bu fonksiyon, ChatService yapısını başlatmak ve yapılandırmak için kullanılır. Sohbet
+servisinin adresi ai.service_url (AI_SERVICE_URL), kullanılacak yapay zekâ modeli ise
+ai.model (AI_MODEL) ayarından alınır; adres tanımlı değilse varsayılan olarak
+http://localhost:11434 adresi kullanılır. Uygulama Docker içinde
 çalışıyorsa ve servis adresi localhost ise, container’ın ana makinedeki servise erişebilmesi
-için adres otomatik olarak host.docker.internal şeklinde güncellenir. Son olarak, 30
-saniyelik zaman aşımına sahip bir HTTP istemcisi oluşturularak ChatService aktif
+için adres otomatik olarak host.docker.internal şeklinde güncellenir. Son olarak,
+ai.timeout zaman aşımına sahip bir HTTP istemcisi oluşturularak ChatService aktif
 (enabled: true) şekilde döndürülür.
 */
-func NewChatService() *ChatService {
-	baseURL := os.Getenv("AI_SERVICE_URL")
+func NewChatService(cfg config.AIConfig) *ChatService {
+	baseURL := cfg.ServiceURL
 	if baseURL == "" {
 		baseURL = "http://localhost:11434"
 	}
 
-	model := os.Getenv("AI_MODEL")
-	if model == "" {
-		model = "mistral"
-	}
-
-	
-	if baseURL == "http://localhost:11434" || baseURL == "http://127.0.0.1:11434" {
-		if os.Getenv("DB_HOST") != "" {
-				
-			baseURL = "http://host.docker.internal:11434"
-		}
-	}
-
 	return &ChatService{
-		baseURL: baseURL,
-		model:   model,
+		baseURL: dockerHostURL(baseURL, cfg.DockerHost),
+		model:   cfg.Model,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second, 
+			Timeout: cfg.Timeout.Duration,
 		},
 		enabled: true,
 	}
@@ -121,11 +112,28 @@ olup olmadığına bakılır. Son olarak, temizlenmiş yanıt metni çağıran f
 döndürülür; tüm hata durumlarında ayrıntılı log kaydı tutulur ve uygun hata mesajları
 döndürülür
 */
-func (s *ChatService) Chat(userMessage string) (string, error) {
+func (s *ChatService) Chat(ctx context.Context, userMessage string) (string, error) {
 	if !s.enabled {
 		return "", fmt.Errorf("chat service is not enabled")
 	}
 
+	ctx, span := tracing.Start(ctx, "ai.chat", tracing.KindClient, s.spanAttrs()...)
+	defer span.End()
+	reply, err := s.chat(ctx, userMessage)
+	span.RecordError(err)
+	return reply, err
+}
+
+// spanAttrs describes a request to the generate endpoint
+func (s *ChatService) spanAttrs() []tracing.Attr {
+	return []tracing.Attr{
+		tracing.String("http.request.method", "POST"),
+		tracing.String("url.full", s.baseURL+"/api/generate"),
+		tracing.String("gen_ai.request.model", s.model),
+	}
+}
+
+func (s *ChatService) chat(ctx context.Context, userMessage string) (string, error) {
 	prompt := fmt.Sprintf(`Sen bir siber güvenlik analistisin. Kısa ve net yorum yap (2-3 cümle max).
 
 KURALLAR: Sadece yorumlama yap, karar verme. Sistem zaten kararları verdi. Mesaj: %s`, userMessage)
@@ -139,45 +147,46 @@ KURALLAR: Sadece yorumlama yap, karar verme. Sistem zaten kararları verdi. Mesa
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		log.Printf("Failed to marshal chat request: %v", err)
+		logger.ErrorContext(ctx, "failed to marshal chat request", logging.Err(err))
 		return "", fmt.Errorf("failed to prepare request: %v", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", s.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("Failed to create chat request: %v", err)
+		logger.ErrorContext(ctx, "failed to create chat request", logging.Err(err))
 		return "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, httpReq.Header)
 
 	resp, err := s.httpClient.Do(httpReq)
 	if err != nil {
-		log.Printf("Chat service request failed (network/timeout): %v", err)
+		logger.WarnContext(ctx, "chat service request failed", logging.Err(err))
 		return "", fmt.Errorf("chat service unavailable: %v", err)
 	}
 		defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Chat service returned non-OK status %d: %s", resp.StatusCode, string(body))
+		logger.WarnContext(ctx, "chat service returned non-OK status", "status", resp.StatusCode, "body", string(body))
 		return "", fmt.Errorf("chat service error: status %d", resp.StatusCode)
 	}
 
 	var ollamaResp OllamaGenerateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		log.Printf("Failed to decode chat response: %v", err)
+		logger.WarnContext(ctx, "failed to decode chat response", logging.Err(err))
 		return "", fmt.Errorf("failed to parse response: %v", err)
 	}
 
 	if ollamaResp.Error != "" {
-		log.Printf("Ollama returned error: %s", ollamaResp.Error)
+		logger.WarnContext(ctx, "ollama returned error", "ollama_error", ollamaResp.Error)
 		return "", fmt.Errorf("ollama error: %s", ollamaResp.Error)
 	}
 
 	reply := strings.TrimSpace(ollamaResp.Response)
 	if reply == "" {
-		log.Printf("Empty response from chat service")
+		logger.WarnContext(ctx, "empty response from chat service")
 		return "", fmt.Errorf("empty response from chat service")
 	}
 
@@ -3,12 +3,15 @@ package ai
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
+
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/tracing"
 )
 
 /*Bu fonksiyon, ChatService üzerinden gelen kullanıcı mesajını akış (stream) halinde yapay
@@ -25,11 +28,19 @@ biriktirilir ve stream sonunda boş olup olmadığı kontrol edilir. Hatalar ve
 problemleri uygun şekilde loglanır ve fonksiyon çağırana iletilir. Bu yöntem özellikle uzun
 yanıtların bekletilmeden kullanıcıya aktarılması gereken durumlar için idealdir.
 */
-func (s *ChatService) ChatStream(userMessage string, writer io.Writer) error {
+func (s *ChatService) ChatStream(ctx context.Context, userMessage string, writer io.Writer) error {
 	if !s.enabled {
 		return fmt.Errorf("chat service is not enabled")
 	}
 
+	ctx, span := tracing.Start(ctx, "ai.chat_stream", tracing.KindClient, s.spanAttrs()...)
+	defer span.End()
+	err := s.chatStream(ctx, userMessage, writer)
+	span.RecordError(err)
+	return err
+}
+
+func (s *ChatService) chatStream(ctx context.Context, userMessage string, writer io.Writer) error {
 
 	prompt := fmt.Sprintf(`Sen bir siber güvenlik analistisin. Kısa ve net yorum yap (2-3 cümle max).
 
@@ -49,23 +60,24 @@ Mesaj: %s`, userMessage)
 		return fmt.Errorf("failed to prepare request: %v", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", s.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, httpReq.Header)
 
 	resp, err := s.httpClient.Do(httpReq)
 	if err != nil {
-		log.Printf("Chat stream request failed: %v", err)
+		logger.WarnContext(ctx, "chat stream request failed", logging.Err(err))
 		return fmt.Errorf("chat service unavailable: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Chat service returned status %d: %s", resp.StatusCode, string(body))
+		logger.WarnContext(ctx, "chat service returned non-OK status", "status", resp.StatusCode, "body", string(body))
 		return fmt.Errorf("chat service error: status %d", resp.StatusCode)
 	}
 
@@ -86,7 +98,7 @@ Mesaj: %s`, userMessage)
 
 		
 		if streamChunk.Error != "" {
-			log.Printf("Ollama stream error: %s", streamChunk.Error)
+			logger.WarnContext(ctx, "ollama stream error", "ollama_error", streamChunk.Error)
 			return fmt.Errorf("ollama error: %s", streamChunk.Error)
 		}
 
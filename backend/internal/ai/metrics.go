@@ -0,0 +1,10 @@
+package ai
+
+import "interactive-scraper/internal/metrics"
+
+var (
+	analysisDuration = metrics.NewHistogramVec("ai_analysis_duration_seconds",
+		"Duration of AI analysis requests by outcome (success or error).", metrics.DurationBuckets, "outcome")
+	analysisFailuresTotal = metrics.NewCounter("ai_analysis_failures_total",
+		"AI analysis requests that failed or returned an error.")
+)
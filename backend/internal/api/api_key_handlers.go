@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/service"
+)
+
+func apiKeyID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func ListMyAPIKeysHandler(apiKeyService *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		keys, err := apiKeyService.ListUserKeys(id)
+		if err != nil {
+			writeAPIKeyError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+/*Bu fonksiyon, oturumdaki kullanıcı için yeni bir API anahtarı oluşturan handler'dır.
+Gövdede ad, kapsamlar (ör. ["export", "scraper:run"]) ve gün cinsinden geçerlilik süresi
+beklenir; kapsamlar RequirePermission ile rotalara uygulanır, "export" kapsamı ör.
+GET /api/entries/export'u açar. Düz anahtar yalnızca bu yanıttaki api_key alanında döner ve daha sonra tekrar
+gösterilemez.
+*/
+func CreateAPIKeyHandler(apiKeyService *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		var req service.CreateAPIKeyInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		key, err := apiKeyService.CreateKey(id, req)
+		if err != nil {
+			writeAPIKeyError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, key)
+	}
+}
+
+func RevokeMyAPIKeyHandler(apiKeyService *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+		keyID, ok := apiKeyID(c)
+		if !ok {
+			return
+		}
+
+		if err := apiKeyService.RevokeKey(id, keyID); err != nil {
+			writeAPIKeyError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+	}
+}
+
+func GetUserAPIKeysHandler(apiKeyService *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+
+		keys, err := apiKeyService.ListUserKeys(id)
+		if err != nil {
+			writeAPIKeyError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// RevokeUserAPIKeyHandler lets an admin revoke another user's key, e.g. after a leak
+func RevokeUserAPIKeyHandler(apiKeyService *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+		keyID, ok := apiKeyID(c)
+		if !ok {
+			return
+		}
+
+		if err := apiKeyService.RevokeKey(id, keyID); err != nil {
+			writeAPIKeyError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+	}
+}
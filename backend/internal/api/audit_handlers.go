@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/audit"
+)
+
+// auditSkipPaths are POST routes that do not change any state
+var auditSkipPaths = map[string]bool{
+	"/api/chat": true,
+}
+
+/*Bu yapı (auditTarget), denetim kaydında hedef tipini ve hedefin işlem öncesi/sonrası
+anlık görüntüsünü almak için kullanılan yükleyiciyi tanımlar. Yükleyici, rotadaki :id
+parametresiyle çağrılır; hedef bulunamazsa (ör. silindikten sonra) hata döner ve ilgili
+anlık görüntü boş bırakılır.
+*/
+type auditTarget struct {
+	targetType string
+	load       func(c *gin.Context, id int) (interface{}, error)
+}
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.body.Len() < 64*1024 {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+/*Bu fonksiyon, /api altındaki tüm değişiklik yapan istekleri (POST, PUT, DELETE)
+denetim kaydına yazan middleware'dir. İstek gövdesi hassas alanları maskelenerek
+details alanına yazılır. Rota, targets tablosundaki bir önekle eşleşiyorsa hedefin
+işlem öncesi ve sonrası hali de kaydedilir; :id içermeyen POST isteklerinde (oluşturma)
+yanıt gövdesi "after" olarak saklanır ve hedef ID'si yanıttaki id alanından alınır.
+Kayıt, handler çalıştıktan sonra gerçek durum koduyla birlikte yazılır; başarısız
+istekler de success=false olarak kaydedilir.
+*/
+func AuditMiddleware(recorder *audit.Recorder, targets map[string]auditTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions ||
+			auditSkipPaths[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		fullPath := c.FullPath()
+		entry := audit.Entry{
+			Action:    method + " " + fullPath,
+			Details:   audit.RedactBody(body),
+			IP:        c.ClientIP(),
+			UserAgent: truncate(c.Request.UserAgent(), 500),
+		}
+
+		target, hasTarget := matchAuditTarget(fullPath, targets)
+		id, idErr := strconv.Atoi(c.Param("id"))
+		if hasTarget {
+			entry.TargetType = target.targetType
+			if idErr == nil {
+				entry.TargetID = c.Param("id")
+				if before, err := target.load(c, id); err == nil {
+					entry.Before = audit.Snapshot(before)
+				}
+			}
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		capture := method == http.MethodPost && idErr != nil
+		if capture {
+			c.Writer = writer
+		}
+
+		c.Next()
+
+		entry.Actor = c.GetString("username")
+		userID := c.GetInt("user_id")
+		entry.ActorID = &userID
+		entry.StatusCode = c.Writer.Status()
+		entry.Success = entry.StatusCode < http.StatusBadRequest
+
+		if entry.Success {
+			if hasTarget && idErr == nil && method != http.MethodDelete {
+				if after, err := target.load(c, id); err == nil {
+					entry.After = audit.Snapshot(after)
+				}
+			}
+			if capture && json.Valid(writer.body.Bytes()) {
+				var created struct {
+					ID *int `json:"id"`
+				}
+				if json.Unmarshal(writer.body.Bytes(), &created) == nil && created.ID != nil {
+					entry.TargetID = strconv.Itoa(*created.ID)
+					entry.After = audit.RedactBody(writer.body.Bytes())
+				}
+			}
+		}
+
+		recorder.Record(entry)
+	}
+}
+
+func matchAuditTarget(fullPath string, targets map[string]auditTarget) (auditTarget, bool) {
+	for prefix, target := range targets {
+		if fullPath == prefix || strings.HasPrefix(fullPath, prefix+"/") {
+			return target, true
+		}
+	}
+	return auditTarget{}, false
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+/*Bu fonksiyon, denetim kaydını sorgulayan handler'dır. actor, action (önek eşleşmesi),
+target_type, target_id, success ve from/to (RFC3339 veya YYYY-MM-DD) filtreleri ile
+page/pageSize sayfalaması desteklenir. Kayıtlar en yeniden eskiye sıralanır.
+*/
+func GetAuditLogHandler(recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+
+		filter := audit.Filter{
+			Actor:      c.Query("actor"),
+			Action:     c.Query("action"),
+			TargetType: c.Query("target_type"),
+			TargetID:   c.Query("target_id"),
+		}
+		if value := c.Query("success"); value != "" {
+			success, err := strconv.ParseBool(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid success: " + value})
+				return
+			}
+			filter.Success = &success
+		}
+		var err error
+		if filter.From, err = queryTime(c, "from", false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if filter.To, err = queryTime(c, "to", true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := recorder.Query(filter, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"entries":  result.Entries,
+			"total":    result.Total,
+			"page":     page,
+			"pageSize": pageSize,
+		})
+	}
+}
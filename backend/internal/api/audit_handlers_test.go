@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/service"
+)
+
+// captureDriver is a database/sql driver that keeps the arguments of every Exec, standing in for audit_log
+type captureDriver struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+}
+
+func (d *captureDriver) Open(string) (driver.Conn, error) { return captureConn{d}, nil }
+
+type captureConn struct{ d *captureDriver }
+
+func (c captureConn) Prepare(string) (driver.Stmt, error) { return captureStmt(c), nil }
+func (c captureConn) Close() error                        { return nil }
+func (c captureConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type captureStmt struct{ d *captureDriver }
+
+func (s captureStmt) Close() error  { return nil }
+func (s captureStmt) NumInput() int { return -1 }
+func (s captureStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.rows = append(s.d.rows, args)
+	return driver.RowsAffected(1), nil
+}
+func (s captureStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+// captureConnector opens a fresh captureDriver per test without registering a driver name
+type captureConnector struct{ d *captureDriver }
+
+func (c captureConnector) Connect(context.Context) (driver.Conn, error) { return captureConn{c.d}, nil }
+func (c captureConnector) Driver() driver.Driver                        { return c.d }
+
+// recordedIPs returns the ip column ($9) of the audit rows written so far
+func (d *captureDriver) recordedIPs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var ips []string
+	for _, row := range d.rows {
+		ip, _ := row[8].(string)
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+func TestAuditRecordsPeerUnlessProxyIsTrusted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"spoofed header without trusted proxies", nil, "192.0.2.1:4000", "203.0.113.9", "192.0.2.1"},
+		{"spoofed header from an untrusted peer", []string{"10.0.0.0/8"}, "192.0.2.1:4000", "203.0.113.9", "192.0.2.1"},
+		{"trusted proxy forwards the client", []string{"10.0.0.0/8"}, "10.0.0.5:4000", "198.51.100.7", "198.51.100.7"},
+		{"client prepends a fake hop", []string{"10.0.0.0/8"}, "10.0.0.5:4000", "203.0.113.9, 198.51.100.7", "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := &captureDriver{}
+			db := sql.OpenDB(captureConnector{capture})
+			defer db.Close()
+			recorder := audit.NewRecorder(db)
+
+			throttle := service.NewLoginThrottle(service.ThrottleConfig{
+				MaxAttempts: 1, MaxAttemptsPerIP: 100,
+				LockoutBase: time.Minute, LockoutMax: time.Hour, FailureWindow: time.Hour,
+			})
+			throttle.Failure("alice", "198.18.0.1")
+
+			router := newEngine(tt.trusted)
+			// The locked-out login is audited without reaching the nil AuthService
+			router.POST("/api/login", LoginHandler(nil, throttle, recorder))
+			router.POST("/api/sources", AuditMiddleware(recorder, nil), func(c *gin.Context) {
+				c.JSON(http.StatusCreated, gin.H{"id": 1})
+			})
+
+			for _, request := range []struct{ path, body string }{
+				{"/api/login", `{"username":"alice","password":"x"}`},
+				{"/api/sources", `{"name":"forum"}`},
+			} {
+				req := httptest.NewRequest(http.MethodPost, request.path, strings.NewReader(request.body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+				req.RemoteAddr = tt.remoteAddr
+				router.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			ips := capture.recordedIPs()
+			if len(ips) != 2 {
+				t.Fatalf("recorded %d audit entries, want 2", len(ips))
+			}
+			for i, ip := range ips {
+				if ip != tt.want {
+					t.Errorf("audit entry %d ip = %q, want %q", i, ip, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/audit"
 	"interactive-scraper/internal/service"
 )
 /*Bu fonksiyon, HTTP üzerinden kullanıcı girişini (login) yöneten bir handler’dır ve Gin web
@@ -13,9 +18,12 @@ yapısına bağlar; eğer veri geçersizse 400 Bad Request döner. Ardından,
 authService.Login metodu ile kullanıcının kullanıcı adı ve şifresi doğrulanır. Doğrulama
 başarılı olursa bir JWT veya benzeri oturum tokeni oluşturulur ve 200 OK ile istemciye
 döndürülür; başarısız olursa 401 Unauthorized hatası ile hata mesajı gönderilir. Bu handler,
-API’de kullanıcı kimlik doğrulamasının temel noktasını oluşturur.
+API’de kullanıcı kimlik doğrulamasının temel noktasını oluşturur. Başarısız denemeler
+kullanıcı adı ve IP bazında sayılır; eşik aşıldığında anahtar üstel artan sürelerle
+kilitlenir ve kilit süresince istekler 429 Too Many Requests ve Retry-After başlığı ile
+reddedilir. Başarılı, başarısız ve engellenen tüm denemeler denetim kaydına yazılır.
 */
-func LoginHandler(authService *service.AuthService) gin.HandlerFunc {
+func LoginHandler(authService *service.AuthService, throttle *service.LoginThrottle, recorder *audit.Recorder) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req service.LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -23,25 +31,209 @@ func LoginHandler(authService *service.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		token, err := authService.Login(req.Username, req.Password)
+		attempt := audit.Entry{
+			Actor:      truncate(req.Username, 100),
+			Action:     "login",
+			TargetType: audit.TargetUser,
+			TargetID:   truncate(req.Username, 100),
+			IP:         c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 500),
+		}
+
+		if wait := throttle.Check(req.Username, c.ClientIP()); wait > 0 {
+			attempt.StatusCode = http.StatusTooManyRequests
+			attempt.Details = audit.Snapshot(gin.H{"error": "locked out", "retry_after": retryAfterSeconds(wait)})
+			recorder.Record(attempt)
+			writeTooManyAttempts(c, wait)
+			return
+		}
+
+		tokens, err := authService.Login(req.Username, req.Password, sessionMeta(c))
+		switch {
+		case err == nil:
+			// With 2FA the counter is only cleared once the second step succeeds
+			if !tokens.MFARequired {
+				throttle.Success(req.Username)
+			}
+			attempt.StatusCode = http.StatusOK
+			attempt.Success = true
+			attempt.Details = audit.Snapshot(gin.H{
+				"password_change_required": tokens.PasswordChangeRequired,
+				"mfa_required":             tokens.MFARequired,
+				"mfa_setup_required":       tokens.MFASetupRequired,
+			})
+		case errors.Is(err, service.ErrInvalidCredentials):
+			lock := throttle.Failure(req.Username, c.ClientIP())
+			attempt.StatusCode = http.StatusUnauthorized
+			details := gin.H{"error": err.Error()}
+			if lock > 0 {
+				details["locked_for"] = retryAfterSeconds(lock)
+			}
+			attempt.Details = audit.Snapshot(details)
+		default:
+			attempt.StatusCode = http.StatusInternalServerError
+			attempt.Details = audit.Snapshot(gin.H{"error": err.Error()})
+		}
+		recorder.Record(attempt)
+
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.JSON(attempt.StatusCode, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"token": token})
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func writeTooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := retryAfterSeconds(wait)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts, try again later",
+		"retry_after": seconds,
+	})
+}
+
+func sessionMeta(c *gin.Context) service.SessionMeta {
+	return service.SessionMeta{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+/*Bu fonksiyon, refresh token ile yeni bir erişim token'ı ve yeni bir refresh token üreten
+handler'dır. Gönderilen refresh token bu işlemden sonra geçersiz olur; istemcinin
+yanıttaki yeni refresh token'ı saklaması gerekir. Geçersiz, süresi dolmuş veya iptal
+edilmiş token'lar 401 Unauthorized döner.
+*/
+func RefreshTokenHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req refreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+			return
+		}
+
+		tokens, err := authService.Refresh(req.RefreshToken, sessionMeta(c))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidRefreshToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+/*Bu fonksiyon, çıkış (logout) işlemini yapan handler'dır. Gövdedeki refresh token'ın
+oturumu ve Authorization başlığında geçerli bir erişim token'ı varsa onun oturumu iptal
+edilir. Erişim token'ı süresi dolmuş olsa bile refresh token ile çıkış yapılabildiği için
+bu rota AuthMiddleware dışındadır. Çıkış işlemi denetim kaydına yazılır.
+*/
+func LogoutHandler(authService *service.AuthService, recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req refreshRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+		}
+
+		var sessionID string
+		if token, ok := bearerToken(c); ok {
+			if claims, err := authService.ValidateToken(token); err == nil {
+				sessionID = claims.SessionID
+			}
+		}
+		if req.RefreshToken == "" && sessionID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token or a valid access token is required"})
+			return
+		}
+
+		userID, username, err := authService.Logout(req.RefreshToken, sessionID)
+		if err != nil && !errors.Is(err, service.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err == nil {
+			recorder.Record(audit.Entry{
+				ActorID:    &userID,
+				Actor:      username,
+				Action:     "logout",
+				TargetType: audit.TargetUser,
+				TargetID:   strconv.Itoa(userID),
+				IP:         c.ClientIP(),
+				UserAgent:  truncate(c.Request.UserAgent(), 500),
+				StatusCode: http.StatusOK,
+				Success:    true,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
 	}
+	return parts[1], true
+}
+// passwordChangeAllowedPaths are the only routes usable until a required password change is done
+var passwordChangeAllowedPaths = map[string]bool{
+	"/api/users/me":          true,
+	"/api/users/me/password": true,
 }
+
+// mfaSetupAllowedPaths are the only routes usable until required 2FA enrollment is done
+var mfaSetupAllowedPaths = map[string]bool{
+	"/api/users/me":            true,
+	"/api/users/me/2fa":        true,
+	"/api/users/me/2fa/enroll": true,
+	"/api/users/me/2fa/verify": true,
+}
+
 /*Bu fonksiyon, Gin framework üzerinde JWT tabanlı kimlik doğrulama (authentication)
-middleware’i olarak çalışır. Gelen HTTP isteğindeki Authorization başlığını kontrol eder;
+middleware’i olarak çalışır. İstekte X-API-Key başlığı varsa kimlik API anahtarıyla
+doğrulanır ve anahtarın kapsamları context'e eklenir; aksi halde Authorization başlığını kontrol eder;
 başlık eksik veya formatı hatalıysa 401 Unauthorized döner ve isteği durdurur. Başlık doğru
 formatta ise (Bearer <token>), token authService.ValidateToken ile doğrulanır. Token
 geçerli ise içerisindeki kullanıcı bilgisi (Username) context’e eklenir ve istek bir sonraki
 handler’a geçer. Bu middleware, API’nin korumalı rotalarında kullanıcı doğrulamasını
 merkezi ve güvenli bir şekilde sağlar.
 */
-func AuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
+func AuthMiddleware(authService *service.AuthService, apiKeyService *service.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			principal, err := apiKeyService.Authenticate(key, c.ClientIP())
+			if err != nil {
+				message := "Invalid API key"
+				if errors.Is(err, service.ErrAPIKeyExpired) {
+					message = err.Error()
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+				c.Abort()
+				return
+			}
+			c.Set("user_id", principal.UserID)
+			c.Set("username", principal.Username)
+			c.Set("role", principal.Role)
+			c.Set("api_key_id", principal.KeyID)
+			c.Set("api_key_scopes", principal.Scopes)
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -58,12 +250,85 @@ func AuthMiddleware(authService *service.AuthService) gin.HandlerFunc {
 
 		claims, err := authService.ValidateToken(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			message := "Invalid token"
+			if errors.Is(err, service.ErrSessionRevoked) {
+				message = "Session has been revoked, please log in again"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
 			c.Abort()
 			return
 		}
 
+		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+
+		if claims.PasswordChange && !passwordChangeAllowedPaths[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                    "Password change required",
+				"password_change_required": true,
+			})
+			c.Abort()
+			return
+		}
+		if claims.MFASetup && !mfaSetupAllowedPaths[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":              "Two-factor authentication enrollment required",
+				"mfa_setup_required": true,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+/*Bu fonksiyon, rotaya erişim için gereken yetkiyi kontrol eden middleware'dir.
+Kullanıcının rolü AuthMiddleware tarafından JWT'deki role claim'inden context'e
+eklenir; rol bu yetkiye sahip değilse 403 Forbidden döner. Rol bilgisi içermeyen eski
+token'lar hiçbir yetkiye sahip sayılmaz ve kullanıcının yeniden giriş yapması istenir.
+API anahtarıyla gelen isteklerde yetki ayrıca anahtarın kapsamlarında da bulunmalıdır.
+*/
+func RequirePermission(perm service.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Session has no role, please log in again"})
+			c.Abort()
+			return
+		}
+		if !service.HasPermission(role, perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "required": perm})
+			c.Abort()
+			return
+		}
+		if scopes, ok := c.Get("api_key_scopes"); ok && !hasScope(scopes.([]service.Permission), perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing the required scope", "required": perm})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasScope(scopes []service.Permission, perm service.Permission) bool {
+	for _, scope := range scopes {
+		if scope == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireSession rejects API key requests on routes that manage the account itself
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key_id"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a user session, not an API key"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"interactive-scraper/internal/service"
+)
+
+func TestRequirePermissionAPIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		role   string
+		scopes []service.Permission // nil means a session rather than an API key
+		want   int
+	}{
+		{"session analyst", service.RoleAnalyst, nil, http.StatusOK},
+		{"session viewer", service.RoleViewer, nil, http.StatusForbidden},
+		{"key with export scope", service.RoleAnalyst, []service.Permission{service.PermRead, service.PermExport}, http.StatusOK},
+		{"key without export scope", service.RoleAnalyst, []service.Permission{service.PermRead}, http.StatusForbidden},
+		{"key whose owner lost export", service.RoleViewer, []service.Permission{service.PermRead, service.PermExport}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("role", tt.role)
+				if tt.scopes != nil {
+					c.Set("api_key_scopes", tt.scopes)
+				}
+			})
+			router.GET("/api/entries/export", RequirePermission(service.PermExport), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entries/export", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLoginThrottleIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		forwarded  string
+	}{
+		{"no trusted proxies", nil, "192.0.2.1:4000", "203.0.113.9"},
+		{"peer is not a trusted proxy", []string{"10.0.0.0/8"}, "192.0.2.1:4000", "203.0.113.9"},
+		{"trusted proxy forwards the client", []string{"10.0.0.0/8"}, "10.0.0.5:4000", "192.0.2.1"},
+		{"client prepends a fake hop", []string{"10.0.0.0/8"}, "10.0.0.5:4000", "203.0.113.9, 192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := service.NewLoginThrottle(service.ThrottleConfig{
+				MaxAttempts: 100, MaxAttemptsPerIP: 3,
+				LockoutBase: time.Minute, LockoutMax: time.Hour, FailureWindow: time.Hour,
+			})
+			// Lock 192.0.2.1 out with failures against other accounts
+			for _, username := range []string{"alice", "bob", "carol"} {
+				throttle.Failure(username, "192.0.2.1")
+			}
+
+			// A nil AuthService panics if the throttle lets the attempt through
+			router := newEngine(tt.trusted)
+			router.POST("/api/login", LoginHandler(nil, throttle, nil))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"username":"dave","password":"x"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusTooManyRequests {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, http.StatusTooManyRequests, w.Body.String())
+			}
+		})
+	}
+}
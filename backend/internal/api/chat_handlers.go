@@ -1,36 +1,25 @@
 package api
 
 import (
-	"log"
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"interactive-scraper/internal/ai"
+	"interactive-scraper/internal/logging"
 )
 
-var chatService *ai.ChatService
-
-/*Bu kod, program başlatıldığında chatService nesnesini otomatik olarak başlatan bir
-init fonksiyonudur. Go’da init() fonksiyonu, main fonksiyonu çalışmadan önce
-otomatik olarak çağrılır. Burada ai.NewChatService() çağrısı ile ChatService yapılandırılır
-ve uygulama içinde kullanılmak üzere chatService değişkenine atanır. Böylece, uygulama
-başladığında sohbet servisi hazır ve kullanıma uygun hale gelir.
-*/
-func init() {
-	chatService = ai.NewChatService()
-}
-
 /*Bu fonksiyon, Gin framework üzerinde çalışan bir HTTP chat handler’ıdır ve kullanıcıdan
 gelen mesajları AI sohbet servisine iletir. Gelen JSON isteği doğrulanır; Message alanı
 zorunludur ve Stream alanı yanıtın akış halinde mi yoksa tek seferde mi alınacağını belirler.
 Chat servisi aktif değilse kullanıcıya “servis kullanılamıyor” mesajı döner. Eğer Stream
 aktifse, handleStreamingChat çağrılarak yanıt parça parça iletilir; değilse ayrı bir goroutine
-içinde chatService.Chat çalıştırılır ve sonuç kanallar aracılığıyla alınır. 25 saniyelik zaman
-aşımıyla servis yanıt vermezse kullanıcıya uygun mesaj iletilir. Bu yapı, hem eşzamansız
+içinde chatService.Chat çalıştırılır ve sonuç kanallar aracılığıyla alınır. Servis timeout
+(ai.chat_timeout, varsayılan 25 saniye) içinde yanıt vermezse kullanıcıya uygun mesaj iletilir. Bu yapı, hem eşzamansız
 hem de gerçek zamanlı chat akışını yönetir ve servis hatalarında kullanıcı deneyimini korur.
 */
-func ChatHandler() gin.HandlerFunc {
+func ChatHandler(chatService *ai.ChatService, timeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			Message string `json:"message" binding:"required"`
@@ -52,16 +41,19 @@ func ChatHandler() gin.HandlerFunc {
 
 		
 		if req.Stream {
-			handleStreamingChat(c, req.Message)
+			handleStreamingChat(c, chatService, req.Message)
 			return
 		}
 
 		
 		replyChan := make(chan string, 1)
 		errChan := make(chan error, 1)
+		// Abandoned requests are cancelled so they stop tying up the model
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
 
 		go func() {
-			reply, err := chatService.Chat(req.Message)
+			reply, err := chatService.Chat(ctx, req.Message)
 			if err != nil {
 				errChan <- err
 				return
@@ -76,12 +68,12 @@ func ChatHandler() gin.HandlerFunc {
 				"reply": reply,
 			})
 		case err := <-errChan:
-			log.Printf("Chat error: %v", err)
+			logger.WarnContext(ctx, "chat request failed", logging.Err(err))
 			c.JSON(http.StatusOK, gin.H{
 				"reply": "Lokal AI chat servisi şu anda kullanılamıyor. Lütfen daha sonra tekrar deneyin.",
 			})
-		case <-time.After(25 * time.Second):
-			
+		case <-ctx.Done():
+			logger.WarnContext(ctx, "chat request timed out", "timeout", timeout.String())
 			c.JSON(http.StatusOK, gin.H{
 				"reply": "Lokal AI chat servisi şu anda kullanılamıyor. Lütfen daha sonra tekrar deneyin.",
 			})
@@ -97,7 +89,7 @@ bir hata oluşursa, hata mesajı bir SSE eventi olarak iletilir ve akış sonlan
 tamamlandığında ise “done” eventi gönderilerek istemciye akışın bittiği bildirilir; böylece
 kullanıcıya anlık ve kesintisiz chat deneyimi sağlanır.
 */
-func handleStreamingChat(c *gin.Context, message string) {
+func handleStreamingChat(c *gin.Context, chatService *ai.ChatService, message string) {
 	
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
@@ -105,9 +97,9 @@ func handleStreamingChat(c *gin.Context, message string) {
 	c.Header("X-Accel-Buffering", "no") 
 
 	
-	err := chatService.ChatStream(message, c.Writer)
+	err := chatService.ChatStream(c.Request.Context(), message, c.Writer)
 	if err != nil {
-		log.Printf("Chat stream error: %v", err)
+		logger.WarnContext(c.Request.Context(), "chat stream failed", logging.Err(err))
 		
 		c.SSEvent("error", "Lokal AI chat servisi şu anda kullanılamıyor. Lütfen daha sonra tekrar deneyin.")
 		c.Writer.Flush()
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/config"
+)
+
+/*Bu fonksiyon, uygulamanın çalışırken kullandığı yapılandırmayı döndüren handler'dır.
+Şifre ve JWT anahtarları gibi gizli alanlar "[REDACTED]" ile değiştirilir. Yanıtta ayrıca
+yüklenen dosyanın yolu (file) ve dosyadaki değerleri ezen ortam değişkenlerinin adları
+(env_overrides) bulunur; böylece bir ayarın nereden geldiği sunucuya erişmeden görülebilir.
+*/
+func GetConfigHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"config":        cfg.Redacted(),
+			"file":          cfg.File(),
+			"env_overrides": cfg.EnvOverrides(),
+		})
+	}
+}
@@ -1,10 +1,17 @@
 package api
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/logging"
 	"interactive-scraper/internal/service"
 )
 /*Bu fonksiyon, Gin framework üzerinde çalışan bir dashboard istatistik handler’ıdır ve
@@ -27,35 +34,254 @@ func GetDashboardStatsHandler(dataService *service.DataService) gin.HandlerFunc
 }
 
 /*Bu fonksiyon, Gin framework üzerinde çalışan bir kayıt (entries) listeleme handler’ıdır ve
-DataService üzerinden veri tabanındaki kayıtları sayfalı ve filtreli şekilde getirir. Kullanıcı
-isteğinden page, pageSize, category ve search parametreleri alınır; sayfa ve sayfa
-boyutu için varsayılan değerler atanır. dataService.GetAllEntries çağrısıyla kayıtlar ve
-toplam kayıt sayısı çekilir; hata oluşursa 500 Internal Server Error döndürülür. Başarılı
-olursa, kayıtlar, toplam kayıt sayısı, sayfa numarası ve sayfa boyutu JSON formatında 200
-OK ile istemciye iletilir. Bu yapı, API’de sayfalama ve filtreleme destekli veri sunumu sağlar.
+DataService üzerinden veri tabanındaki kayıtları sayfalı ve filtreli şekilde getirir. Filtreler
+parseEntryFilter ile sorgu parametrelerinden okunur; geçersiz bir filtre, sıralama, arama
+sorgusu veya cursor 400 Bad Request ile reddedilir. cursor parametresi verilirse OFFSET
+yerine bir önceki yanıttaki next_cursor değerinden devam edilir. Başarılı olursa kayıtlar,
+toplam kayıt sayısı, sayfa bilgisi ve varsa next_cursor 200 OK ile istemciye iletilir.
 */
 func GetEntriesHandler(dataService *service.DataService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 		pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
-		category := c.Query("category")
-		search := c.Query("search")
 
-		entries, total, err := dataService.GetAllEntries(page, pageSize, category, search)
+		filter, err := parseEntryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := dataService.GetAllEntries(filter, service.PageRequest{
+			Page:     page,
+			PageSize: pageSize,
+			Cursor:   c.Query("cursor"),
+		})
+		if isEntryFilterError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"entries": entries,
-			"total":   total,
-			"page":    page,
-			"pageSize": pageSize,
+			"entries":     result.Entries,
+			"total":       result.Total,
+			"page":        page,
+			"pageSize":    pageSize,
+			"next_cursor": result.NextCursor,
+		})
+	}
+}
+
+/*Bu fonksiyon, kayıt listeleme isteğindeki sorgu parametrelerini service.EntryFilter
+yapısına çevirir. source_id birden fazla kez ya da virgülle ayrılmış olarak verilebilir.
+Tarih parametreleri RFC3339 veya YYYY-MM-DD biçiminde kabul edilir; yalnızca tarih
+verilen bitiş parametrelerinde günün tamamı aralığa dahil edilir. assignee_id için
+"me" oturumdaki kullanıcıyı, "none" ise atanmamış kayıtları ifade eder.
+*/
+func parseEntryFilter(c *gin.Context) (service.EntryFilter, error) {
+	filter := service.EntryFilter{
+		Category: c.Query("category"),
+		Search:   c.Query("search"),
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+	}
+
+	var err error
+	if filter.MinCriticality, err = queryInt(c, "min_criticality"); err != nil {
+		return filter, err
+	}
+	if filter.MaxCriticality, err = queryInt(c, "max_criticality"); err != nil {
+		return filter, err
+	}
+
+	for _, value := range c.QueryArray("source_id") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return filter, fmt.Errorf("invalid source_id: %s", part)
+			}
+			filter.SourceIDs = append(filter.SourceIDs, id)
+		}
+	}
+
+	if filter.ShareDateFrom, err = queryTime(c, "share_date_from", false); err != nil {
+		return filter, err
+	}
+	if filter.ShareDateTo, err = queryTime(c, "share_date_to", true); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = queryTime(c, "created_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = queryTime(c, "created_to", true); err != nil {
+		return filter, err
+	}
+
+	for _, value := range c.QueryArray("triage_status") {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				filter.TriageStatuses = append(filter.TriageStatuses, part)
+			}
+		}
+	}
+
+	for _, value := range c.QueryArray("tag") {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				filter.Tags = append(filter.Tags, part)
+			}
+		}
+	}
+
+	switch value := c.Query("assignee_id"); value {
+	case "":
+	case "me":
+		userID := c.GetInt("user_id")
+		if userID == 0 {
+			return filter, fmt.Errorf("assignee_id=me requires a session with user information, please log in again")
+		}
+		filter.AssigneeID = &userID
+	case "none":
+		unassigned := 0
+		filter.AssigneeID = &unassigned
+	default:
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid assignee_id: %s", value)
+		}
+		filter.AssigneeID = &id
+	}
+
+	if value := c.Query("has_ai_analysis"); value != "" {
+		hasAnalysis, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid has_ai_analysis: %s", value)
+		}
+		filter.HasAIAnalysis = &hasAnalysis
+	}
+
+	return filter, nil
+}
+
+// exportContentTypes are the response types of the export formats
+var exportContentTypes = map[string]string{
+	service.ExportJSON: "application/json",
+	service.ExportCSV:  "text/csv; charset=utf-8",
+	service.ExportSTIX: "application/stix+json;version=2.1",
+}
+
+// exportBufferSize is how much of an export is held back so that an early error can still be sent as JSON
+const exportBufferSize = 64 << 10
+
+/*Bu fonksiyon, filtreye uyan kayıtları indirilebilir bir dosya olarak döndüren
+handler'dır; "export" komutunun HTTP karşılığıdır ve aynı DataService.ExportEntries
+yolunu kullanır. format json, csv veya stix olabilir (varsayılan json); filtreler
+GET /api/entries ile aynıdır, sıralama ise her zaman oluşturulma zamanına göredir.
+Yanıt akıtılarak yazılır; ilk tampon gönderilmeden oluşan hatalar (ör. geçersiz arama
+sorgusu) yine 400/500 JSON yanıtı olarak döner; sonrasında oluşan bir hata loglanır ve
+dosya yarım kalır. Her dışa aktarım, kayıt sayısıyla birlikte denetim kaydına yazılır.
+*/
+func ExportEntriesHandler(dataService *service.DataService, recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", service.ExportJSON)
+		contentType, ok := exportContentTypes[format]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid format %q (expected json, csv or stix)", format)})
+			return
+		}
+
+		filter, err := parseEntryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		extension := format
+		if format == service.ExportSTIX {
+			extension = "stix.json"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="entries-%s.%s"`, time.Now().UTC().Format("20060102-150405"), extension))
+
+		out := bufio.NewWriterSize(c.Writer, exportBufferSize)
+		count, err := dataService.ExportEntries(out, format, filter)
+		if err == nil {
+			err = out.Flush()
+		}
+
+		status := http.StatusOK
+		switch {
+		case err != nil && !c.Writer.Written():
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			status = http.StatusInternalServerError
+			if isEntryFilterError(err) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+		case err != nil:
+			// The status line is out already, so the client only sees a truncated file
+			status = http.StatusInternalServerError
+			logger.ErrorContext(c.Request.Context(), "entry export failed", "entries", count, logging.Err(err))
+		}
+
+		userID := c.GetInt("user_id")
+		recorder.Record(audit.Entry{
+			ActorID:    &userID,
+			Actor:      c.GetString("username"),
+			Action:     "export",
+			Details:    audit.Snapshot(gin.H{"format": format, "query": c.Request.URL.RawQuery, "entries": count}),
+			IP:         c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 500),
+			StatusCode: status,
+			Success:    err == nil,
 		})
 	}
 }
 
+func queryInt(c *gin.Context, name string) (*int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return &n, nil
+}
+
+func queryTime(c *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s (expected RFC3339 or YYYY-MM-DD)", name, value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+func isEntryFilterError(err error) bool {
+	return errors.Is(err, service.ErrInvalidFilter) ||
+		errors.Is(err, service.ErrInvalidSearchQuery) ||
+		errors.Is(err, service.ErrInvalidCursor)
+}
+
 /*Bu fonksiyon, Gin framework üzerinde çalışan bir tekil kayıt (entry) görüntüleme
 handler’ıdır. İstekten URL parametresi olarak alınan id, tamsayıya dönüştürülür;
 geçersizse 400 Bad Request döner. Ardından dataService.GetEntryByID ile ilgili kayıt
@@ -85,7 +311,7 @@ güncelleyen handler’dır. URL’den alınan id parametresi tamsayıya dönü
 geçersizse 400 Bad Request döner. İstek gövdesinde beklenen JSON’dan score değeri
 okunur; geçersiz JSON ise aynı şekilde 400 hatası ile geri bildirim yapılır.
 dataService.UpdateCriticality metodu çağrılarak ilgili kaydın kritik puanı güncellenir;
-hata oluşursa 500 Internal Server Error döner. İşlem başarılı olursa, kullanıcıya “Criticality
+kayıt yoksa 404 Not Found, başka bir hata oluşursa 500 Internal Server Error döner. İşlem başarılı olursa, kullanıcıya “Criticality
 updated successfully” mesajı ile 200 OK yanıtı gönderilir. Bu handler, API’de kayıtların
 önem seviyesinin güvenli ve kontrollü bir şekilde güncellenmesini sağlar.
 */
@@ -106,7 +332,11 @@ func UpdateCriticalityHandler(dataService *service.DataService) gin.HandlerFunc
 			return
 		}
 
-		if err := dataService.UpdateCriticality(id, req.Score); err != nil {
+		if err := dataService.UpdateCriticality(id, req.Score, c.GetString("username")); err != nil {
+			if errors.Is(err, service.ErrEntryNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -118,7 +348,8 @@ func UpdateCriticalityHandler(dataService *service.DataService) gin.HandlerFunc
 handler’dır. URL’den alınan id parametresi tamsayıya dönüştürülür; geçersizse 400 Bad
 Request döner. İstek gövdesinde JSON olarak gönderilen category değeri okunur;
 geçersiz JSON ise yine 400 hatası ile geri bildirim yapılır. dataService.UpdateCategory
-çağrısı ile ilgili kaydın kategorisi güncellenir; hata oluşursa 500 Internal Server Error döner.
+çağrısı ile ilgili kaydın kategorisi güncellenir; kayıt yoksa 404 Not Found, başka bir
+hata oluşursa 500 Internal Server Error döner.
 işlem başarılı olursa, kullanıcıya “Category updated successfully” mesajı ile 200 OK yanıtı
 gönderilir. Bu handler, API’de kayıtların kategori bilgilerinin güvenli ve kontrollü şekilde
 değiştirilmesini sağlar
@@ -140,7 +371,11 @@ func UpdateCategoryHandler(dataService *service.DataService) gin.HandlerFunc {
 			return
 		}
 
-		if err := dataService.UpdateCategory(id, req.Category); err != nil {
+		if err := dataService.UpdateCategory(id, req.Category, c.GetString("username")); err != nil {
+			if errors.Is(err, service.ErrEntryNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -166,3 +401,36 @@ func GetCategoriesHandler(dataService *service.DataService) gin.HandlerFunc {
 	}
 }
 
+
+/*Bu fonksiyon, birden fazla kayda aynı işlemi uygulayan toplu işlem handler'ıdır.
+Gövdede action (set_category, set_criticality, set_status, add_tags, remove_tags,
+delete), hedef olarak ids veya filter ve işleme ait değer beklenir. Her kayıt için
+sonuç döner; bir kayıt başarısız olduğunda allow_partial verilmemişse hiçbir değişiklik
+kaydedilmez ve 409 Conflict ile birlikte hangi kayıtların neden başarısız olduğu bildirilir.
+*/
+func BulkEntriesHandler(dataService *service.DataService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.BulkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		result, err := dataService.BulkUpdate(req, c.GetInt("user_id"), c.GetString("username"))
+		if errors.Is(err, service.ErrInvalidBulkRequest) || errors.Is(err, service.ErrInvalidTag) ||
+			errors.Is(err, service.ErrTagNotFound) || isEntryFilterError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		status := http.StatusOK
+		if !result.Committed {
+			status = http.StatusConflict
+		}
+		c.JSON(status, result)
+	}
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/ai"
+	"interactive-scraper/internal/database"
+	"interactive-scraper/internal/scraper"
+)
+
+// readinessTimeout bounds each component check so a hung dependency cannot stall the probe
+const readinessTimeout = 3 * time.Second
+
+// probePaths are polled by monitoring systems; requests to them are logged at debug and not traced
+var probePaths = map[string]bool{"/metrics": true, "/healthz": true, "/readyz": true}
+
+// errCheckDisabled marks a component that is turned off in the configuration
+var errCheckDisabled = errors.New("disabled")
+
+/*Bu yapı (ReadinessCheck), /readyz'nin kontrol ettiği tek bir bileşeni tanımlar. Check,
+bileşen sağlıklıysa kısa bir açıklama, değilse hata döner; bileşen yapılandırmada kapalıysa
+errCheckDisabled döner. Required bileşenler (veritabanı, şema) çalışmıyorsa servis hazır
+değildir ve 503 döner; diğerleri (Tor, zamanlayıcı, AI) yalnızca "degraded" durumuna yol
+açar, çünkü API bunlar olmadan da kayıtları sunabilir.
+*/
+type ReadinessCheck struct {
+	Name     string
+	Required bool
+	Check    func(ctx context.Context) (string, error)
+}
+
+type componentHealth struct {
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthzHandler reports that the process is alive; it checks no dependencies
+func HealthzHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+/*Bu fonksiyon, oturum gerektirmeyen /readyz endpoint'ini sunar. Tüm bileşen kontrolleri
+paralel çalıştırılır ve her biri readinessTimeout ile sınırlıdır. Yanıt, her bileşen için
+durum (up, down, disabled), gecikme ve varsa hata içerir. Genel durum "ready", zorunlu
+olmayan bir bileşen çalışmıyorsa "degraded" (ikisi de 200), zorunlu bir bileşen
+çalışmıyorsa "not_ready" (503) olur. Eski Docker healthcheck'inin aksine internetteki
+bir servise istek atılmaz.
+*/
+func ReadyzHandler(checks []ReadinessCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		results := make([]componentHealth, len(checks))
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func(i int, check ReadinessCheck) {
+				defer wg.Done()
+				results[i] = runReadinessCheck(c.Request.Context(), check)
+			}(i, check)
+		}
+		wg.Wait()
+
+		status, code := "ready", http.StatusOK
+		components := make(gin.H, len(checks))
+		for i, check := range checks {
+			components[check.Name] = results[i]
+			if results[i].Status != "down" {
+				continue
+			}
+			if check.Required {
+				status, code = "not_ready", http.StatusServiceUnavailable
+			} else if status == "ready" {
+				status = "degraded"
+			}
+		}
+		c.JSON(code, gin.H{
+			"status":     status,
+			"checked_at": time.Now().UTC(),
+			"components": components,
+		})
+	}
+}
+
+func runReadinessCheck(ctx context.Context, check ReadinessCheck) componentHealth {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	started := time.Now()
+	go func() {
+		detail, err := check.Check(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result.err = fmt.Errorf("timed out after %s", readinessTimeout)
+	}
+
+	health := componentHealth{
+		Status:    "up",
+		Required:  check.Required,
+		LatencyMS: float64(time.Since(started).Microseconds()) / 1000,
+		Detail:    result.detail,
+	}
+	switch {
+	case errors.Is(result.err, errCheckDisabled):
+		health.Status = "disabled"
+	case result.err != nil:
+		health.Status = "down"
+		health.Error = result.err.Error()
+	}
+	return health
+}
+
+/*Bu fonksiyon, router'ın /readyz için kullandığı bileşen kontrollerini oluşturur:
+veritabanı bağlantısı (ping), şema sürümünün bu sürümün beklediği sürümle eşleşmesi, Tor
+SOCKS5 portu, scraper zamanlayıcısının heartbeat'i ve AI servisine erişim. scraperService
+nil ise (ör. yalnızca API çalıştıran kurulumlarda) zamanlayıcı kontrolü eklenmez.
+*/
+func readinessChecks(db *sql.DB, scraperService *scraper.ScraperService, aiService *ai.AIService) []ReadinessCheck {
+	checks := []ReadinessCheck{
+		{Name: "database", Required: true, Check: func(ctx context.Context) (string, error) {
+			return "", db.PingContext(ctx)
+		}},
+		{Name: "migrations", Required: true, Check: func(ctx context.Context) (string, error) {
+			current, err := database.SchemaVersion(db)
+			if err != nil {
+				return "", err
+			}
+			latest := database.LatestVersion()
+			detail := fmt.Sprintf("schema version %d, build expects %d", current, latest)
+			if current != latest {
+				return "", errors.New(detail)
+			}
+			return detail, nil
+		}},
+		{Name: "tor", Check: func(ctx context.Context) (string, error) {
+			detail, err := scraper.CheckTorSOCKS(ctx)
+			if err != nil || !scraper.TorControlEnabled() {
+				return detail, err
+			}
+			bootstrap, err := scraper.GetTorBootstrap(ctx)
+			if err != nil {
+				return "", err
+			}
+			if detail != "" {
+				detail += ", "
+			}
+			detail += fmt.Sprintf("bootstrap %d%%: %s", bootstrap.Progress, bootstrap.Summary)
+			if bootstrap.Progress < 100 {
+				return "", errors.New(detail)
+			}
+			return detail, nil
+		}},
+	}
+	if scraperService != nil {
+		checks = append(checks, ReadinessCheck{Name: "scheduler", Check: func(ctx context.Context) (string, error) {
+			last, maxAge := scraperService.Heartbeat()
+			if last.IsZero() {
+				return "", errors.New("scheduler has not started")
+			}
+			age := time.Since(last).Round(time.Second)
+			if age > maxAge {
+				return "", fmt.Errorf("last heartbeat %s ago, expected within %s", age, maxAge)
+			}
+			return fmt.Sprintf("last heartbeat %s ago", age), nil
+		}})
+	}
+	checks = append(checks, ReadinessCheck{Name: "ai", Check: func(ctx context.Context) (string, error) {
+		if !aiService.IsEnabled() {
+			return "", errCheckDisabled
+		}
+		return "", aiService.Ping(ctx)
+	}})
+	return checks
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/metrics"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounterVec("http_requests_total",
+		"HTTP requests by method, route and status code.", "method", "route", "status")
+	httpRequestDuration = metrics.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency by method and route.", metrics.DurationBuckets, "method", "route")
+	httpRequestsInFlight = metrics.NewGauge("http_requests_in_flight",
+		"HTTP requests currently being served.")
+)
+
+/*Bu fonksiyon, her HTTP isteğinin sayısını, süresini ve durum kodunu metriklere yazan
+middleware'dir. Etiket olarak gerçek yol yerine Gin'in rota kalıbı (ör. /api/entries/:id)
+kullanılır; böylece her kayıt ID'si ayrı bir seri oluşturmaz. Hiçbir rotayla eşleşmeyen
+istekler (frontend'e düşen yollar dahil) "unmatched" olarak sayılır.
+*/
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		httpRequestsInFlight.Inc()
+		started := time.Now()
+
+		c.Next()
+
+		httpRequestsInFlight.Dec()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequestsTotal.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		httpRequestDuration.Observe(time.Since(started).Seconds(), method, route)
+	}
+}
+
+/*Bu fonksiyon, Prometheus'un topladığı /metrics endpoint'ini sunar. Endpoint oturum
+gerektirmez; token verilmişse (metrics.token, METRICS_TOKEN) istek "Authorization: Bearer
+<token>" başlığını taşımalıdır, aksi halde 401 döner. Karşılaştırma sabit sürede yapılır.
+*/
+func MetricsHandler(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token != "" {
+			provided := c.GetHeader("Authorization")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte("Bearer "+token)) != 1 {
+				c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+				return
+			}
+		}
+		c.Header("Content-Type", metrics.ContentType)
+		c.Status(http.StatusOK)
+		metrics.WriteAll(c.Writer)
+	}
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/service"
+)
+
+func writeMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFAToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrWrongPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMFAState), errors.Is(err, service.ErrMFARequiredByAll):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+/*Bu fonksiyon, iki adımlı girişin ikinci adımını işleyen handler'dır. /api/login yanıtındaki
+mfa_token ile birlikte kimlik doğrulayıcı uygulamadaki 6 haneli kod veya bir kurtarma kodu
+gönderilir. Kod doğruysa oturum açılır ve token'lar döner. Yanlış kodlar şifre
+denemeleriyle aynı sayaca yazılır ve denetim kaydına "login_mfa" olarak kaydedilir.
+*/
+func LoginVerifyHandler(authService *service.AuthService, throttle *service.LoginThrottle, recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req mfaLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token and code are required"})
+			return
+		}
+
+		challenge, err := authService.ParseMFAToken(req.MFAToken)
+		if err != nil {
+			writeMFAError(c, err)
+			return
+		}
+
+		attempt := audit.Entry{
+			ActorID:    &challenge.UserID,
+			Actor:      challenge.Username,
+			Action:     "login_mfa",
+			TargetType: audit.TargetUser,
+			TargetID:   challenge.Username,
+			IP:         c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 500),
+		}
+
+		if wait := throttle.Check(challenge.Username, c.ClientIP()); wait > 0 {
+			attempt.StatusCode = http.StatusTooManyRequests
+			attempt.Details = audit.Snapshot(gin.H{"error": "locked out", "retry_after": retryAfterSeconds(wait)})
+			recorder.Record(attempt)
+			writeTooManyAttempts(c, wait)
+			return
+		}
+
+		tokens, err := authService.VerifyMFA(challenge, req.Code, sessionMeta(c))
+		switch {
+		case err == nil:
+			throttle.Success(challenge.Username)
+			attempt.StatusCode = http.StatusOK
+			attempt.Success = true
+		case errors.Is(err, service.ErrInvalidMFACode):
+			lock := throttle.Failure(challenge.Username, c.ClientIP())
+			attempt.StatusCode = http.StatusUnauthorized
+			details := gin.H{"error": err.Error()}
+			if lock > 0 {
+				details["locked_for"] = retryAfterSeconds(lock)
+			}
+			attempt.Details = audit.Snapshot(details)
+		default:
+			attempt.StatusCode = http.StatusInternalServerError
+			attempt.Details = audit.Snapshot(gin.H{"error": err.Error()})
+		}
+		recorder.Record(attempt)
+
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidMFACode) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			writeMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+func GetMFAStatusHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		status, err := authService.GetMFAStatus(id)
+		if err != nil {
+			writeMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+/*Bu fonksiyon, TOTP kaydını başlatan handler'dır. Yanıttaki provisioning_uri QR kodu olarak
+gösterilip kimlik doğrulayıcı uygulamayla okutulur (veya secret elle girilir); kayıt,
+uygulamanın ürettiği kod POST /api/users/me/2fa/verify ile doğrulanınca tamamlanır.
+*/
+func EnrollMFAHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		enrollment, err := authService.EnrollTOTP(id)
+		if err != nil {
+			writeMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+type mfaCodeRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+/*Bu fonksiyon, bekleyen TOTP kaydını uygulamadan okunan kodla doğrulayıp iki adımlı
+doğrulamayı etkinleştiren handler'dır. Kurtarma kodları yalnızca bu yanıtta gösterilir.
+Yönetici 2FA'yı zorunlu kıldığı için kayıt bekleyen oturumlara, kısıtlaması kaldırılmış
+yeni token'lar da "session" alanında döndürülür.
+*/
+func ConfirmMFAHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		var req mfaCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+			return
+		}
+
+		codes, err := authService.ConfirmTOTP(id, req.Code)
+		if err != nil {
+			writeMFAError(c, err)
+			return
+		}
+
+		response := gin.H{"enabled": true, "recovery_codes": codes}
+		if tokens, err := authService.ReissueSession(c.GetString("session_id"), sessionMeta(c)); err == nil {
+			response["session"] = tokens
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func RegenerateRecoveryCodesHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		var req mfaCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+			return
+		}
+
+		codes, err := authService.RegenerateRecoveryCodes(id, req.Code)
+		if err != nil {
+			writeMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+/*Bu fonksiyon, kullanıcının kendi iki adımlı doğrulamasını kapatan handler'dır. Gövdede
+şifre ve geçerli bir TOTP ya da kurtarma kodu beklenir. Yanlış denemeler giriş sayacına
+yazılır. 2FA herkes için zorunluysa 409 Conflict döner.
+*/
+func DisableMFAHandler(authService *service.AuthService, throttle *service.LoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		var req mfaCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" || req.Password == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "password and code are required"})
+			return
+		}
+
+		username := c.GetString("username")
+		if wait := throttle.Check(username, c.ClientIP()); wait > 0 {
+			writeTooManyAttempts(c, wait)
+			return
+		}
+
+		if err := authService.DisableTOTP(id, req.Password, req.Code); err != nil {
+			if errors.Is(err, service.ErrWrongPassword) || errors.Is(err, service.ErrInvalidMFACode) {
+				throttle.Failure(username, c.ClientIP())
+			}
+			writeMFAError(c, err)
+			return
+		}
+		throttle.Success(username)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// ResetUserMFAHandler lets an admin remove a user's 2FA, e.g. after a lost device
+func ResetUserMFAHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+
+		if err := authService.ResetMFA(id); err != nil {
+			writeMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+	}
+}
+
+func GetMFAPolicyHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"required": authService.MFARequired()})
+	}
+}
+
+/*Bu fonksiyon, iki adımlı doğrulamanın tüm kullanıcılar için zorunlu olup olmadığını
+ayarlayan handler'dır. Zorunluluk açıldığında 2FA kaydı olmayan kullanıcılar giriş
+yapabilir ancak kayıt tamamlanana kadar yalnızca 2FA kayıt rotalarını kullanabilir.
+*/
+func UpdateMFAPolicyHandler(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Required *bool `json:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Required == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "required (boolean) is required"})
+			return
+		}
+
+		if err := authService.SetMFARequired(*req.Required); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"required": *req.Required})
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/service"
+)
+
+// oidcStateCookie binds an SSO login to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+func GetOIDCConfigHandler(oidcService *service.OIDCService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !oidcService.Enabled() {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":       true,
+			"provider_name": oidcService.ProviderName(),
+			"login_url":     "/api/auth/oidc/login",
+		})
+	}
+}
+
+// ssoFailedMessage is shown instead of provider or network errors, which are only logged
+const ssoFailedMessage = "Single sign-on failed, please try again or contact an administrator"
+
+// redirectToLogin sends the browser back to the frontend; values go in the fragment so they never reach server logs
+func redirectToLogin(c *gin.Context, key, value string) {
+	c.Redirect(http.StatusFound, "/#"+key+"="+url.QueryEscape(value))
+}
+
+/*Bu fonksiyon, SSO girişini başlatan handler'dır. Tarayıcı kimlik sağlayıcının giriş
+sayfasına yönlendirilir; state değeri ayrıca HttpOnly bir çereze yazılır. Geri dönüşte
+çerezdeki state ile URL'deki state karşılaştırılarak başka bir tarayıcıda başlatılmış
+girişin kurbana tamamlatılması (login CSRF) engellenir.
+*/
+func OIDCLoginHandler(oidcService *service.OIDCService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authURL, state, err := oidcService.StartLogin()
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "failed to start SSO login", logging.Err(err))
+			redirectToLogin(c, "sso_error", ssoFailedMessage)
+			return
+		}
+
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(oidcStateCookie, state, 600, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+/*Bu fonksiyon, kimlik sağlayıcının geri dönüş (redirect_uri) handler'ıdır. Yetkilendirme
+kodu token'a çevrilir, kullanıcı gerekirse oluşturulur ve oturum açılır. Tarayıcı, oturum
+token'ları yerine tek kullanımlık bir giriş koduyla ana sayfaya yönlendirilir; frontend bu
+kodu POST /api/auth/oidc/exchange ile token'lara çevirir. Başarılı ve başarısız tüm
+denemeler denetim kaydına "login_oidc" olarak yazılır.
+*/
+func OIDCCallbackHandler(oidcService *service.OIDCService, recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cookieState, _ := c.Cookie(oidcStateCookie)
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+
+		attempt := audit.Entry{
+			Action:     "login_oidc",
+			TargetType: audit.TargetUser,
+			IP:         c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 500),
+		}
+
+		if idpError := c.Query("error"); idpError != "" {
+			message := strings.TrimSpace(idpError + " " + c.Query("error_description"))
+			attempt.StatusCode = http.StatusUnauthorized
+			attempt.Details = audit.Snapshot(gin.H{"error": truncate(message, 500)})
+			recorder.Record(attempt)
+			redirectToLogin(c, "sso_error", truncate(message, 200))
+			return
+		}
+
+		state := c.Query("state")
+		var loginCode string
+		var identity *service.OIDCIdentity
+		err := service.ErrOIDCState
+		if state != "" && state == cookieState {
+			loginCode, identity, err = oidcService.CompleteLogin(state, c.Query("code"), sessionMeta(c))
+		}
+
+		details := gin.H{}
+		if identity != nil {
+			attempt.Actor = identity.Username
+			attempt.TargetID = identity.Username
+			if identity.UserID != 0 {
+				attempt.ActorID = &identity.UserID
+			}
+			details["subject"] = identity.Subject
+			details["groups"] = identity.Groups
+			details["role"] = identity.Role
+		}
+		message := ""
+		if err != nil {
+			attempt.StatusCode = http.StatusUnauthorized
+			message = err.Error()
+			if !errors.Is(err, service.ErrOIDCState) && !errors.Is(err, service.ErrOIDCAccessDenied) &&
+				!errors.Is(err, service.ErrOIDCUserConflict) {
+				attempt.StatusCode = http.StatusBadGateway
+				message = ssoFailedMessage
+				logger.WarnContext(c.Request.Context(), "SSO login failed", logging.Err(err))
+			}
+			details["error"] = err.Error()
+		} else {
+			attempt.StatusCode = http.StatusOK
+			attempt.Success = true
+		}
+		attempt.Details = audit.Snapshot(details)
+		recorder.Record(attempt)
+
+		if err != nil {
+			redirectToLogin(c, "sso_error", message)
+			return
+		}
+		redirectToLogin(c, "sso_code", loginCode)
+	}
+}
+
+func OIDCExchangeHandler(oidcService *service.OIDCService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+			return
+		}
+
+		tokens, err := oidcService.ExchangeLoginCode(req.Code)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tokens)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/tracing"
+)
+
+var logger = logging.For("api")
+
+// requestIDPattern limits client supplied request IDs to something safe to log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+/*Bu fonksiyon, Gin'in varsayılan metin loglayıcısının yerini alan middleware'dir. Her
+isteğe bir korelasyon ID'si verilir: istemci geçerli bir X-Request-ID başlığı gönderdiyse
+o, göndermediyse rastgele bir ID kullanılır. ID, isteğin context'ine eklenir; böylece
+handler'ların ve servislerin c.Request.Context() ile yazdığı her log satırı aynı
+correlation_id'yi taşır. ID ayrıca X-Request-ID yanıt başlığıyla istemciye döner. İstek
+bittiğinde yöntem, rota, durum kodu ve süre tek bir kayıt olarak loglanır; 5xx yanıtlar
+error, statik dosyalar, /metrics ve sağlık kontrolleri (probePaths) debug seviyesindedir.
+*/
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			id = logging.NewCorrelationID()
+		}
+		c.Request = c.Request.WithContext(logging.WithCorrelationID(c.Request.Context(), id))
+		c.Header("X-Request-ID", id)
+		started := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch path := c.Request.URL.Path; {
+		case status >= 500:
+			level = slog.LevelError
+		case strings.HasPrefix(path, "/static/") || probePaths[path]:
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(started).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if username := c.GetString("username"); username != "" {
+			attrs = append(attrs, slog.String("user", username))
+		}
+		// Set when TracingMiddleware recorded the request
+		if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+			attrs = append(attrs, slog.String("trace_id", traceID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
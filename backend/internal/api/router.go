@@ -6,15 +6,52 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	"interactive-scraper/internal/ai"
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
 	"interactive-scraper/internal/scraper"
 	"interactive-scraper/internal/service"
 )
 
+// RouterDeps are the services behind the HTTP routes, built once in runServe
+type RouterDeps struct {
+	Data        *service.DataService
+	Auth        *service.AuthService
+	Throttle    *service.LoginThrottle
+	Scraper     *scraper.ScraperService
+	SavedSearch *service.SavedSearchService
+	Triage      *service.TriageService
+	Tags        *service.TagService
+	Users       *service.UserService
+	APIKeys     *service.APIKeyService
+	OIDC        *service.OIDCService
+	Recorder    *audit.Recorder
+}
+
+/*Bu fonksiyon, Gin motorunu oluşturur ve c.ClientIP()'nin hangi vekil sunuculara
+güveneceğini ayarlar. Gin varsayılan olarak tüm vekillere güvendiği için istemci
+X-Forwarded-For başlığıyla istediği adresi gösterebilir; bu da IP bazlı giriş kilidini
+atlatmaya ve denetim kaydına sahte adres yazdırmaya yol açar. Burada yalnızca
+server.trusted_proxies listesindeki adreslerden gelen yönlendirme başlıkları dikkate
+alınır; liste boşsa her zaman bağlantının karşı ucunun adresi (c.RemoteIP()) kullanılır.
+*/
+func newEngine(trustedProxies []string) *gin.Engine {
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		// config.Validate rejects bad entries, so this only guards direct callers
+		logger.Error("invalid trusted proxies, trusting none", "trusted_proxies", trustedProxies, logging.Err(err))
+		_ = router.SetTrustedProxies(nil)
+	}
+	return router
+}
+
 /*Bu fonksiyon, uygulamanın tüm HTTP rotalarını ve middleware’lerini yapılandıran
 merkezi router kurulumunu sağlar. Gin framework kullanılarak oluşturulan router,
 öncelikle cache kontrol başlıklarını ayarlayan ve CORS politikalarını uygulayan
 middleware’lerle donatılır. /api/login rotası ile kullanıcı girişleri yönetilir; /api altındaki
-tüm rotalar AuthMiddleware ile korunur. Bu alt grup içerisinde dashboard istatistikleri,
+tüm rotalar AuthMiddleware ile korunur ve her rota, kullanıcının rolüne göre
+RequirePermission ile gerekli yetkiyi kontrol eder. Bu alt grup içerisinde dashboard istatistikleri,
 kayıtlar, kategoriler ve kaynak yönetimi gibi API endpoint’leri tanımlanır; scraper ve chat
 servislerine ait işlemler de burada erişilebilir hale getirilir. Ayrıca, frontend dosyaları
 /static ve / yollarında sunulur ve cache önleme başlıkları eklenir; bilinmeyen rotalar
@@ -22,8 +59,13 @@ servislerine ait işlemler de burada erişilebilir hale getirilir. Ayrıca, fron
 trafiğini merkezi, güvenli ve yönetilebilir şekilde yöneten eksiksiz bir web sunucu altyapısı
 sağlar
 */
-func SetupRouter(dataService *service.DataService, authService *service.AuthService, scraperService *scraper.ScraperService) *gin.Engine {
-	router := gin.Default()
+func SetupRouter(deps RouterDeps, cfg *config.Config) *gin.Engine {
+	dataService, authService, throttle, recorder := deps.Data, deps.Auth, deps.Throttle, deps.Recorder
+	scraperService, savedSearchService, triageService := deps.Scraper, deps.SavedSearch, deps.Triage
+	tagService, userService, apiKeyService, oidcService := deps.Tags, deps.Users, deps.APIKeys, deps.OIDC
+
+	router := newEngine(cfg.Server.TrustedProxies)
+	router.Use(RequestLogger(), TracingMiddleware(), gin.Recovery(), MetricsMiddleware())
 
 	router.Use(func(c *gin.Context) {
 		if c.Request.URL.Path == "/" || c.Request.URL.Path == "/index.html" {
@@ -37,39 +79,126 @@ func SetupRouter(dataService *service.DataService, authService *service.AuthServ
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"*"}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-		c.Next()
-	})
-
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "X-Request-ID"}
+	config.ExposeHeaders = []string{"X-Request-ID"}
 	router.Use(cors.New(config))
 
-	router.POST("/api/login", LoginHandler(authService))
+	if cfg.Metrics.Enabled {
+		router.GET("/metrics", MetricsHandler(cfg.Metrics.Token))
+	}
+	router.GET("/healthz", HealthzHandler())
+	router.GET("/readyz", ReadyzHandler(readinessChecks(dataService.GetDB(), scraperService, ai.NewAIService(cfg.AI))))
+
+	router.POST("/api/login", LoginHandler(authService, throttle, recorder))
+	router.POST("/api/login/verify", LoginVerifyHandler(authService, throttle, recorder))
+	router.POST("/api/token/refresh", RefreshTokenHandler(authService))
+	router.POST("/api/logout", LogoutHandler(authService, recorder))
+	router.GET("/api/auth/oidc", GetOIDCConfigHandler(oidcService))
+	router.GET("/api/auth/oidc/login", OIDCLoginHandler(oidcService))
+	router.GET("/api/auth/oidc/callback", OIDCCallbackHandler(oidcService, recorder))
+	router.POST("/api/auth/oidc/exchange", OIDCExchangeHandler(oidcService))
+
+	sourceService := service.NewSourceService(dataService.GetDB())
+	auditTargets := map[string]auditTarget{
+		"/api/entries": {audit.TargetEntry, func(c *gin.Context, id int) (interface{}, error) {
+			return dataService.GetEntryByID(id)
+		}},
+		"/api/sources": {audit.TargetSource, func(c *gin.Context, id int) (interface{}, error) {
+			return sourceService.GetSourceByID(id)
+		}},
+		"/api/tags": {audit.TargetTag, func(c *gin.Context, id int) (interface{}, error) {
+			return tagService.GetTagByID(id)
+		}},
+		"/api/saved-searches": {audit.TargetSavedSearch, func(c *gin.Context, id int) (interface{}, error) {
+			return savedSearchService.Get(c.GetInt("user_id"), id)
+		}},
+		"/api/users": {audit.TargetUser, func(c *gin.Context, id int) (interface{}, error) {
+			return userService.GetUserByID(id)
+		}},
+	}
 
 	api := router.Group("/api")
-	api.Use(AuthMiddleware(authService))
+	api.Use(AuthMiddleware(authService, apiKeyService))
+	api.Use(AuditMiddleware(recorder, auditTargets))
+	api.Use(RequirePermission(service.PermRead))
 	{
+		canWriteEntries := RequirePermission(service.PermEntriesWrite)
+		canManageSources := RequirePermission(service.PermSourcesManage)
+		canRunScraper := RequirePermission(service.PermScraperRun)
+		canExport := RequirePermission(service.PermExport)
+		canManageUsers := RequirePermission(service.PermUsersManage)
+		sessionOnly := RequireSession()
+
 		api.GET("/dashboard/stats", GetDashboardStatsHandler(dataService))
 		api.GET("/entries", GetEntriesHandler(dataService))
+		api.POST("/entries/bulk", canWriteEntries, BulkEntriesHandler(dataService))
+		api.GET("/entries/export", canExport, ExportEntriesHandler(dataService, recorder))
 		api.GET("/entries/:id", GetEntryHandler(dataService))
-		api.PUT("/entries/:id/criticality", UpdateCriticalityHandler(dataService))
-		api.PUT("/entries/:id/category", UpdateCategoryHandler(dataService))
+		api.PUT("/entries/:id/criticality", canWriteEntries, UpdateCriticalityHandler(dataService))
+		api.PUT("/entries/:id/category", canWriteEntries, UpdateCategoryHandler(dataService))
+		api.PUT("/entries/:id/status", canWriteEntries, UpdateTriageStatusHandler(triageService))
+		api.PUT("/entries/:id/assignee", canWriteEntries, UpdateAssigneeHandler(triageService))
+		api.GET("/entries/:id/notes", GetEntryNotesHandler(triageService))
+		api.POST("/entries/:id/notes", canWriteEntries, CreateEntryNoteHandler(triageService))
+		api.GET("/triage/queue", GetMyQueueHandler(triageService))
+
+		api.GET("/tags", GetTagsHandler(tagService))
+		api.POST("/tags", canWriteEntries, CreateTagHandler(tagService))
+		api.PUT("/tags/:id", canWriteEntries, UpdateTagHandler(tagService))
+		api.DELETE("/tags/:id", canWriteEntries, DeleteTagHandler(tagService))
+		api.POST("/tags/bulk", canWriteEntries, BulkTagHandler(tagService))
 		api.GET("/categories", GetCategoriesHandler(dataService))
-		
-		sourceService := service.NewSourceService(dataService.GetDB())
+
+		api.GET("/saved-searches", ListSavedSearchesHandler(savedSearchService))
+		api.POST("/saved-searches", CreateSavedSearchHandler(savedSearchService))
+		api.GET("/saved-searches/:id", GetSavedSearchHandler(savedSearchService))
+		api.PUT("/saved-searches/:id", UpdateSavedSearchHandler(savedSearchService))
+		api.DELETE("/saved-searches/:id", DeleteSavedSearchHandler(savedSearchService))
+		api.GET("/saved-searches/:id/results", GetSavedSearchResultsHandler(savedSearchService))
+		api.POST("/saved-searches/:id/run", RunSavedSearchHandler(savedSearchService))
+
 		api.GET("/sources", GetSourcesHandler(sourceService))
 		api.GET("/sources/:id", GetSourceHandler(sourceService))
-		api.POST("/sources", CreateSourceHandler(sourceService, scraperService))
-		api.PUT("/sources/:id", UpdateSourceHandler(sourceService))
-		api.DELETE("/sources/:id", DeleteSourceHandler(sourceService))
+		api.POST("/sources", canManageSources, CreateSourceHandler(sourceService, scraperService))
+		api.PUT("/sources/:id", canManageSources, UpdateSourceHandler(sourceService))
+		api.DELETE("/sources/:id", canManageSources, DeleteSourceHandler(sourceService))
 		
 		api.GET("/tor/status", GetTorStatusHandler())
+		api.GET("/tor/control", GetTorControlHandler())
+		api.POST("/tor/control/newnym", canRunScraper, RequestNewnymHandler())
 		
-		api.POST("/scraper/trigger", TriggerManualScrapeHandler(scraperService))
-		api.POST("/sources/:id/scrape", TriggerSourceScrapeHandler(scraperService))
+		api.POST("/scraper/trigger", canRunScraper, TriggerManualScrapeHandler(scraperService))
+		api.POST("/sources/:id/scrape", canRunScraper, TriggerSourceScrapeHandler(scraperService))
 		api.GET("/scraper/status", GetScraperStatusHandler())
 		api.GET("/scraper/status/:id", GetSourceScrapeStatusHandler())
 		
-		api.POST("/chat", ChatHandler())
+		api.POST("/chat", ChatHandler(ai.NewChatService(cfg.AI), cfg.AI.ChatTimeout.Duration))
+
+		api.GET("/users/me", GetCurrentUserHandler(userService))
+		api.POST("/users/me/password", sessionOnly, ChangeOwnPasswordHandler(userService, authService, throttle))
+		api.GET("/users/me/2fa", sessionOnly, GetMFAStatusHandler(authService))
+		api.DELETE("/users/me/2fa", sessionOnly, DisableMFAHandler(authService, throttle))
+		api.POST("/users/me/2fa/enroll", sessionOnly, EnrollMFAHandler(authService))
+		api.POST("/users/me/2fa/verify", sessionOnly, ConfirmMFAHandler(authService))
+		api.POST("/users/me/2fa/recovery-codes", sessionOnly, RegenerateRecoveryCodesHandler(authService))
+		api.GET("/users/me/api-keys", sessionOnly, ListMyAPIKeysHandler(apiKeyService))
+		api.POST("/users/me/api-keys", sessionOnly, CreateAPIKeyHandler(apiKeyService))
+		api.DELETE("/users/me/api-keys/:keyId", sessionOnly, RevokeMyAPIKeyHandler(apiKeyService))
+		api.GET("/users", canManageUsers, GetUsersHandler(userService))
+		api.POST("/users", canManageUsers, CreateUserHandler(userService))
+		api.GET("/users/:id", canManageUsers, GetUserHandler(userService))
+		api.PUT("/users/:id", canManageUsers, UpdateUserHandler(userService))
+		api.DELETE("/users/:id", canManageUsers, DeleteUserHandler(userService))
+		api.GET("/users/:id/sessions", canManageUsers, GetUserSessionsHandler(userService, authService))
+		api.DELETE("/users/:id/sessions", canManageUsers, RevokeUserSessionsHandler(userService, authService))
+		api.DELETE("/users/:id/2fa", canManageUsers, ResetUserMFAHandler(authService))
+		api.GET("/users/:id/api-keys", canManageUsers, GetUserAPIKeysHandler(apiKeyService))
+		api.DELETE("/users/:id/api-keys/:keyId", canManageUsers, RevokeUserAPIKeyHandler(apiKeyService))
+		api.GET("/auth/2fa-policy", canManageUsers, GetMFAPolicyHandler(authService))
+		api.PUT("/auth/2fa-policy", canManageUsers, UpdateMFAPolicyHandler(authService))
+
+		api.GET("/audit", RequirePermission(service.PermAuditRead), GetAuditLogHandler(recorder))
+		api.GET("/admin/config", RequirePermission(service.PermConfigRead), GetConfigHandler(cfg))
 	}
 
 	router.Static("/static", "./frontend/static")
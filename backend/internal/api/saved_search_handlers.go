@@ -0,0 +1,225 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/service"
+)
+
+/*Bu fonksiyon, istekteki kullanıcının kimliğini AuthMiddleware'in context'e eklediği
+user_id değerinden okur. Kayıtlı aramalar kullanıcıya özel olduğu için kimliği
+bulunamayan (ör. user_id içermeyen eski bir token ile gelen) istekler 401 ile reddedilir.
+*/
+func requireUserID(c *gin.Context) (int, bool) {
+	userID := c.GetInt("user_id")
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session is missing user information, please log in again"})
+		return 0, false
+	}
+	return userID, true
+}
+
+func savedSearchID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeSavedSearchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSavedSearchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+	case isEntryFilterError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+/*Bu fonksiyon, oturumdaki kullanıcının kayıtlı aramalarını listeleyen handler'dır.
+Her arama; filtresi, zamanlaması, son çalıştırma zamanı ve son çalıştırmada bulunan
+yeni kayıt sayısıyla birlikte döndürülür.
+*/
+func ListSavedSearchesHandler(savedSearchService *service.SavedSearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		searches, err := savedSearchService.List(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"saved_searches": searches})
+	}
+}
+
+func GetSavedSearchHandler(savedSearchService *service.SavedSearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := savedSearchID(c)
+		if !ok {
+			return
+		}
+
+		search, err := savedSearchService.Get(userID, id)
+		if err != nil {
+			writeSavedSearchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, search)
+	}
+}
+
+/*Bu fonksiyon, yeni bir kayıtlı arama oluşturan handler'dır. Gövdede name, filter
+(GET /api/entries parametreleriyle aynı alanlar; sort ve order dahil) ve isteğe bağlı
+schedule_minutes beklenir. Geçersiz filtre veya aynı isimde başka bir arama 400 ile
+reddedilir; başarılı olursa oluşturulan arama 201 Created ile döner.
+*/
+func CreateSavedSearchHandler(savedSearchService *service.SavedSearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		var req service.SavedSearchInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		search, err := savedSearchService.Create(userID, req)
+		if err != nil {
+			writeSavedSearchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, search)
+	}
+}
+
+func UpdateSavedSearchHandler(savedSearchService *service.SavedSearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := savedSearchID(c)
+		if !ok {
+			return
+		}
+
+		var req service.SavedSearchInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		search, err := savedSearchService.Update(userID, id, req)
+		if err != nil {
+			writeSavedSearchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, search)
+	}
+}
+
+func DeleteSavedSearchHandler(savedSearchService *service.SavedSearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := savedSearchID(c)
+		if !ok {
+			return
+		}
+
+		if err := savedSearchService.Delete(userID, id); err != nil {
+			writeSavedSearchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+	}
+}
+
+/*Bu fonksiyon, kayıtlı aramayı çalıştırıp sonuçlarını GET /api/entries ile aynı
+biçimde döndüren handler'dır. page, pageSize ve cursor parametreleri desteklenir.
+new_only=true verilirse yalnızca son çalıştırmada yeni olarak kaydedilen kayıtlar
+listelenir; bu, bildirim veya günlük özet ekranları için kullanılır.
+*/
+func GetSavedSearchResultsHandler(savedSearchService *service.SavedSearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := savedSearchID(c)
+		if !ok {
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+		newOnly, _ := strconv.ParseBool(c.DefaultQuery("new_only", "false"))
+
+		result, err := savedSearchService.Results(userID, id, service.PageRequest{
+			Page:     page,
+			PageSize: pageSize,
+			Cursor:   c.Query("cursor"),
+		}, newOnly)
+		if err != nil {
+			writeSavedSearchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"entries":     result.Entries,
+			"total":       result.Total,
+			"page":        page,
+			"pageSize":    pageSize,
+			"next_cursor": result.NextCursor,
+		})
+	}
+}
+
+/*Bu fonksiyon, kayıtlı aramayı zamanlamayı beklemeden hemen çalıştırır ve son
+çalıştırmadan bu yana eşleşen yeni kayıtların sayısını döndürür. Zamanlanmış bir
+aramada bir sonraki çalıştırma zamanı da bu andan itibaren yeniden hesaplanır.
+*/
+func RunSavedSearchHandler(savedSearchService *service.SavedSearchService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+		id, ok := savedSearchID(c)
+		if !ok {
+			return
+		}
+
+		count, err := savedSearchService.Run(userID, id)
+		if err != nil {
+			writeSavedSearchError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"new_matches": count})
+	}
+}
@@ -1,7 +1,7 @@
 package api
 
 import (
-	"log"
+	"context"
 	"net/http"
 	"strconv"
 
@@ -19,12 +19,12 @@ planda çalıştırmayı ve kullanıcıya anında geri bildirim vermeyi sağlar.
 */
 func TriggerManualScrapeHandler(scraperService *scraper.ScraperService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Println("[SCRAPER] TRIGGERED: Manual scrape of all sources requested via API")
+		logger.InfoContext(c.Request.Context(), "manual scrape of all sources requested")
 		
 		
 		status, err := scraper.CheckTorReadiness()
 		if err != nil || !status.IsReady {
-			log.Printf("[SCRAPER] WARNING: Tor not ready when manual scrape triggered: %s", status.Message)
+			logger.WarnContext(c.Request.Context(), "Tor not ready, manual scrape rejected", "tor_message", status.Message)
 			c.JSON(http.StatusServiceUnavailable, gin.H{
 				"error":   "Tor not ready",
 				"message": status.Message,
@@ -34,11 +34,8 @@ func TriggerManualScrapeHandler(scraperService *scraper.ScraperService) gin.Hand
 		}
 		
 		
-		go func() {
-			log.Println("[SCRAPER] Starting manual scrape of all sources in background...")
-			scraperService.ScrapeAll()
-			log.Println("[SCRAPER] Finished manual scrape of all sources")
-		}()
+		// The scrape keeps the request ID for correlation but outlives the request
+		go scraperService.ScrapeAll(context.WithoutCancel(c.Request.Context()))
 
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Scraping started in background. Sources will be scraped shortly.",
@@ -61,7 +58,6 @@ func TriggerSourceScrapeHandler(scraperService *scraper.ScraperService) gin.Hand
 		sourceIDStr := c.Param("id")
 		sourceID, err := strconv.Atoi(sourceIDStr)
 		if err != nil {
-			log.Printf("[SCRAPER] ERROR: Invalid source ID in request: %s", sourceIDStr)
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "Invalid source ID",
 				"message": "Source ID must be a valid number",
@@ -69,7 +65,7 @@ func TriggerSourceScrapeHandler(scraperService *scraper.ScraperService) gin.Hand
 			return
 		}
 
-		log.Printf("[SCRAPER] TRIGGERED: Manual scrape requested for source ID: %d", sourceID)
+		logger.InfoContext(c.Request.Context(), "manual scrape requested", "source_id", sourceID)
 
 		
 		existingState := scraper.GetScrapeState(sourceID)
@@ -84,11 +80,7 @@ func TriggerSourceScrapeHandler(scraperService *scraper.ScraperService) gin.Hand
 		}
 
 		
-		go func(id int) {
-			log.Printf("[SCRAPER] Starting manual scrape for source ID: %d", id)
-			scraperService.ScrapeSource(id)
-			log.Printf("[SCRAPER] Finished manual scrape for source ID: %d", id)
-		}(sourceID)
+		go scraperService.ScrapeSource(context.WithoutCancel(c.Request.Context()), sourceID)
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":   "Tarama başlatıldı. Kaynak arka planda taranıyor...",
@@ -1,16 +1,38 @@
 package api
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/logging"
 	"interactive-scraper/internal/scraper"
 	"interactive-scraper/internal/service"
 )
 
+// sourceRequest is the body of create and update; transport defaults to tor
+type sourceRequest struct {
+	Name      string `json:"name" binding:"required"`
+	URL       string `json:"url" binding:"required"`
+	Transport string `json:"transport"`
+	ProxyURL  string `json:"proxy_url"`
+}
+
+func (r sourceRequest) spec() service.SourceSpec {
+	return service.SourceSpec{Name: r.Name, URL: r.URL, Transport: r.Transport, ProxyURL: r.ProxyURL}
+}
+
+// sourceErrorStatus maps rejected source input to 400 and anything else to 500
+func sourceErrorStatus(err error) int {
+	if errors.Is(err, service.ErrInvalidSource) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 /*Bu fonksiyon, Gin framework üzerinde çalışan ve tüm kaynakları listeleyen API handler’dır.
 sourceService.GetAllSources() çağrısıyla veritabanındaki kaynaklar alınır; hata oluşursa
 500 Internal Server Error döner. Başarılı olursa, kaynaklar JSON formatında 200 OK yanıtı ile
@@ -55,47 +77,43 @@ func GetSourceHandler(sourceService *service.SourceService) gin.HandlerFunc {
 }
 
 /*Bu fonksiyon, Gin framework üzerinde çalışan ve yeni bir kaynağı oluşturan API
-handler’dır. İstek gövdesinden JSON ile Name ve URL bilgileri alınır; eksik veya geçersizse
-400 Bad Request döner. sourceService.CreateSource ile veritabanına yeni kaynak eklenir;
-hata oluşursa 500 Internal Server Error döner. Kaynak başarıyla eklendikten sonra, arka
-planda bir goroutine ile otomatik scraping işlemi başlatılır. Bu süreçte Tor servisinin hazır
-olması beklenir; hazır değilse sonraki periyodik taramada işleme alınır. Kullanıcıya ise
+handler’dır. İstek gövdesinden JSON ile Name, URL ve isteğe bağlı transport ile proxy_url
+bilgileri alınır; eksik veya geçersizse 400 Bad Request döner. sourceService.CreateSource ile
+veritabanına yeni kaynak eklenir; hata oluşursa 500 Internal Server Error döner. Kaynak
+başarıyla eklendikten sonra, arka planda bir goroutine ile otomatik scraping işlemi
+başlatılır. Kaynak Tor üzerinden çekiliyorsa bu süreçte Tor servisinin hazır olması
+beklenir; hazır değilse sonraki periyodik taramada işleme alınır. Kullanıcıya ise
 oluşturulan kaynak JSON formatında 200 OK yanıtıyla iletilir. Bu handler, API’de kaynak
 ekleme ve eklenen kaynağın otomatik olarak taranmasını güvenli ve asenkron şekilde
 sağlar
 */
 func CreateSourceHandler(sourceService *service.SourceService, scraperService *scraper.ScraperService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			Name string `json:"name" binding:"required"`
-			URL  string `json:"url" binding:"required"`
-		}
-
+		var req sourceRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 			return
 		}
 
-		source, err := sourceService.CreateSource(req.Name, req.URL)
+		source, err := sourceService.CreateSource(req.spec())
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(sourceErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
 		
-		log.Printf("[SCRAPER] TRIGGERED: Source '%s' added (ID: %d), URL: %s", source.Name, source.ID, source.URL)
-		go func(sourceID int, sourceName, sourceURL string) {
-			log.Printf("[SCRAPER] Waiting for Tor to be ready for auto-scrape of source ID %d...", sourceID)
-			if err := scraper.WaitForTorReady(10, 3*time.Second); err != nil {
-				log.Printf("[SCRAPER] WARNING: Tor did not become ready for auto-scrape of source ID %d: %v", sourceID, err)
-				log.Printf("[SCRAPER] Source will be scraped on next periodic scrape cycle")
-				return
+		logger.InfoContext(c.Request.Context(), "source added, scheduling automatic scrape",
+			"source_id", source.ID, "source_name", source.Name, "url", source.URL)
+		go func(ctx context.Context, sourceID int, transport string) {
+			if transport == service.TransportTor {
+				if err := scraper.WaitForTorReady(ctx, 10, 3*time.Second); err != nil {
+					logger.WarnContext(ctx, "Tor not ready, source will be scraped in the next cycle",
+						"source_id", sourceID, logging.Err(err))
+					return
+				}
 			}
-			
-			log.Printf("[SCRAPER] Tor is ready. Starting automatic scrape for newly added source - ID: %d, Name: %s, URL: %s", sourceID, sourceName, sourceURL)
-			scraperService.ScrapeSource(sourceID)
-			log.Printf("[SCRAPER] Finished automatic scrape for source ID: %d", sourceID)
-		}(source.ID, source.Name, source.URL)
+			scraperService.ScrapeSource(ctx, sourceID)
+		}(context.WithoutCancel(c.Request.Context()), source.ID, source.Transport)
 
 		c.JSON(http.StatusOK, source)
 	}
@@ -103,8 +121,8 @@ func CreateSourceHandler(sourceService *service.SourceService, scraperService *s
 
 /*Bu fonksiyon, Gin framework üzerinde çalışan ve var olan bir kaynağın bilgilerini
 güncelleyen API handler’dır. URL’den alınan id parametresi tamsayıya dönüştürülür;
-geçersizse 400 Bad Request döner. İstek gövdesinden JSON ile Name ve URL bilgileri alınır;
-eksik veya geçersizse yine 400 hatası döner. sourceService.UpdateSource ile ilgili kaynak
+geçersizse 400 Bad Request döner. İstek gövdesinden JSON ile Name, URL, transport ve
+proxy_url bilgileri alınır; eksik veya geçersizse yine 400 hatası döner. sourceService.UpdateSource ile ilgili kaynak
 veritabanında güncellenir; hata oluşursa 500 Internal Server Error döner. Başarılı olursa,
 kullanıcıya “Source updated successfully” mesajı ile 200 OK yanıtı gönderilir. Bu handler,
 API’de kaynakların güvenli ve kontrollü bir şekilde güncellenmesini sağlar.
@@ -117,18 +135,14 @@ func UpdateSourceHandler(sourceService *service.SourceService) gin.HandlerFunc {
 			return
 		}
 
-		var req struct {
-			Name string `json:"name" binding:"required"`
-			URL  string `json:"url" binding:"required"`
-		}
-
+		var req sourceRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 			return
 		}
 
-		if err := sourceService.UpdateSource(id, req.Name, req.URL); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := sourceService.UpdateSource(id, req.spec()); err != nil {
+			c.JSON(sourceErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
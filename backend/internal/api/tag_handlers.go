@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/service"
+)
+
+func writeTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+/*Bu fonksiyon, tüm etiketleri renk, açıklama ve kaç kayıt ile kaç kaynakta
+kullanıldıkları bilgisiyle birlikte listeleyen handler'dır.
+*/
+func GetTagsHandler(tagService *service.TagService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tags, err := tagService.GetAllTags()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"tags": tags})
+	}
+}
+
+/*Bu fonksiyon, yeni bir etiket oluşturan handler'dır. Gövdede name, isteğe bağlı color
+(#rrggbb) ve description beklenir. Aynı isimde (büyük/küçük harf farkı gözetmeksizin)
+bir etiket varsa veya renk geçersizse 400 Bad Request döner.
+*/
+func CreateTagHandler(tagService *service.TagService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.TagInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		tag, err := tagService.CreateTag(req)
+		if err != nil {
+			writeTagError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, tag)
+	}
+}
+
+func UpdateTagHandler(tagService *service.TagService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+			return
+		}
+
+		var req service.TagInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		tag, err := tagService.UpdateTag(id, req)
+		if err != nil {
+			writeTagError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, tag)
+	}
+}
+
+func DeleteTagHandler(tagService *service.TagService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+			return
+		}
+
+		if err := tagService.DeleteTag(id); err != nil {
+			writeTagError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+	}
+}
+
+/*Bu fonksiyon, etiketleri kayıtlara ve kaynaklara toplu olarak ekleyen veya onlardan
+kaldıran handler'dır. Gövdede action (add/remove), tag_ids ve/veya tag_names ile
+entry_ids ve/veya source_ids beklenir. Kaynaklara eklenen etiketler için auto_apply,
+o kaynaktan gelecek yeni kayıtların otomatik etiketlenip etiketlenmeyeceğini belirler.
+İşlem tek transaction içinde yapılır ve etkilenen ilişki sayıları döner.
+*/
+func BulkTagHandler(tagService *service.TagService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.TagBulkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		result, err := tagService.BulkApply(req)
+		if err != nil {
+			writeTagError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
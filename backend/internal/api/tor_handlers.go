@@ -1,20 +1,22 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/logging"
 	"interactive-scraper/internal/scraper"
 )
 
 /*Bu fonksiyon, Gin framework üzerinde çalışan ve Tor servisinin durumunu sorgulayan API
-handler’dır. scraper.CheckTorStatus() ile Tor’un mevcut durumu alınır; hata oluşsa bile
+handler’dır. scraper.CheckTorStatus ile Tor’un mevcut durumu alınır; hata oluşsa bile
 durum nesnesi JSON formatında 200 OK yanıtı ile istemciye iletilir. Bu handler, uygulamada
 Tor ağının kullanılabilirliğini ve durumunu güvenli bir şekilde izlemeyi sağlar.
 */
 func GetTorStatusHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		status, err := scraper.CheckTorStatus()
+		status, err := scraper.CheckTorStatus(c.Request.Context())
 		if err != nil {
 			
 			c.JSON(http.StatusOK, status)
@@ -25,3 +27,50 @@ func GetTorStatusHandler() gin.HandlerFunc {
 	}
 }
 
+
+/*Bu fonksiyon, Tor'un control portundan okunan durumu döndürür: sürüm, bootstrap aşaması,
+devreler ve her devredeki stream sayısı, çıkış IP'si, ardışık başarısız fetch sayısı ve son
+NEWNYM zamanı. Control port ayarlı değilse veya Tor'a ulaşılamıyorsa 503 döner; status
+alanı iki durumu ayırt eder (disabled, unavailable).
+*/
+func GetTorControlHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := scraper.GetTorControlStatus(c.Request.Context())
+		if err != nil {
+			torControlError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// RequestNewnymHandler asks Tor for new circuits, which changes the exit IP of later fetches
+func RequestNewnymHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := scraper.RequestNewnym(c.Request.Context(), "manual"); err != nil {
+			torControlError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "New Tor circuits requested. Tor applies NEWNYM at most once every 10 seconds.",
+			"status":  "requested",
+		})
+	}
+}
+
+func torControlError(c *gin.Context, err error) {
+	if errors.Is(err, scraper.ErrTorControlDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   err.Error(),
+			"message": "Set TOR_CONTROL_ADDR to enable the Tor control port integration",
+			"status":  "disabled",
+		})
+		return
+	}
+	logger.WarnContext(c.Request.Context(), "Tor control port request failed", logging.Err(err))
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "Tor control port request failed",
+		"message": err.Error(),
+		"status":  "unavailable",
+	})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/tracing"
+)
+
+/*Bu fonksiyon, her HTTP isteği için bir sunucu span'i başlatan middleware'dir. İstek bir
+traceparent başlığı taşıyorsa span çağıranın izine bağlanır. Span adı, metrik etiketlerinde
+olduğu gibi gerçek yol yerine rota kalıbından oluşur (ör. "GET /api/entries/:id"); isteğin
+korelasyon ID'si span'e eklenir, böylece loglardan ize ve izden loglara geçilebilir.
+İzleme sistemlerinin sık yaptığı /metrics, /healthz ve /readyz istekleri izlenmez. 5xx yanıtlar hata olarak işaretlenir.
+RequestLogger'dan sonra kullanılmalıdır.
+*/
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if probePaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Start(ctx, c.Request.Method, tracing.KindServer,
+			tracing.String("http.request.method", c.Request.Method),
+			tracing.String("url.path", c.Request.URL.Path),
+			tracing.String("client.address", c.ClientIP()),
+			tracing.String("correlation_id", logging.CorrelationID(ctx)))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if route := c.FullPath(); route != "" {
+			span.SetName(c.Request.Method + " " + route)
+			span.SetAttributes(tracing.String("http.route", route))
+		}
+		span.SetAttributes(tracing.Int("http.response.status_code", status))
+		if username := c.GetString("username"); username != "" {
+			span.SetAttributes(tracing.String("enduser.id", username))
+		}
+		if status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+		}
+	}
+}
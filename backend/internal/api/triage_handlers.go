@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/service"
+)
+
+func writeTriageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+	case errors.Is(err, service.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parent note not found"})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee user not found"})
+	case errors.Is(err, service.ErrEmptyNote):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case isEntryFilterError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+/*Bu fonksiyon, bir kaydın triage durumunu değiştiren handler'dır. Gövdede status
+(new, in_review, escalated, false_positive, closed) beklenir. İzin verilmeyen bir geçiş
+(ör. closed -> escalated) 409 Conflict ile reddedilir. Atanmamış bir kayıt incelemeye
+alındığında işlemi yapan kullanıcı kayda atanır. Başarılı olursa kaydın güncel hali döner.
+*/
+func UpdateTriageStatusHandler(triageService *service.TriageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+			return
+		}
+
+		var req struct {
+			Status string `json:"status" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		entry, err := triageService.TransitionStatus(id, req.Status, c.GetInt("user_id"), c.GetString("username"))
+		if err != nil {
+			writeTriageError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+/*Bu fonksiyon, kaydı bir analiste atayan handler'dır. Gövdede assignee_id beklenir;
+null verilirse atama kaldırılır. Alan hiç gönderilmezse istek geçersiz sayılır, böylece
+boş bir gövde yanlışlıkla atamayı silmez.
+*/
+func UpdateAssigneeHandler(triageService *service.TriageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+			return
+		}
+
+		var req struct {
+			AssigneeID json.RawMessage `json:"assignee_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.AssigneeID) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		var assigneeID *int
+		if err := json.Unmarshal(req.AssigneeID, &assigneeID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignee_id"})
+			return
+		}
+
+		entry, err := triageService.Assign(id, assigneeID, c.GetString("username"))
+		if err != nil {
+			writeTriageError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+/*Bu fonksiyon, oturumdaki analistin kuyruğunu listeleyen handler'dır. Kendisine
+atanmış ve açık durumda (new, in_review, escalated) olan kayıtlar döner; triage_status
+ve GET /api/entries ile aynı diğer filtreler, sıralama ve sayfalama parametreleri
+desteklenir. Varsayılan sıralama kritikliğe göre azalandır.
+*/
+func GetMyQueueHandler(triageService *service.TriageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+		filter, err := parseEntryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := triageService.Queue(userID, filter, service.PageRequest{
+			Page:     page,
+			PageSize: pageSize,
+			Cursor:   c.Query("cursor"),
+		})
+		if err != nil {
+			writeTriageError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"entries":     result.Entries,
+			"total":       result.Total,
+			"page":        page,
+			"pageSize":    pageSize,
+			"next_cursor": result.NextCursor,
+		})
+	}
+}
+
+func GetEntryNotesHandler(triageService *service.TriageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+			return
+		}
+
+		notes, err := triageService.GetNotes(id)
+		if err != nil {
+			writeTriageError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"notes": notes})
+	}
+}
+
+/*Bu fonksiyon, kayda analist notu ekleyen handler'dır. Gövdede body ve isteğe bağlı
+parent_id beklenir; parent_id verilirse not, aynı kayıttaki ilgili nota yanıt olarak
+eklenir. Oluşturulan not 201 Created ile döner.
+*/
+func CreateEntryNoteHandler(triageService *service.TriageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+			return
+		}
+
+		var req struct {
+			Body     string `json:"body" binding:"required"`
+			ParentID *int   `json:"parent_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		note, err := triageService.AddNote(id, req.ParentID, userID, req.Body)
+		if err != nil {
+			writeTriageError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, note)
+	}
+}
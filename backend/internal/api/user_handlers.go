@@ -0,0 +1,246 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"interactive-scraper/internal/service"
+)
+
+func targetUserID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, service.ErrInvalidUser):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWrongPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+/*Bu fonksiyon, oturumdaki kullanıcının bilgilerini ve rolünün sahip olduğu yetkileri
+döndüren handler'dır. Frontend, hangi işlemleri göstereceğine bu yetki listesine göre
+karar verir.
+*/
+func GetCurrentUserHandler(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		user, err := userService.GetUserByID(id)
+		if err != nil {
+			writeUserError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+/*Bu fonksiyon, oturumdaki kullanıcının kendi şifresini değiştirdiği handler'dır. Gövdede
+current_password ve new_password beklenir; yeni şifre yapılandırılmış şifre politikasına
+uymalıdır. Yanlış mevcut şifre denemeleri giriş denemeleriyle aynı sayaca yazılır, böylece
+bu rota şifre tahmini için kullanılamaz. Başarılı değişiklikte diğer oturumlar kapatılır
+ve mevcut oturum için şifre değiştirme zorunluluğu taşımayan yeni token'lar döndürülür.
+*/
+func ChangeOwnPasswordHandler(userService *service.UserService, authService *service.AuthService, throttle *service.LoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := requireUserID(c)
+		if !ok {
+			return
+		}
+
+		var req service.ChangePasswordInput
+		if err := c.ShouldBindJSON(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "current_password and new_password are required"})
+			return
+		}
+
+		username := c.GetString("username")
+		if wait := throttle.Check(username, c.ClientIP()); wait > 0 {
+			writeTooManyAttempts(c, wait)
+			return
+		}
+
+		sessionID := c.GetString("session_id")
+		if err := userService.ChangePassword(id, req, sessionID); err != nil {
+			if errors.Is(err, service.ErrWrongPassword) {
+				throttle.Failure(username, c.ClientIP())
+			}
+			writeUserError(c, err)
+			return
+		}
+		throttle.Success(username)
+
+		tokens, err := authService.ReissueSession(sessionID, sessionMeta(c))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "Password changed, please log in again"})
+			return
+		}
+
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+func GetUsersHandler(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		users, err := userService.GetAllUsers()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, users)
+	}
+}
+
+func GetUserHandler(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+
+		user, err := userService.GetUserByID(id)
+		if err != nil {
+			writeUserError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+/*Bu fonksiyon, yeni kullanıcı oluşturan handler'dır. İstek gövdesinde username,
+password ve role (viewer, analyst veya admin; varsayılan analyst) beklenir. Kullanıcı
+adı alınmışsa veya şifre çok kısaysa 400 Bad Request döner.
+*/
+func CreateUserHandler(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.CreateUserInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		user, err := userService.CreateUser(req)
+		if err != nil {
+			writeUserError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, user)
+	}
+}
+
+/*Bu fonksiyon, kullanıcının rolünü ve/veya şifresini güncelleyen handler'dır. Gövdede
+yalnızca değiştirilecek alanlar gönderilir. Son admin kullanıcısının rolü düşürülmek
+istenirse 409 Conflict döner.
+*/
+func UpdateUserHandler(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+
+		var req service.UpdateUserInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if req.Role == nil && req.Password == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update, provide role and/or password"})
+			return
+		}
+
+		user, err := userService.UpdateUser(id, req)
+		if err != nil {
+			writeUserError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+func DeleteUserHandler(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+
+		if err := userService.DeleteUser(id); err != nil {
+			writeUserError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	}
+}
+
+func GetUserSessionsHandler(userService *service.UserService, authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+		if _, err := userService.GetUserByID(id); err != nil {
+			writeUserError(c, err)
+			return
+		}
+
+		sessions, err := authService.ListUserSessions(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, sessions)
+	}
+}
+
+/*Bu fonksiyon, bir kullanıcının tüm açık oturumlarını ve API anahtarlarını iptal eden
+handler'dır. İptal edilen oturumların refresh token'ları ve erişim token'ları hemen
+geçersiz olur; kullanıcının yeniden giriş yapması ve gerekiyorsa yeni anahtar oluşturması
+gerekir. İptal edilen oturum sayısı "revoked", anahtar sayısı "api_keys_revoked" alanında
+döndürülür.
+*/
+func RevokeUserSessionsHandler(userService *service.UserService, authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := targetUserID(c)
+		if !ok {
+			return
+		}
+		if _, err := userService.GetUserByID(id); err != nil {
+			writeUserError(c, err)
+			return
+		}
+
+		revoked, apiKeysRevoked, err := authService.RevokeUserSessions(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"revoked": revoked, "api_keys_revoked": apiKeysRevoked})
+	}
+}
@@ -0,0 +1,217 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"interactive-scraper/internal/logging"
+)
+
+var logger = logging.For("audit")
+
+const (
+	ActorSystem = "system"
+	// ActorCLI marks changes made with the command line tool rather than through the API
+	ActorCLI = "cli"
+
+	TargetEntry       = "entry"
+	TargetSource      = "source"
+	TargetTag         = "tag"
+	TargetSavedSearch = "saved_search"
+	TargetUser        = "user"
+)
+
+/*Bu yapı (Entry), denetim kaydındaki tek bir satırı temsil eder. Actor işlemi yapan
+kullanıcı adı ya da sistem işlemleri için "system" değeridir. Before ve After, hedefin
+işlemden önceki ve sonraki halinin JSON anlık görüntüsüdür; Details ise istek gövdesi
+veya işleme özgü ek bilgiler için kullanılır. IP, bağlantının karşı ucunun adresidir;
+yalnızca server.trusted_proxies listesindeki bir vekilden gelen isteklerde vekilin
+X-Forwarded-For ile ilettiği istemci adresi yazılır, böylece istemci kendi adresini
+kayıtta değiştiremez. Kayıtlar yalnızca eklenebilir; tablo üzerindeki tetikleyici
+güncelleme ve silmeyi engeller.
+*/
+type Entry struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	ActorID    *int            `json:"actor_id,omitempty"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type,omitempty"`
+	TargetID   string          `json:"target_id,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	UserAgent  string          `json:"user_agent,omitempty"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Success    bool            `json:"success"`
+}
+
+/*Bu yapı (Recorder), denetim kayıtlarını veritabanına yazar ve sorgular. siem.Emitter
+gibi nil-güvenlidir: nil bir Recorder üzerinde Record çağrısı hiçbir şey yapmaz.
+Yazma işlemi senkron yapılır; denetim kaydı yazılamazsa hata loglanır ancak asıl
+işlem geri alınmaz.
+*/
+type Recorder struct {
+	db *sql.DB
+}
+
+func NewRecorder(db *sql.DB) *Recorder {
+	return &Recorder{db: db}
+}
+
+func (r *Recorder) Record(e Entry) {
+	if r == nil {
+		return
+	}
+	var actorID interface{}
+	if e.ActorID != nil && *e.ActorID != 0 {
+		actorID = *e.ActorID
+	}
+	if e.Actor == "" {
+		e.Actor = "anonymous"
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO audit_log (actor_id, actor, action, target_type, target_id,
+		                       before_value, after_value, details, ip, user_agent, status_code, success)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`, actorID, e.Actor, e.Action, nullString(e.TargetType), nullString(e.TargetID),
+		nullJSON(e.Before), nullJSON(e.After), nullJSON(e.Details),
+		nullString(e.IP), nullString(e.UserAgent), e.StatusCode, e.Success)
+	if err != nil {
+		logger.Error("failed to record audit entry", "action", e.Action, "actor", e.Actor, logging.Err(err))
+	}
+}
+
+// Snapshot marshals v for use as Before/After, returning nil for nil values
+func Snapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+/*Bu yapı (Filter), GET /api/audit sorgusundaki filtreleri tutar. Boş alanlar filtre
+uygulanmadığı anlamına gelir. Action öneki ile eşleşir; örneğin "PUT /api/entries"
+tüm kayıt güncellemelerini döndürür.
+*/
+type Filter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Success    *bool
+	From       *time.Time
+	To         *time.Time
+}
+
+type Page struct {
+	Entries []Entry `json:"entries"`
+	Total   int     `json:"total"`
+}
+
+func (r *Recorder) Query(f Filter, page, pageSize int) (*Page, error) {
+	if pageSize < 1 {
+		pageSize = 50
+	}
+	if pageSize > 500 {
+		pageSize = 500
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	var where []string
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if f.Action != "" {
+		add("action LIKE $%d || '%%'", escapeLike(f.Action))
+	}
+	if f.TargetType != "" {
+		add("target_type = $%d", f.TargetType)
+	}
+	if f.TargetID != "" {
+		add("target_id = $%d", f.TargetID)
+	}
+	if f.Success != nil {
+		add("success = $%d", *f.Success)
+	}
+	if f.From != nil {
+		add("occurred_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("occurred_at <= $%d", *f.To)
+	}
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	result := &Page{Entries: []Entry{}}
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_log`+whereClause, args...).Scan(&result.Total); err != nil {
+		return nil, err
+	}
+
+	args = append(args, pageSize, (page-1)*pageSize)
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT id, occurred_at, actor_id, actor, action, COALESCE(target_type, ''), COALESCE(target_id, ''),
+		       before_value, after_value, details, COALESCE(ip, ''), COALESCE(user_agent, ''),
+		       status_code, success
+		FROM audit_log%s
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Entry
+		var actorID sql.NullInt64
+		var before, after, details []byte
+		if err := rows.Scan(&e.ID, &e.OccurredAt, &actorID, &e.Actor, &e.Action, &e.TargetType, &e.TargetID,
+			&before, &after, &details, &e.IP, &e.UserAgent, &e.StatusCode, &e.Success); err != nil {
+			return nil, err
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			e.ActorID = &id
+		}
+		e.Before, e.After, e.Details = before, after, details
+		result.Entries = append(result.Entries, e)
+	}
+	return result, rows.Err()
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
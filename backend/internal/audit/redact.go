@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// sensitiveKeyParts marks JSON keys whose values must never reach the audit log
+var sensitiveKeyParts = []string{"password", "secret", "token", "totp", "recovery", "api_key", "code", "proxy_url"}
+
+// maxDetailsSize caps how much of a request body is stored in Details
+const maxDetailsSize = 16 * 1024
+
+/*Bu fonksiyon, istek gövdesini denetim kaydına yazılabilecek hale getirir. Gövde JSON
+ise içindeki parola, token, gizli anahtar gibi alanların değeri "[REDACTED]" ile
+değiştirilir; JSON değilse veya maxDetailsSize'dan büyükse yalnızca boyut bilgisi
+saklanır. Böylece denetim kaydı hassas bilgilerin yeni bir sızıntı noktası olmaz.
+*/
+func RedactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) > maxDetailsSize {
+		return Snapshot(map[string]interface{}{"body_size": len(body), "truncated": true})
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return Snapshot(map[string]interface{}{"body_size": len(body)})
+	}
+	return Snapshot(redactValue(v))
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if isSensitiveKey(k) {
+				t[k] = "[REDACTED]"
+				continue
+			}
+			t[k] = redactValue(val)
+		}
+		return t
+	case []interface{}:
+		for i, val := range t {
+			t[i] = redactValue(val)
+		}
+		return t
+	}
+	return v
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,470 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*Bu yapı (Config), uygulamanın tüm çalışma ayarlarını tek bir yerde toplar. Değerler önce
+Default ile varsayılanlara ayarlanır, ardından CONFIG_FILE ile verilen YAML veya TOML
+dosyasından, en son da ortam değişkenlerinden okunur; yani ortam değişkeni her zaman
+dosyadaki değeri ezer. Her alanın env etiketi onu ezen ortam değişkenini, secret etiketi
+ise GET /api/admin/config çıktısında gizlenmesi gerektiğini belirtir.
+*/
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server" json:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database" json:"database"`
+	Tor       TorConfig       `yaml:"tor" toml:"tor" json:"tor"`
+	Transport TransportConfig `yaml:"transport" toml:"transport" json:"transport"`
+	Scheduler SchedulerConfig `yaml:"scheduler" toml:"scheduler" json:"scheduler"`
+	AI        AIConfig        `yaml:"ai" toml:"ai" json:"ai"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth" json:"auth"`
+	Limits    LimitsConfig    `yaml:"limits" toml:"limits" json:"limits"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics" json:"metrics"`
+	Logging   LoggingConfig   `yaml:"logging" toml:"logging" json:"logging"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing" json:"tracing"`
+	SIEM      SIEMConfig      `yaml:"siem" toml:"siem" json:"siem"`
+	OIDC      OIDCConfig      `yaml:"oidc" toml:"oidc" json:"oidc"`
+
+	file         string
+	envOverrides []string
+}
+
+type ServerConfig struct {
+	Port int `yaml:"port" toml:"port" json:"port" env:"PORT"`
+	// TrustedProxies are the reverse proxy IPs or CIDRs whose X-Forwarded-For is believed; empty trusts none
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" json:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+type DatabaseConfig struct {
+	Host           string `yaml:"host" toml:"host" json:"host" env:"DB_HOST"`
+	Port           int    `yaml:"port" toml:"port" json:"port" env:"DB_PORT"`
+	User           string `yaml:"user" toml:"user" json:"user" env:"DB_USER"`
+	Password       string `yaml:"password" toml:"password" json:"password" env:"DB_PASSWORD" secret:"true"`
+	Name           string `yaml:"name" toml:"name" json:"name" env:"DB_NAME"`
+	SSLMode        string `yaml:"sslmode" toml:"sslmode" json:"sslmode" env:"DB_SSLMODE"`
+	MaxOpenConns   int    `yaml:"max_open_conns" toml:"max_open_conns" json:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	AutoMigrate    bool   `yaml:"auto_migrate" toml:"auto_migrate" json:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+	SearchLanguage string `yaml:"search_language" toml:"search_language" json:"search_language" env:"SEARCH_LANGUAGE"`
+	// AdminPassword seeds the admin account on an empty database
+	AdminPassword string `yaml:"admin_password" toml:"admin_password" json:"admin_password" env:"ADMIN_PASSWORD" secret:"true"`
+}
+
+type TorConfig struct {
+	// Proxy is the host:port of the Tor SOCKS5 listener
+	Proxy        string   `yaml:"proxy" toml:"proxy" json:"proxy" env:"TOR_PROXY"`
+	FetchTimeout Duration `yaml:"fetch_timeout" toml:"fetch_timeout" json:"fetch_timeout" env:"TOR_FETCH_TIMEOUT"`
+	// Proxies pools several Tor SOCKS5 listeners; when set it replaces Proxy
+	Proxies []string `yaml:"proxies" toml:"proxies" json:"proxies" env:"TOR_PROXIES"`
+	// Balance picks among healthy instances by "round_robin" or "least_loaded" (fewest fetches in flight)
+	Balance        string   `yaml:"balance" toml:"balance" json:"balance" env:"TOR_BALANCE"`
+	HealthInterval Duration `yaml:"health_interval" toml:"health_interval" json:"health_interval" env:"TOR_HEALTH_INTERVAL"`
+	// QuarantineAfter takes an instance out of rotation for Quarantine after this many fetches in a row could not reach it
+	QuarantineAfter int      `yaml:"quarantine_after" toml:"quarantine_after" json:"quarantine_after" env:"TOR_QUARANTINE_AFTER"`
+	Quarantine      Duration `yaml:"quarantine" toml:"quarantine" json:"quarantine" env:"TOR_QUARANTINE"`
+	// ControlAddress is the host:port of Tor's ControlPort; empty disables the control port integration.
+	// It belongs to one Tor instance: with Proxies, bootstrap status, circuits and NEWNYM cover only that instance
+	ControlAddress  string `yaml:"control_address" toml:"control_address" json:"control_address" env:"TOR_CONTROL_ADDR"`
+	ControlPassword string `yaml:"control_password" toml:"control_password" json:"control_password" env:"TOR_CONTROL_PASSWORD" secret:"true"`
+	// ControlCookieFile overrides the cookie path Tor reports, e.g. when the cookie is mounted from another container
+	ControlCookieFile string `yaml:"control_cookie_file" toml:"control_cookie_file" json:"control_cookie_file" env:"TOR_CONTROL_COOKIE_FILE"`
+	// Isolation gives each source ("source"), each scrape run ("run") or nobody ("none") its own Tor circuits
+	Isolation string `yaml:"isolation" toml:"isolation" json:"isolation" env:"TOR_ISOLATION"`
+	// NewnymAfterFailures requests new circuits after this many fetches in a row failed to connect; 0 disables it
+	NewnymAfterFailures int `yaml:"newnym_after_failures" toml:"newnym_after_failures" json:"newnym_after_failures" env:"TOR_NEWNYM_AFTER_FAILURES"`
+}
+
+// TransportConfig covers the sources that are not fetched through Tor
+type TransportConfig struct {
+	// I2PProxy is the URL of the I2P router's HTTP proxy, e.g. http://i2p:4444; empty makes I2P sources fail
+	I2PProxy   string   `yaml:"i2p_proxy" toml:"i2p_proxy" json:"i2p_proxy" env:"I2P_PROXY"`
+	I2PTimeout Duration `yaml:"i2p_timeout" toml:"i2p_timeout" json:"i2p_timeout" env:"I2P_FETCH_TIMEOUT"`
+	// FetchTimeout bounds fetches of sources using their own proxy or a direct connection
+	FetchTimeout Duration `yaml:"fetch_timeout" toml:"fetch_timeout" json:"fetch_timeout" env:"FETCH_TIMEOUT"`
+}
+
+type SchedulerConfig struct {
+	// Interval is the pause between two passes over all sources
+	Interval    Duration `yaml:"interval" toml:"interval" json:"interval" env:"SCRAPE_INTERVAL"`
+	SourceDelay Duration `yaml:"source_delay" toml:"source_delay" json:"source_delay" env:"SCRAPE_SOURCE_DELAY"`
+	// FetchRetries is how many times a failed fetch is attempted in total
+	FetchRetries        int      `yaml:"fetch_retries" toml:"fetch_retries" json:"fetch_retries" env:"SCRAPE_FETCH_RETRIES"`
+	RetryDelay          Duration `yaml:"retry_delay" toml:"retry_delay" json:"retry_delay" env:"SCRAPE_RETRY_DELAY"`
+	SavedSearchInterval Duration `yaml:"saved_search_interval" toml:"saved_search_interval" json:"saved_search_interval" env:"SAVED_SEARCH_INTERVAL"`
+}
+
+type AIConfig struct {
+	// ServiceURL empty disables entry analysis; chat then falls back to a local Ollama
+	ServiceURL  string   `yaml:"service_url" toml:"service_url" json:"service_url" env:"AI_SERVICE_URL"`
+	Model       string   `yaml:"model" toml:"model" json:"model" env:"AI_MODEL"`
+	Timeout     Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"AI_TIMEOUT"`
+	ChatTimeout Duration `yaml:"chat_timeout" toml:"chat_timeout" json:"chat_timeout" env:"AI_CHAT_TIMEOUT"`
+	// DockerHost, if set, replaces localhost in ServiceURL, e.g. host.docker.internal when Ollama runs on the Docker host
+	DockerHost string `yaml:"docker_host" toml:"docker_host" json:"docker_host" env:"AI_DOCKER_HOST"`
+}
+
+type AuthConfig struct {
+	JWTSecret         string   `yaml:"jwt_secret" toml:"jwt_secret" json:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	JWTSecretFile     string   `yaml:"jwt_secret_file" toml:"jwt_secret_file" json:"jwt_secret_file" env:"JWT_SECRET_FILE"`
+	JWTKeys           string   `yaml:"jwt_keys" toml:"jwt_keys" json:"jwt_keys" env:"JWT_KEYS" secret:"true"`
+	JWTKeysFile       string   `yaml:"jwt_keys_file" toml:"jwt_keys_file" json:"jwt_keys_file" env:"JWT_KEYS_FILE"`
+	JWTActiveKeyID    string   `yaml:"jwt_active_key_id" toml:"jwt_active_key_id" json:"jwt_active_key_id" env:"JWT_ACTIVE_KEY_ID"`
+	AccessTTL         Duration `yaml:"access_ttl" toml:"access_ttl" json:"access_ttl" env:"JWT_ACCESS_TTL"`
+	RefreshTTL        Duration `yaml:"refresh_ttl" toml:"refresh_ttl" json:"refresh_ttl" env:"JWT_REFRESH_TTL"`
+	TOTPIssuer        string   `yaml:"totp_issuer" toml:"totp_issuer" json:"totp_issuer" env:"TOTP_ISSUER"`
+	PasswordMinLength int      `yaml:"password_min_length" toml:"password_min_length" json:"password_min_length" env:"PASSWORD_MIN_LENGTH"`
+	// PasswordRequire lists the character classes (upper, lower, digit, symbol) every password needs
+	PasswordRequire []string `yaml:"password_require" toml:"password_require" json:"password_require" env:"PASSWORD_REQUIRE"`
+}
+
+type LimitsConfig struct {
+	LoginMaxAttempts      int      `yaml:"login_max_attempts" toml:"login_max_attempts" json:"login_max_attempts" env:"LOGIN_MAX_ATTEMPTS"`
+	LoginMaxAttemptsPerIP int      `yaml:"login_max_attempts_per_ip" toml:"login_max_attempts_per_ip" json:"login_max_attempts_per_ip" env:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LoginLockoutBase      Duration `yaml:"login_lockout_base" toml:"login_lockout_base" json:"login_lockout_base" env:"LOGIN_LOCKOUT_BASE"`
+	LoginLockoutMax       Duration `yaml:"login_lockout_max" toml:"login_lockout_max" json:"login_lockout_max" env:"LOGIN_LOCKOUT_MAX"`
+	LoginFailureWindow    Duration `yaml:"login_failure_window" toml:"login_failure_window" json:"login_failure_window" env:"LOGIN_FAILURE_WINDOW"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" json:"enabled" env:"METRICS_ENABLED"`
+	// Token, if set, must be sent as "Authorization: Bearer <token>" to read /metrics
+	Token string `yaml:"token" toml:"token" json:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+type LoggingConfig struct {
+	// Level applies to every subsystem without an entry in Levels
+	Level  string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" json:"format" env:"LOG_FORMAT"`
+	// Levels overrides the level per subsystem, e.g. scraper: debug; LOG_LEVELS takes "scraper=debug,tor=warn"
+	Levels map[string]string `yaml:"levels" toml:"levels" json:"levels" env:"LOG_LEVELS"`
+}
+
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" json:"enabled" env:"TRACING_ENABLED"`
+	// Endpoint is the base URL of an OTLP/HTTP collector; spans are posted to <endpoint>/v1/traces
+	Endpoint    string `yaml:"endpoint" toml:"endpoint" json:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName string `yaml:"service_name" toml:"service_name" json:"service_name" env:"OTEL_SERVICE_NAME"`
+	// SampleRatio is the share of new traces that are recorded; traces started by a caller follow its decision
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" json:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type SIEMConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" json:"enabled" env:"SIEM_ENABLED"`
+	// Format is cef or json; Transport is udp, tcp or tls (syslog) or file
+	Format         string `yaml:"format" toml:"format" json:"format" env:"SIEM_FORMAT"`
+	Transport      string `yaml:"transport" toml:"transport" json:"transport" env:"SIEM_TRANSPORT"`
+	Address        string `yaml:"address" toml:"address" json:"address" env:"SIEM_ADDRESS"`
+	Facility       string `yaml:"facility" toml:"facility" json:"facility" env:"SIEM_FACILITY"`
+	TLSCAFile      string `yaml:"tls_ca_file" toml:"tls_ca_file" json:"tls_ca_file" env:"SIEM_TLS_CA_FILE"`
+	TLSInsecure    bool   `yaml:"tls_insecure" toml:"tls_insecure" json:"tls_insecure" env:"SIEM_TLS_INSECURE"`
+	FilePath       string `yaml:"file_path" toml:"file_path" json:"file_path" env:"SIEM_FILE_PATH"`
+	FileMaxSizeMB  int    `yaml:"file_max_size_mb" toml:"file_max_size_mb" json:"file_max_size_mb" env:"SIEM_FILE_MAX_SIZE_MB"`
+	FileMaxBackups int    `yaml:"file_max_backups" toml:"file_max_backups" json:"file_max_backups" env:"SIEM_FILE_MAX_BACKUPS"`
+	// FieldMap renames event fields as "field:key" pairs, e.g. "title:msg,actor:-"; "-" drops the field
+	FieldMap string `yaml:"field_map" toml:"field_map" json:"field_map" env:"SIEM_FIELD_MAP"`
+	// AlertThreshold is the criticality that adds an alert event, 0 disables
+	AlertThreshold int `yaml:"alert_threshold" toml:"alert_threshold" json:"alert_threshold" env:"SIEM_ALERT_THRESHOLD"`
+}
+
+type OIDCConfig struct {
+	// IssuerURL empty disables single sign-on
+	IssuerURL        string `yaml:"issuer_url" toml:"issuer_url" json:"issuer_url" env:"OIDC_ISSUER_URL"`
+	ClientID         string `yaml:"client_id" toml:"client_id" json:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret     string `yaml:"client_secret" toml:"client_secret" json:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true"`
+	ClientSecretFile string `yaml:"client_secret_file" toml:"client_secret_file" json:"client_secret_file" env:"OIDC_CLIENT_SECRET_FILE"`
+	RedirectURL      string `yaml:"redirect_url" toml:"redirect_url" json:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	// Scopes are separated by spaces or commas
+	Scopes        string `yaml:"scopes" toml:"scopes" json:"scopes" env:"OIDC_SCOPES"`
+	ProviderName  string `yaml:"provider_name" toml:"provider_name" json:"provider_name" env:"OIDC_PROVIDER_NAME"`
+	UsernameClaim string `yaml:"username_claim" toml:"username_claim" json:"username_claim" env:"OIDC_USERNAME_CLAIM"`
+	GroupsClaim   string `yaml:"groups_claim" toml:"groups_claim" json:"groups_claim" env:"OIDC_GROUPS_CLAIM"`
+	// RoleMapping maps identity provider groups to roles as "group:role" pairs; group names keep their case
+	RoleMapping string `yaml:"role_mapping" toml:"role_mapping" json:"role_mapping" env:"OIDC_ROLE_MAPPING"`
+	DefaultRole string `yaml:"default_role" toml:"default_role" json:"default_role" env:"OIDC_DEFAULT_ROLE"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{Port: 8080},
+		Database: DatabaseConfig{
+			Host:           "postgres",
+			Port:           5432,
+			User:           "postgres",
+			Password:       "postgres",
+			Name:           "scraper_db",
+			SSLMode:        "disable",
+			MaxOpenConns:   25,
+			AutoMigrate:    true,
+			SearchLanguage: "english",
+		},
+		Tor: TorConfig{
+			Proxy:               "tor:9050",
+			FetchTimeout:        Duration{90 * time.Second},
+			Balance:             "round_robin",
+			HealthInterval:      Duration{30 * time.Second},
+			QuarantineAfter:     3,
+			Quarantine:          Duration{2 * time.Minute},
+			Isolation:           "source",
+			NewnymAfterFailures: 3,
+		},
+		Transport: TransportConfig{
+			I2PTimeout:   Duration{2 * time.Minute},
+			FetchTimeout: Duration{30 * time.Second},
+		},
+		Scheduler: SchedulerConfig{
+			Interval:            Duration{30 * time.Second},
+			SourceDelay:         Duration{2 * time.Second},
+			FetchRetries:        3,
+			RetryDelay:          Duration{5 * time.Second},
+			SavedSearchInterval: Duration{time.Minute},
+		},
+		AI: AIConfig{
+			Model:       "mistral",
+			Timeout:     Duration{30 * time.Second},
+			ChatTimeout: Duration{25 * time.Second},
+		},
+		Auth: AuthConfig{
+			AccessTTL:         Duration{15 * time.Minute},
+			RefreshTTL:        Duration{7 * 24 * time.Hour},
+			PasswordMinLength: 12,
+			PasswordRequire:   []string{"upper", "lower", "digit"},
+		},
+		Limits: LimitsConfig{
+			LoginMaxAttempts:      5,
+			LoginMaxAttemptsPerIP: 20,
+			LoginLockoutBase:      Duration{30 * time.Second},
+			LoginLockoutMax:       Duration{time.Hour},
+			LoginFailureWindow:    Duration{15 * time.Minute},
+		},
+		Metrics: MetricsConfig{Enabled: true},
+		Logging: LoggingConfig{Level: "info", Format: "json"},
+		Tracing: TracingConfig{
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "interactive-scraper",
+			SampleRatio: 1,
+		},
+		SIEM: SIEMConfig{
+			Format:         "json",
+			Transport:      "udp",
+			Facility:       "local0",
+			FilePath:       "./siem/events.log",
+			FileMaxSizeMB:  100,
+			FileMaxBackups: 5,
+			AlertThreshold: 80,
+		},
+		OIDC: OIDCConfig{
+			Scopes:        "openid profile email",
+			ProviderName:  "SSO",
+			UsernameClaim: "preferred_username",
+			GroupsClaim:   "groups",
+		},
+	}
+}
+
+// SOCKSProxies returns the pooled Tor SOCKS5 listeners, which is Proxy alone unless Proxies is set
+func (t TorConfig) SOCKSProxies() []string {
+	if len(t.Proxies) > 0 {
+		return t.Proxies
+	}
+	return []string{t.Proxy}
+}
+
+// File is the configuration file that was loaded, empty if none
+func (c *Config) File() string {
+	return c.file
+}
+
+// EnvOverrides lists the environment variables that overrode a default or file value
+func (c *Config) EnvOverrides() []string {
+	return append([]string(nil), c.envOverrides...)
+}
+
+var ErrInvalid = errors.New("invalid configuration")
+
+var (
+	searchLanguagePattern = regexp.MustCompile(`^[a-z_]+$`)
+	passwordClasses       = map[string]bool{"upper": true, "lower": true, "digit": true, "symbol": true}
+	logLevels             = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+	torIsolationModes     = map[string]bool{"source": true, "run": true, "none": true}
+	torBalanceModes       = map[string]bool{"round_robin": true, "least_loaded": true}
+	siemFormats           = map[string]bool{"cef": true, "json": true}
+	siemTransports        = map[string]bool{"udp": true, "tcp": true, "tls": true, "file": true}
+	sslModes              = map[string]bool{"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true}
+)
+
+// LogSubsystems are the names accepted in logging.levels
+var LogSubsystems = map[string]bool{
+	"api": true, "scraper": true, "tor": true, "ai": true, "database": true,
+	"auth": true, "saved_search": true, "siem": true, "audit": true, "tracing": true, "main": true,
+}
+
+/*Bu fonksiyon, ayarları açılışta doğrular ve bulduğu tüm sorunları tek bir hata içinde
+döndürür; böylece hatalı bir yapılandırma dosyası tek tek deneme yanılma yerine tek
+seferde düzeltilebilir. Hata mesajlarında alanın dosyadaki yolu ve onu ezen ortam
+değişkeni birlikte geçer.
+*/
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	positive := func(d Duration, name string) {
+		check(d.Duration > 0, "%s must be a positive duration, got %s", name, d)
+	}
+
+	check(c.Server.Port >= 1 && c.Server.Port <= 65535, "server.port (PORT) must be 1-65535, got %d", c.Server.Port)
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil,
+			"server.trusted_proxies (TRUSTED_PROXIES) entries must be an IP or CIDR, got %q", proxy)
+	}
+
+	check(c.Database.Host != "", "database.host (DB_HOST) is required")
+	check(c.Database.Port >= 1 && c.Database.Port <= 65535, "database.port (DB_PORT) must be 1-65535, got %d", c.Database.Port)
+	check(c.Database.User != "", "database.user (DB_USER) is required")
+	check(c.Database.Name != "", "database.name (DB_NAME) is required")
+	check(sslModes[c.Database.SSLMode], "database.sslmode (DB_SSLMODE) %q is not a PostgreSQL sslmode", c.Database.SSLMode)
+	check(c.Database.MaxOpenConns >= 1, "database.max_open_conns (DB_MAX_OPEN_CONNS) must be at least 1")
+	check(searchLanguagePattern.MatchString(c.Database.SearchLanguage),
+		"database.search_language (SEARCH_LANGUAGE) %q is not a text search configuration name", c.Database.SearchLanguage)
+
+	if _, port, err := net.SplitHostPort(c.Tor.Proxy); err != nil || port == "" {
+		problems = append(problems, fmt.Sprintf("tor.proxy (TOR_PROXY) must be host:port, got %q", c.Tor.Proxy))
+	}
+	for _, address := range c.Tor.Proxies {
+		if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("tor.proxies (TOR_PROXIES) entries must be host:port, got %q", address))
+		}
+	}
+	positive(c.Tor.FetchTimeout, "tor.fetch_timeout (TOR_FETCH_TIMEOUT)")
+	check(torBalanceModes[c.Tor.Balance], "tor.balance (TOR_BALANCE) must be round_robin or least_loaded, got %q", c.Tor.Balance)
+	check(c.Tor.HealthInterval.Duration >= time.Second, "tor.health_interval (TOR_HEALTH_INTERVAL) must be at least 1s, got %s", c.Tor.HealthInterval)
+	check(c.Tor.QuarantineAfter >= 1, "tor.quarantine_after (TOR_QUARANTINE_AFTER) must be at least 1")
+	positive(c.Tor.Quarantine, "tor.quarantine (TOR_QUARANTINE)")
+	if c.Tor.ControlAddress != "" {
+		if _, port, err := net.SplitHostPort(c.Tor.ControlAddress); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("tor.control_address (TOR_CONTROL_ADDR) must be host:port, got %q", c.Tor.ControlAddress))
+		}
+	}
+	check(torIsolationModes[c.Tor.Isolation], "tor.isolation (TOR_ISOLATION) must be source, run or none, got %q", c.Tor.Isolation)
+	check(c.Tor.NewnymAfterFailures >= 0, "tor.newnym_after_failures (TOR_NEWNYM_AFTER_FAILURES) must not be negative")
+
+	if c.Transport.I2PProxy != "" {
+		u, err := url.Parse(c.Transport.I2PProxy)
+		check(err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https"),
+			"transport.i2p_proxy (I2P_PROXY) must be an http(s) URL, got %q", c.Transport.I2PProxy)
+	}
+	positive(c.Transport.I2PTimeout, "transport.i2p_timeout (I2P_FETCH_TIMEOUT)")
+	positive(c.Transport.FetchTimeout, "transport.fetch_timeout (FETCH_TIMEOUT)")
+
+	check(c.Scheduler.Interval.Duration >= 5*time.Second, "scheduler.interval (SCRAPE_INTERVAL) must be at least 5s, got %s", c.Scheduler.Interval)
+	check(c.Scheduler.SourceDelay.Duration >= 0, "scheduler.source_delay (SCRAPE_SOURCE_DELAY) must not be negative")
+	check(c.Scheduler.FetchRetries >= 1 && c.Scheduler.FetchRetries <= 10,
+		"scheduler.fetch_retries (SCRAPE_FETCH_RETRIES) must be 1-10, got %d", c.Scheduler.FetchRetries)
+	check(c.Scheduler.RetryDelay.Duration >= 0, "scheduler.retry_delay (SCRAPE_RETRY_DELAY) must not be negative")
+	check(c.Scheduler.SavedSearchInterval.Duration >= 10*time.Second,
+		"scheduler.saved_search_interval (SAVED_SEARCH_INTERVAL) must be at least 10s, got %s", c.Scheduler.SavedSearchInterval)
+
+	if c.AI.ServiceURL != "" {
+		u, err := url.Parse(c.AI.ServiceURL)
+		check(err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https"),
+			"ai.service_url (AI_SERVICE_URL) must be an http(s) URL, got %q", c.AI.ServiceURL)
+	}
+	check(c.AI.Model != "", "ai.model (AI_MODEL) is required")
+	positive(c.AI.Timeout, "ai.timeout (AI_TIMEOUT)")
+	positive(c.AI.ChatTimeout, "ai.chat_timeout (AI_CHAT_TIMEOUT)")
+
+	check(c.Auth.AccessTTL.Duration >= time.Minute, "auth.access_ttl (JWT_ACCESS_TTL) must be at least 1m, got %s", c.Auth.AccessTTL)
+	check(c.Auth.RefreshTTL.Duration >= c.Auth.AccessTTL.Duration,
+		"auth.refresh_ttl (JWT_REFRESH_TTL) %s must not be shorter than auth.access_ttl %s", c.Auth.RefreshTTL, c.Auth.AccessTTL)
+	check(c.Auth.PasswordMinLength >= 8 && c.Auth.PasswordMinLength <= 128,
+		"auth.password_min_length (PASSWORD_MIN_LENGTH) must be 8-128, got %d", c.Auth.PasswordMinLength)
+	for _, class := range c.Auth.PasswordRequire {
+		check(passwordClasses[class], "auth.password_require (PASSWORD_REQUIRE): unknown class %q (expected upper, lower, digit or symbol)", class)
+	}
+
+	check(c.Limits.LoginMaxAttempts >= 1, "limits.login_max_attempts (LOGIN_MAX_ATTEMPTS) must be at least 1")
+	check(c.Limits.LoginMaxAttemptsPerIP >= 1, "limits.login_max_attempts_per_ip (LOGIN_MAX_ATTEMPTS_PER_IP) must be at least 1")
+	positive(c.Limits.LoginLockoutBase, "limits.login_lockout_base (LOGIN_LOCKOUT_BASE)")
+	positive(c.Limits.LoginFailureWindow, "limits.login_failure_window (LOGIN_FAILURE_WINDOW)")
+	check(c.Limits.LoginLockoutMax.Duration >= c.Limits.LoginLockoutBase.Duration,
+		"limits.login_lockout_max (LOGIN_LOCKOUT_MAX) %s must not be shorter than limits.login_lockout_base %s",
+		c.Limits.LoginLockoutMax, c.Limits.LoginLockoutBase)
+
+	check(logLevels[c.Logging.Level], "logging.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Logging.Level)
+	check(c.Logging.Format == "json" || c.Logging.Format == "text", "logging.format (LOG_FORMAT) must be json or text, got %q", c.Logging.Format)
+	subsystems := make([]string, 0, len(c.Logging.Levels))
+	for subsystem := range c.Logging.Levels {
+		subsystems = append(subsystems, subsystem)
+	}
+	sort.Strings(subsystems)
+	for _, subsystem := range subsystems {
+		check(LogSubsystems[subsystem], "logging.levels (LOG_LEVELS): unknown subsystem %q", subsystem)
+		check(logLevels[c.Logging.Levels[subsystem]], "logging.levels (LOG_LEVELS): level of %s must be debug, info, warn or error, got %q",
+			subsystem, c.Logging.Levels[subsystem])
+	}
+
+	if c.Tracing.Enabled {
+		u, err := url.Parse(c.Tracing.Endpoint)
+		check(err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https"),
+			"tracing.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) must be an http(s) URL, got %q", c.Tracing.Endpoint)
+		check(c.Tracing.ServiceName != "", "tracing.service_name (OTEL_SERVICE_NAME) is required")
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	if c.SIEM.Enabled {
+		check(siemFormats[c.SIEM.Format], "siem.format (SIEM_FORMAT) must be cef or json, got %q", c.SIEM.Format)
+		check(siemTransports[c.SIEM.Transport], "siem.transport (SIEM_TRANSPORT) must be udp, tcp, tls or file, got %q", c.SIEM.Transport)
+		if c.SIEM.Transport == "file" {
+			check(c.SIEM.FilePath != "", "siem.file_path (SIEM_FILE_PATH) is required for the file transport")
+		} else if siemTransports[c.SIEM.Transport] {
+			check(c.SIEM.Address != "", "siem.address (SIEM_ADDRESS) is required for the %s transport", c.SIEM.Transport)
+		}
+		check(c.SIEM.FileMaxSizeMB >= 0, "siem.file_max_size_mb (SIEM_FILE_MAX_SIZE_MB) must not be negative")
+		check(c.SIEM.FileMaxBackups >= 0, "siem.file_max_backups (SIEM_FILE_MAX_BACKUPS) must not be negative")
+		check(c.SIEM.AlertThreshold >= 0 && c.SIEM.AlertThreshold <= 100,
+			"siem.alert_threshold (SIEM_ALERT_THRESHOLD) must be 0-100, got %d", c.SIEM.AlertThreshold)
+	}
+
+	if c.OIDC.IssuerURL != "" {
+		u, err := url.Parse(c.OIDC.IssuerURL)
+		check(err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https"),
+			"oidc.issuer_url (OIDC_ISSUER_URL) must be an http(s) URL, got %q", c.OIDC.IssuerURL)
+		check(c.OIDC.ClientID != "", "oidc.client_id (OIDC_CLIENT_ID) is required when oidc.issuer_url is set")
+		u, err = url.Parse(c.OIDC.RedirectURL)
+		check(err == nil && u.IsAbs(),
+			"oidc.redirect_url (OIDC_REDIRECT_URL) must be an absolute URL ending in /api/auth/oidc/callback, got %q", c.OIDC.RedirectURL)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n  %s", ErrInvalid, strings.Join(problems, "\n  "))
+}
+
+// Duration is a time.Duration written as "30s" or "15m" in files, env and JSON
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(strings.TrimSpace(string(text)))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (expected e.g. 30s, 5m, 1h)", text)
+	}
+	d.Duration = parsed
+	return nil
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv blanks every variable the config reads, so the developer's environment does not leak into a test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	cfg := Default()
+	walkFields(reflect.ValueOf(&cfg).Elem(), func(field reflect.StructField, _ reflect.Value) error {
+		if name := field.Tag.Get("env"); name != "" {
+			t.Setenv(name, "")
+		}
+		return nil
+	})
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Default().Validate() = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"port out of range", func(c *Config) { c.Server.Port = 70000 }, "server.port (PORT) must be 1-65535"},
+		{"trusted proxy hostname", func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8", "nginx"} }, `server.trusted_proxies (TRUSTED_PROXIES) entries must be an IP or CIDR, got "nginx"`},
+		{"missing database host", func(c *Config) { c.Database.Host = "" }, "database.host (DB_HOST) is required"},
+		{"unknown sslmode", func(c *Config) { c.Database.SSLMode = "maybe" }, "database.sslmode (DB_SSLMODE)"},
+		{"search language injection", func(c *Config) { c.Database.SearchLanguage = "english'; --" }, "database.search_language"},
+		{"tor proxy without port", func(c *Config) { c.Tor.Proxy = "tor" }, "tor.proxy (TOR_PROXY) must be host:port"},
+		{"unknown tor balance", func(c *Config) { c.Tor.Balance = "random" }, "tor.balance (TOR_BALANCE)"},
+		{"bad i2p proxy", func(c *Config) { c.Transport.I2PProxy = "socks5://i2p:4447" }, "transport.i2p_proxy (I2P_PROXY)"},
+		{"scrape interval too short", func(c *Config) { c.Scheduler.Interval = Duration{time.Second} }, "scheduler.interval (SCRAPE_INTERVAL) must be at least 5s"},
+		{"refresh shorter than access", func(c *Config) { c.Auth.RefreshTTL = Duration{time.Minute} }, "auth.refresh_ttl (JWT_REFRESH_TTL)"},
+		{"unknown password class", func(c *Config) { c.Auth.PasswordRequire = []string{"emoji"} }, `unknown class "emoji"`},
+		{"unknown log subsystem", func(c *Config) { c.Logging.Levels = map[string]string{"database": "debug", "web": "info"} }, `unknown subsystem "web"`},
+		{"sample ratio above one", func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio"},
+		{"siem without address", func(c *Config) { c.SIEM.Enabled = true }, "siem.address (SIEM_ADDRESS) is required for the udp transport"},
+		{"siem unknown format", func(c *Config) { c.SIEM.Enabled = true; c.SIEM.Format = "xml" }, "siem.format (SIEM_FORMAT)"},
+		{"siem threshold", func(c *Config) { c.SIEM.Enabled = true; c.SIEM.Transport = "file"; c.SIEM.AlertThreshold = 101 }, "siem.alert_threshold"},
+		{"oidc without client", func(c *Config) {
+			c.OIDC.IssuerURL = "https://login.example.com"
+			c.OIDC.RedirectURL = "https://cti.example.com/api/auth/oidc/callback"
+		}, "oidc.client_id (OIDC_CLIENT_ID) is required"},
+		{"oidc relative redirect", func(c *Config) {
+			c.OIDC.IssuerURL = "https://login.example.com"
+			c.OIDC.ClientID = "cti"
+			c.OIDC.RedirectURL = "/api/auth/oidc/callback"
+		}, "oidc.redirect_url (OIDC_REDIRECT_URL) must be an absolute URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Validate() = %v, want ErrInvalid", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Database.User = ""
+	cfg.AI.Model = ""
+	err := cfg.Validate()
+	for _, want := range []string{"server.port", "database.user", "ai.model"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("PORT", "9090")
+	t.Setenv("TOR_PROXIES", "tor-1, TOR-2:9150")
+	t.Setenv("SCRAPE_INTERVAL", "2m")
+	t.Setenv("DB_AUTO_MIGRATE", "false")
+	t.Setenv("LOG_LEVELS", "Scraper=DEBUG, tor=warn")
+	t.Setenv("PASSWORD_REQUIRE", "none")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	t.Setenv("OIDC_ROLE_MAPPING", "CTI-Admins:admin")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if cfg.Server.Port != 9090 {
+		t.Errorf("Server.Port = %d, want 9090", cfg.Server.Port)
+	}
+	if want := []string{"tor-1:9050", "tor-2:9150"}; !reflect.DeepEqual(cfg.Tor.Proxies, want) {
+		t.Errorf("Tor.Proxies = %v, want %v", cfg.Tor.Proxies, want)
+	}
+	if cfg.Scheduler.Interval.Duration != 2*time.Minute {
+		t.Errorf("Scheduler.Interval = %s, want 2m", cfg.Scheduler.Interval)
+	}
+	if cfg.Database.AutoMigrate {
+		t.Error("Database.AutoMigrate = true, want false")
+	}
+	if want := map[string]string{"scraper": "debug", "tor": "warn"}; !reflect.DeepEqual(cfg.Logging.Levels, want) {
+		t.Errorf("Logging.Levels = %v, want %v", cfg.Logging.Levels, want)
+	}
+	if len(cfg.Auth.PasswordRequire) != 0 {
+		t.Errorf("Auth.PasswordRequire = %v, want none", cfg.Auth.PasswordRequire)
+	}
+	if cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("Tracing.SampleRatio = %g, want 0.25", cfg.Tracing.SampleRatio)
+	}
+	// String values keep their case; group names in the identity provider are case sensitive
+	if cfg.OIDC.RoleMapping != "CTI-Admins:admin" {
+		t.Errorf("OIDC.RoleMapping = %q, want it unchanged", cfg.OIDC.RoleMapping)
+	}
+	if cfg.Database.Host != "postgres" {
+		t.Errorf("Database.Host = %q, want the default", cfg.Database.Host)
+	}
+
+	overrides := strings.Join(cfg.EnvOverrides(), ",")
+	for _, name := range []string{"PORT", "TOR_PROXIES", "LOG_LEVELS", "OIDC_ROLE_MAPPING"} {
+		if !strings.Contains(overrides, name) {
+			t.Errorf("EnvOverrides() = %s, want it to include %s", overrides, name)
+		}
+	}
+	if strings.Contains(overrides, "DB_HOST") {
+		t.Errorf("EnvOverrides() = %s, unset variables must not be listed", overrides)
+	}
+}
+
+func TestLoadRejectsInvalidEnv(t *testing.T) {
+	tests := []struct {
+		name, env, value, want string
+	}{
+		{"not a number", "PORT", "http", "invalid PORT"},
+		{"not a duration", "SCRAPE_INTERVAL", "often", "invalid SCRAPE_INTERVAL"},
+		{"not a bool", "METRICS_ENABLED", "sometimes", "invalid METRICS_ENABLED"},
+		{"not a pair", "LOG_LEVELS", "scraper", "invalid LOG_LEVELS"},
+		{"fails validation", "PORT", "0", "server.port (PORT)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv(tt.env, tt.value)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() with %s=%q = %v, want it to mention %q", tt.env, tt.value, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileThenEnv(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	data := "server:\n  port: 8181\ndatabase:\n  host: db.internal\n  sslmode: require\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_HOST", "db.override")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if cfg.Server.Port != 8181 || cfg.Database.SSLMode != "require" {
+		t.Errorf("file values not applied: port %d, sslmode %q", cfg.Server.Port, cfg.Database.SSLMode)
+	}
+	if cfg.Database.Host != "db.override" {
+		t.Errorf("Database.Host = %q, want the environment to win over the file", cfg.Database.Host)
+	}
+	if cfg.File() != path {
+		t.Errorf("File() = %q, want %q", cfg.File(), path)
+	}
+
+	unknown := filepath.Join(dir, "typo.yaml")
+	if err := os.WriteFile(unknown, []byte("server:\n  prot: 8181\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", unknown)
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted an unknown key")
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.JWTSecret = "s3cret"
+	cfg.OIDC.ClientSecret = "client-secret"
+	cfg.Metrics.Token = ""
+
+	redacted := cfg.Redacted()
+	if redacted.Database.Password != redactedValue || redacted.Auth.JWTSecret != redactedValue || redacted.OIDC.ClientSecret != redactedValue {
+		t.Errorf("secrets were not redacted: %+v", redacted)
+	}
+	if redacted.Metrics.Token != "" {
+		t.Errorf("Metrics.Token = %q, unset secrets stay empty", redacted.Metrics.Token)
+	}
+	if cfg.Auth.JWTSecret != "s3cret" {
+		t.Error("Redacted modified the original configuration")
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in Redacted, matching the audit log
+const redactedValue = "[REDACTED]"
+
+/*Bu fonksiyon, ayarları yükler: varsayılanlar, CONFIG_FILE ile verilen dosya (uzantısı
+.yaml, .yml veya .toml olmalı) ve ortam değişkenleri bu sırayla uygulanır, ardından
+sonuç doğrulanır. Dosyada bilinmeyen bir anahtar varsa, yazım hatalarının sessizce
+yok sayılmaması için hata döner. CONFIG_FILE verilmezse yalnızca ortam değişkenleri
+kullanılır; bu sayede mevcut kurulumlar değişiklik olmadan çalışmaya devam eder.
+*/
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	// A proxy without a port has always meant Tor's default SOCKS port
+	if cfg.Tor.Proxy != "" && !strings.Contains(cfg.Tor.Proxy, ":") {
+		cfg.Tor.Proxy += ":9050"
+	}
+	for i, address := range cfg.Tor.Proxies {
+		if !strings.Contains(address, ":") {
+			cfg.Tor.Proxies[i] = address + ":9050"
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CONFIG_FILE: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(c); err != nil {
+			var details *toml.StrictMissingError
+			if errors.As(err, &details) {
+				return fmt.Errorf("failed to parse %s: %s", path, details.String())
+			}
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file %s: expected .yaml, .yml or .toml", path)
+	}
+	c.file = path
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// applyEnv overrides every field that has an env tag with its environment variable, if set
+func (c *Config) applyEnv() error {
+	return walkFields(reflect.ValueOf(c).Elem(), func(field reflect.StructField, value reflect.Value) error {
+		name := field.Tag.Get("env")
+		if name == "" {
+			return nil
+		}
+		raw := strings.TrimSpace(os.Getenv(name))
+		if raw == "" {
+			return nil
+		}
+		if err := setFromString(value, raw); err != nil {
+			return fmt.Errorf("invalid %s: %s", name, err)
+		}
+		c.envOverrides = append(c.envOverrides, name)
+		return nil
+	})
+}
+
+func setFromString(value reflect.Value, raw string) error {
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		value.SetBool(b)
+	case reflect.Slice:
+		// Comma separated; "none" is an explicit empty list
+		items := []string{}
+		if raw != "none" {
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		// Comma separated key=value pairs
+		items := map[string]string{}
+		for _, pair := range strings.Split(raw, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%q is not a key=value pair", pair)
+			}
+			items[strings.ToLower(strings.TrimSpace(key))] = strings.ToLower(strings.TrimSpace(val))
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", value.Type())
+	}
+	return nil
+}
+
+// walkFields calls fn for every leaf field of the config sections
+func walkFields(v reflect.Value, fn func(reflect.StructField, reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Tag.Get("env") == "" {
+			if err := walkFields(value, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with every secret field that is set replaced
+func (c *Config) Redacted() Config {
+	copied := *c
+	copied.Auth.PasswordRequire = append([]string(nil), c.Auth.PasswordRequire...)
+	copied.Logging.Levels = make(map[string]string, len(c.Logging.Levels))
+	for subsystem, level := range c.Logging.Levels {
+		copied.Logging.Levels[subsystem] = level
+	}
+	walkFields(reflect.ValueOf(&copied).Elem(), func(field reflect.StructField, value reflect.Value) error {
+		if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString(redactedValue)
+		}
+		return nil
+	})
+	return copied
+}
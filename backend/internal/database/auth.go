@@ -9,7 +9,7 @@ bcrypt.GenerateFromPassword kullanılarak şifre bcrypt algoritması ile şifrel
 döndürülür; işlem sırasında hata oluşursa birlikte iletilir. Bu yöntem, şifreleri veritabanında
 düz metin olarak saklamaktan kaçınarak güvenliği artırır.
 */
-func hashPassword(password string) (string, error) {
+func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
 }
@@ -3,22 +3,19 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
 	"time"
 
 	_ "github.com/lib/pq"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
 )
 
-func Connect() (*sql.DB, error) {
-	host := getEnv("DB_HOST", "postgres")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "postgres")
-	dbname := getEnv("DB_NAME", "scraper_db")
+var logger = logging.For("database")
 
-	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+func Connect(cfg config.DatabaseConfig) (*sql.DB, error) {
+	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode)
 
 	var db *sql.DB
 	var err error
@@ -28,20 +25,20 @@ func Connect() (*sql.DB, error) {
 	for i := 0; i < maxRetries; i++ {
 		db, err = sql.Open("postgres", psqlInfo)
 		if err != nil {
-			log.Printf("Attempt %d/%d: Failed to open database connection: %v", i+1, maxRetries, err)
+			logger.Warn("failed to open database connection", "attempt", i+1, "max_attempts", maxRetries, logging.Err(err))
 			if i < maxRetries-1 {
 				time.Sleep(retryDelay)
 			}
 			continue
 		}
 
-		db.SetMaxOpenConns(25)
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
 		db.SetMaxIdleConns(5)
 		db.SetConnMaxLifetime(5 * time.Minute)
 
 		err = db.Ping()
 		if err != nil {
-			log.Printf("Attempt %d/%d: Failed to ping database: %v", i+1, maxRetries, err)
+			logger.Warn("failed to ping database", "attempt", i+1, "max_attempts", maxRetries, logging.Err(err))
 			db.Close()
 			if i < maxRetries-1 {
 				time.Sleep(retryDelay)
@@ -49,84 +46,137 @@ func Connect() (*sql.DB, error) {
 			continue
 		}
 
-		log.Println("Successfully connected to database")
+		logger.Info("connected to database", "host", cfg.Host, "name", cfg.Name)
 		return db, nil
 	}
 
 	return nil, fmt.Errorf("failed to connect to database after %d attempts: %v", maxRetries, err)
 }
 
-func InitSchema(db *sql.DB) error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS sources (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			url VARCHAR(500) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS data_entries (
-			id SERIAL PRIMARY KEY,
-			source_id INTEGER REFERENCES sources(id) ON DELETE CASCADE,
-			title VARCHAR(500) NOT NULL,
-			cleaned_content TEXT NOT NULL,
-			share_date TIMESTAMP,
-			criticality_score INTEGER DEFAULT 0 CHECK (criticality_score >= 0 AND criticality_score <= 100),
-			category VARCHAR(100) DEFAULT 'Uncategorized',
-			ai_analysis TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`DO $$ 
-		BEGIN 
-			IF EXISTS (SELECT 1 FROM information_schema.columns 
-				WHERE table_name='data_entries' AND column_name='raw_content') THEN
-				ALTER TABLE data_entries DROP COLUMN raw_content;
-			END IF;
-		END $$;`,
-		`CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			username VARCHAR(100) UNIQUE NOT NULL,
-			password_hash VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_data_entries_source_id ON data_entries(source_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_data_entries_category ON data_entries(category)`,
-		`CREATE INDEX IF NOT EXISTS idx_data_entries_criticality ON data_entries(criticality_score)`,
-		`CREATE INDEX IF NOT EXISTS idx_data_entries_created_at ON data_entries(created_at)`,
-		`DO $$ 
-		BEGIN 
-			IF NOT EXISTS (SELECT 1 FROM information_schema.columns 
-				WHERE table_name='data_entries' AND column_name='ai_analysis') THEN
-				ALTER TABLE data_entries ADD COLUMN ai_analysis TEXT;
-			END IF;
-		END $$;`,
-	}
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute query: %v", err)
+/*Bu fonksiyon, açılışta veritabanı şemasını hazırlar. Şema, migrations klasöründeki
+sürümlü SQL dosyalarıyla yönetilir; veritabanı bu build'in bildiğinden yeni bir sürümdeyse
+uygulama açılmayı reddeder. DB_AUTO_MIGRATE=false verilmedikçe bekleyen migration'lar
+otomatik uygulanır; verilmişse bekleyen migration varken açılış durdurulur ve
+"migrate up" komutunun çalıştırılması istenir. Ardından admin kullanıcısı ve arama dili
+gibi başlangıç verileri hazırlanır.
+*/
+func InitSchema(db *sql.DB, cfg config.DatabaseConfig) error {
+	if err := CheckSchemaVersion(db); err != nil {
+		return err
+	}
+
+	if cfg.AutoMigrate {
+		if _, err := MigrateUp(db, 0); err != nil {
+			return err
+		}
+	} else {
+		current, err := SchemaVersion(db)
+		if err != nil {
+			return err
 		}
+		if latest := LatestVersion(); current < latest {
+			return fmt.Errorf("database is at schema version %d but this build needs %d; run 'migrate up' first (DB_AUTO_MIGRATE=false)", current, latest)
+		}
+	}
+
+	if err := seedAdmin(db, cfg.AdminPassword); err != nil {
+		logger.Warn("could not create default admin user", logging.Err(err))
 	}
 
-	defaultPassword := getEnv("ADMIN_PASSWORD", "admin123")
-	hashedPassword, _ := hashPassword(defaultPassword)
-	_, err := db.Exec(`
-		INSERT INTO users (username, password_hash) 
-		VALUES ('admin', $1) 
+	if err := configureSearchLanguage(db, cfg.SearchLanguage); err != nil {
+		return err
+	}
+
+	logger.Info("database schema initialized")
+	return nil
+}
+
+/*Bu fonksiyon, varsayılan admin kullanıcısını ADMIN_PASSWORD şifresiyle (verilmezse
+admin123) oluşturur. Oluşturulan hesaptan ilk girişte şifresini değiştirmesi istenir.
+Mevcut admin hesabı hâlâ bu başlangıç şifresini kullanıyorsa da şifre değiştirme
+zorunluluğu yeniden işaretlenir ve uyarı loglanır.
+*/
+func seedAdmin(db *sql.DB, defaultPassword string) error {
+	if defaultPassword == "" {
+		defaultPassword = "admin123"
+		logger.Warn("ADMIN_PASSWORD is not set, the admin account uses the default password and must change it at first login")
+	}
+	hashedPassword, err := HashPassword(defaultPassword)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`
+		INSERT INTO users (username, password_hash, role, must_change_password) 
+		VALUES ('admin', $1, 'admin', TRUE) 
 		ON CONFLICT (username) DO NOTHING
-	`, hashedPassword)
+	`, hashedPassword); err != nil {
+		return err
+	}
 
+	var currentHash string
+	var mustChange bool
+	err = db.QueryRow(`SELECT password_hash, must_change_password FROM users WHERE username = 'admin'`).Scan(&currentHash, &mustChange)
 	if err != nil {
-		log.Printf("Warning: Could not create default admin user: %v", err)
+		return err
+	}
+	if !mustChange && CheckPasswordHash(defaultPassword, currentHash) {
+		logger.Warn("admin account still uses its seeded password, a password change will be required at next login")
+		_, err = db.Exec(`UPDATE users SET must_change_password = TRUE WHERE username = 'admin'`)
+	}
+	return err
+}
+
+var searchLanguage = "simple"
+
+/*Bu fonksiyon, tam metin aramada kullanılacak PostgreSQL metin arama yapılandırmasını
+(ör. english, turkish, simple) database.search_language ayarından (SEARCH_LANGUAGE)
+alır ve app_settings tablosuna yazar. Yapılandırma veritabanında mevcut değilse hata döner.
+Dil daha önce kaydedilenden farklıysa veya search_vector'u boş kayıtlar varsa, ilgili
+kayıtların title alanı kendisine eşitlenerek tetikleyicinin vektörü yeniden üretmesi
+sağlanır; böylece eski kayıtlar da yeni dil kurallarıyla aranabilir hale gelir.
+*/
+func configureSearchLanguage(db *sql.DB, language string) error {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM pg_ts_config WHERE cfgname = $1)`, language).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check text search configuration: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("text search configuration %q does not exist (SEARCH_LANGUAGE)", language)
+	}
+
+	var previous sql.NullString
+	if err := db.QueryRow(`SELECT value FROM app_settings WHERE key = 'search_language'`).Scan(&previous); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read search language: %v", err)
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO app_settings (key, value, updated_at)
+		VALUES ('search_language', $1, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`, language); err != nil {
+		return fmt.Errorf("failed to store search language: %v", err)
+	}
+
+	condition := "search_vector IS NULL"
+	if previous.Valid && previous.String != language {
+		logger.Info("search language changed, rebuilding search index", "from", previous.String, "to", language)
+		condition = "TRUE"
+	}
+	result, err := db.Exec(`UPDATE data_entries SET title = title WHERE ` + condition)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild search vectors: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		logger.Info("rebuilt search vectors", "entries", n)
 	}
 
-	log.Println("Database schema initialized successfully")
+	searchLanguage = language
 	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+// SearchLanguage returns the text search configuration used by the search_vector trigger
+func SearchLanguage() string {
+	return searchLanguage
 }
 
+
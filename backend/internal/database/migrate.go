@@ -0,0 +1,333 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew means the database was migrated by a newer build than this one
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+// migrationLockID is the pg_advisory_lock key that serializes concurrent migrators
+const migrationLockID = 7265736372
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+func (m Migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.up))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrationState is one row of the migrate status output
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set when the embedded up script differs from the one that was applied
+	Modified bool `json:"modified"`
+}
+
+/*Bu fonksiyon, binary'ye gömülü migrations/NNNN_ad.up.sql ve NNNN_ad.down.sql
+dosyalarını okur ve sürüm sırasına göre döndürür. Her sürümün hem up hem down dosyası
+olmalı ve sürümler 1'den başlayarak boşluksuz ilerlemelidir; aksi halde hata döner.
+*/
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be consecutive, expected %d but found %s", i+1, m)
+		}
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down script", m)
+		}
+	}
+	return migrations, nil
+}
+
+// LatestVersion is the schema version this build migrates to
+func LatestVersion() int {
+	migrations, err := Migrations()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func ensureMigrationTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func appliedMigrations(ctx context.Context, q queryer) (map[int]appliedMigration, error) {
+	applied := map[int]appliedMigration{}
+	rows, err := q.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "42P01" {
+		// undefined_table: nothing has been migrated yet
+		return applied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var m appliedMigration
+		if err := rows.Scan(&version, &m.checksum, &m.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = m
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion returns the highest applied migration, 0 for an unmigrated database
+func SchemaVersion(db *sql.DB) (int, error) {
+	applied, err := appliedMigrations(context.Background(), db)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// CheckSchemaVersion fails with ErrSchemaTooNew if the database has migrations this build does not know
+func CheckSchemaVersion(db *sql.DB) error {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+	if latest := LatestVersion(); current > latest {
+		return fmt.Errorf("%w: database is at version %d but this build only knows up to %d; run a newer build or roll back with its 'migrate down'",
+			ErrSchemaTooNew, current, latest)
+	}
+	return nil
+}
+
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = &a.appliedAt
+			state.Modified = a.checksum != m.checksum()
+		}
+		states = append(states, state)
+	}
+	// Versions applied by a newer build are listed too so status shows why boot is refused
+	for version, a := range applied {
+		if version > len(migrations) {
+			appliedAt := a.appliedAt
+			states = append(states, MigrationState{Version: version, Name: "(unknown)", Applied: true, AppliedAt: &appliedAt})
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// withMigrationLock runs fn on a single connection holding the migration advisory lock
+func withMigrationLock(db *sql.DB, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %v", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if err := ensureMigrationTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(ctx, conn)
+}
+
+/*Bu fonksiyon, uygulanmamış migration'ları sürüm sırasıyla uygular; steps 0 veya
+negatifse bekleyen tümü, aksi halde en fazla steps adet migration uygulanır. Her migration
+ve schema_migrations kaydı aynı transaction içinde çalışır; bir adım hata verirse o adım
+geri alınır ve sonraki adımlara geçilmez. Aynı anda başlayan birden fazla uygulama örneği
+PostgreSQL advisory lock ile sıraya sokulur. Veritabanı bu build'den yeni bir sürümdeyse
+hiçbir şey yapılmadan ErrSchemaTooNew döner.
+*/
+func MigrateUp(db *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = withMigrationLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for version := range applied {
+			if version > len(migrations) {
+				return fmt.Errorf("%w: database has migration %d but this build only knows up to %d", ErrSchemaTooNew, version, len(migrations))
+			}
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if steps > 0 && len(done) == steps {
+				break
+			}
+			if err := runMigration(ctx, conn, m.up, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				m.Version, m.Name, m.checksum()); err != nil {
+				return fmt.Errorf("migration %s failed: %v", m, err)
+			}
+			logger.Info("applied migration", "migration", m.String())
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+/*Bu fonksiyon, uygulanmış son steps adet migration'ı en yenisinden başlayarak down
+betikleriyle geri alır. Down betikleri tablo ve kolonları sildiği için veri kaybına yol
+açabilir; bu yüzden yalnızca açıkça istenen sayıda adım geri alınır.
+*/
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("migrate down needs at least one step")
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = withMigrationLock(db, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m.down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+				return fmt.Errorf("rolling back migration %s failed: %v", m, err)
+			}
+			logger.Info("rolled back migration", "migration", m.String())
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// runMigration executes a migration script and its schema_migrations bookkeeping in one transaction
+func runMigration(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
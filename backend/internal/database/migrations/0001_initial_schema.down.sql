@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS data_entries;
+DROP FUNCTION IF EXISTS data_entries_search_vector_update();
+DROP TABLE IF EXISTS sources;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS app_settings;
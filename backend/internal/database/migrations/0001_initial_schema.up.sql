@@ -0,0 +1,64 @@
+-- Sources, entries, users and full-text search. Written idempotently so databases
+-- created before versioned migrations are adopted without changes.
+CREATE TABLE IF NOT EXISTS sources (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	url VARCHAR(500) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS data_entries (
+	id SERIAL PRIMARY KEY,
+	source_id INTEGER REFERENCES sources(id) ON DELETE CASCADE,
+	title VARCHAR(500) NOT NULL,
+	cleaned_content TEXT NOT NULL,
+	share_date TIMESTAMP,
+	criticality_score INTEGER DEFAULT 0 CHECK (criticality_score >= 0 AND criticality_score <= 100),
+	category VARCHAR(100) DEFAULT 'Uncategorized',
+	ai_analysis TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE data_entries DROP COLUMN IF EXISTS raw_content;
+ALTER TABLE data_entries ADD COLUMN IF NOT EXISTS ai_analysis TEXT;
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(100) UNIQUE NOT NULL,
+	password_hash VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_entries_source_id ON data_entries(source_id);
+CREATE INDEX IF NOT EXISTS idx_data_entries_category ON data_entries(category);
+CREATE INDEX IF NOT EXISTS idx_data_entries_criticality ON data_entries(criticality_score);
+CREATE INDEX IF NOT EXISTS idx_data_entries_created_at ON data_entries(created_at);
+
+CREATE TABLE IF NOT EXISTS app_settings (
+	key VARCHAR(100) PRIMARY KEY,
+	value TEXT NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE data_entries ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION data_entries_search_vector_update() RETURNS trigger AS $$
+DECLARE
+	cfg regconfig;
+BEGIN
+	SELECT value::regconfig INTO cfg FROM app_settings WHERE key = 'search_language';
+	IF cfg IS NULL THEN
+		cfg := 'simple'::regconfig;
+	END IF;
+	NEW.search_vector :=
+		setweight(to_tsvector(cfg, coalesce(NEW.title, '')), 'A') ||
+		setweight(to_tsvector(cfg, coalesce(NEW.cleaned_content, '')), 'B');
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS data_entries_search_vector_trigger ON data_entries;
+CREATE TRIGGER data_entries_search_vector_trigger
+	BEFORE INSERT OR UPDATE OF title, cleaned_content ON data_entries
+	FOR EACH ROW EXECUTE FUNCTION data_entries_search_vector_update();
+
+CREATE INDEX IF NOT EXISTS idx_data_entries_search_vector ON data_entries USING GIN(search_vector);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	filter JSONB NOT NULL DEFAULT '{}',
+	schedule_minutes INTEGER CHECK (schedule_minutes IS NULL OR schedule_minutes >= 5),
+	last_run_at TIMESTAMP,
+	next_run_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+	saved_search_id INTEGER NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+	entry_id INTEGER NOT NULL REFERENCES data_entries(id) ON DELETE CASCADE,
+	matched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (saved_search_id, entry_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_search_matches_matched_at ON saved_search_matches(saved_search_id, matched_at);
+CREATE INDEX IF NOT EXISTS idx_saved_searches_next_run_at ON saved_searches(next_run_at) WHERE schedule_minutes IS NOT NULL;
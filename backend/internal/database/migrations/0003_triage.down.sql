@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS entry_notes;
+ALTER TABLE data_entries
+	DROP COLUMN IF EXISTS triage_status,
+	DROP COLUMN IF EXISTS assignee_id,
+	DROP COLUMN IF EXISTS triage_updated_at;
@@ -0,0 +1,18 @@
+ALTER TABLE data_entries
+	ADD COLUMN IF NOT EXISTS triage_status VARCHAR(20) NOT NULL DEFAULT 'new'
+		CHECK (triage_status IN ('new', 'in_review', 'escalated', 'false_positive', 'closed')),
+	ADD COLUMN IF NOT EXISTS assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	ADD COLUMN IF NOT EXISTS triage_updated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_data_entries_triage ON data_entries(assignee_id, triage_status);
+CREATE INDEX IF NOT EXISTS idx_data_entries_triage_status ON data_entries(triage_status);
+
+CREATE TABLE IF NOT EXISTS entry_notes (
+	id SERIAL PRIMARY KEY,
+	entry_id INTEGER NOT NULL REFERENCES data_entries(id) ON DELETE CASCADE,
+	parent_id INTEGER REFERENCES entry_notes(id) ON DELETE CASCADE,
+	user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_entry_notes_entry_id ON entry_notes(entry_id, created_at);
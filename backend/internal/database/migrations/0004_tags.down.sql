@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS data_entries_auto_tag_trigger ON data_entries;
+DROP FUNCTION IF EXISTS data_entries_auto_tag();
+DROP TABLE IF EXISTS source_tags;
+DROP TABLE IF EXISTS entry_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	color VARCHAR(7) NOT NULL DEFAULT '#6b7280' CHECK (color ~ '^#[0-9a-fA-F]{6}$'),
+	description TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags(LOWER(name));
+
+CREATE TABLE IF NOT EXISTS entry_tags (
+	entry_id INTEGER NOT NULL REFERENCES data_entries(id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (entry_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_entry_tags_tag_id ON entry_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS source_tags (
+	source_id INTEGER NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	auto_apply BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (source_id, tag_id)
+);
+
+-- New entries inherit the auto-apply tags of their source
+CREATE OR REPLACE FUNCTION data_entries_auto_tag() RETURNS trigger AS $$
+BEGIN
+	INSERT INTO entry_tags (entry_id, tag_id)
+	SELECT NEW.id, st.tag_id FROM source_tags st
+	WHERE st.source_id = NEW.source_id AND st.auto_apply
+	ON CONFLICT DO NOTHING;
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS data_entries_auto_tag_trigger ON data_entries;
+CREATE TRIGGER data_entries_auto_tag_trigger
+	AFTER INSERT ON data_entries
+	FOR EACH ROW EXECUTE FUNCTION data_entries_auto_tag();
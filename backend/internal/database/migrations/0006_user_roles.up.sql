@@ -0,0 +1,12 @@
+-- Existing users become analysts; the seeded admin account becomes admin
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name='users' AND column_name='role') THEN
+		ALTER TABLE users
+			ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'analyst'
+				CHECK (role IN ('viewer', 'analyst', 'admin')),
+			ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+		UPDATE users SET role = 'admin' WHERE username = 'admin';
+	END IF;
+END $$;
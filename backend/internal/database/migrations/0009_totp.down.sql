@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS user_recovery_codes;
+ALTER TABLE users
+	DROP COLUMN IF EXISTS totp_secret,
+	DROP COLUMN IF EXISTS totp_enabled,
+	DROP COLUMN IF EXISTS totp_last_step;
+DELETE FROM app_settings WHERE key = 'require_mfa';
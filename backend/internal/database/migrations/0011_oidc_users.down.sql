@@ -0,0 +1,5 @@
+-- Single sign-on accounts are kept; their password hash is unusable, so they cannot log in
+DROP INDEX IF EXISTS idx_users_external_id;
+ALTER TABLE users
+	DROP COLUMN IF EXISTS auth_provider,
+	DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,3 @@
+ALTER TABLE sources
+	DROP COLUMN IF EXISTS transport,
+	DROP COLUMN IF EXISTS proxy_url;
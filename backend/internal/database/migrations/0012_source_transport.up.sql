@@ -0,0 +1,5 @@
+-- Existing sources keep fetching through Tor
+ALTER TABLE sources
+	ADD COLUMN IF NOT EXISTS transport VARCHAR(10) NOT NULL DEFAULT 'tor'
+		CHECK (transport IN ('tor', 'i2p', 'proxy', 'direct')),
+	ADD COLUMN IF NOT EXISTS proxy_url VARCHAR(500);
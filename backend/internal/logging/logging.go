@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"interactive-scraper/internal/config"
+)
+
+// settings is the active output handler and per-subsystem levels, replaced by Setup
+type settings struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+var current atomic.Pointer[settings]
+
+func init() {
+	current.Store(&settings{
+		handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		level:   slog.LevelInfo,
+	})
+}
+
+/*Bu fonksiyon, loglamayı merkezi yapılandırmaya göre kurar: tüm loglar stderr'e
+logging.format ile seçilen biçimde (json veya text) yazılır; logging.level varsayılan
+seviyeyi, logging.levels ise alt sistem bazında seviyeyi belirler (ör. scraper için debug,
+tor için warn). For ile alınan logger'lar paket yüklenirken oluşturulsa da her kayıtta
+güncel ayarları okur, bu yüzden Setup'tan önce oluşturulmaları sorun değildir. Standart
+log paketiyle yazılan satırlar da aynı çıktıya "main" alt sistemi ve error seviyesiyle
+yönlendirilir.
+*/
+func Setup(cfg config.LoggingConfig) {
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler = slog.NewJSONHandler(os.Stderr, options)
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(os.Stderr, options)
+	}
+
+	s := &settings{handler: handler, level: parseLevel(cfg.Level), levels: map[string]slog.Level{}}
+	for subsystem, level := range cfg.Levels {
+		s.levels[subsystem] = parseLevel(level)
+	}
+	current.Store(s)
+
+	// Remaining log.Fatal calls report startup failures
+	log.SetFlags(0)
+	log.SetOutput(slog.NewLogLogger(&subsystemHandler{subsystem: "main"}, slog.LevelError).Writer())
+}
+
+func parseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(name))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// For returns the logger of a subsystem; every record carries subsystem=<name>
+func For(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: subsystem})
+}
+
+/*Bu yapı (subsystemHandler), bir alt sistemin kayıtlarını seviyesine göre süzer ve güncel
+çıktı handler'ına iletir. Kayıtlara subsystem alanı ile, context'te bir korelasyon ID'si
+varsa correlation_id alanı eklenir. WithAttrs ve WithGroup ile eklenen alanlar, çıktı
+handler'ı Setup ile değişebileceği için burada saklanır ve her kayıtta yeniden uygulanır.
+*/
+type subsystemHandler struct {
+	subsystem string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, level slog.Level) bool {
+	s := current.Load()
+	threshold, ok := s.levels[h.subsystem]
+	if !ok {
+		threshold = s.level
+	}
+	return level >= threshold
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, record slog.Record) error {
+	var handler slog.Handler = current.Load().handler.WithAttrs([]slog.Attr{slog.String("subsystem", h.subsystem)})
+	if id := CorrelationID(ctx); id != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("correlation_id", id)})
+	}
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *subsystemHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := append(append([]func(slog.Handler) slog.Handler(nil), h.ops...), op)
+	return &subsystemHandler{subsystem: h.subsystem, ops: ops}
+}
+
+type correlationKey struct{}
+
+// WithCorrelationID returns a context whose log records carry the given ID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the ID stored by WithCorrelationID, or ""
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// NewCorrelationID returns a random 16 character hex ID
+func NewCorrelationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Err is the conventional attribute for an error
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.String("error", err.Error())
+}
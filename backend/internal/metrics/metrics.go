@@ -0,0 +1,310 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is implemented by every metric kind
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+/*Bu kayıt defteri (registry), Prometheus metin formatında (0.0.4) yazılacak tüm metrikleri
+tutar. Sayaçlar, göstergeler ve histogramlar paket düzeyinde tanımlanır ve
+tanımlandıkları anda varsayılan kayıt defterine eklenir; /metrics handler'ı her istekte
+tüm metrikleri isim sırasıyla yazar. Bağlantı havuzu gibi değeri okunma anında
+hesaplanan metrikler için GaugeFunc ve CounterFunc kullanılır.
+*/
+var registry = struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}{collectors: map[string]collector{}}
+
+func register(c collector) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, exists := registry.collectors[c.name()]; exists {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	registry.collectors[c.name()] = c
+}
+
+// WriteAll writes every registered metric in the Prometheus text format, sorted by name
+func WriteAll(w io.Writer) {
+	registry.mu.RLock()
+	collectors := make([]collector, 0, len(registry.collectors))
+	for _, c := range registry.collectors {
+		collectors = append(collectors, c)
+	}
+	registry.mu.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// ContentType is the media type of the WriteAll output
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// vec holds one value per label combination
+type vec struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+	// buckets is the number of histogram buckets, 0 for other kinds
+	buckets int
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// histogram only
+	counts []uint64
+	sum    float64
+}
+
+func newVec(name, help, kind string, labels []string, buckets int) *vec {
+	v := &vec{metricName: name, help: help, kind: kind, labels: labels, buckets: buckets, series: map[string]*series{}}
+	if len(labels) == 0 {
+		// A metric without labels is reported as 0 before its first update
+		v.get(nil)
+	}
+	return v
+}
+
+func (v *vec) name() string { return v.metricName }
+
+// get returns the series for the label values, creating it on first use; the caller holds v.mu
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if v.buckets > 0 {
+			s.counts = make([]uint64, v.buckets)
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sorted returns the series ordered by label values so the output is stable
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := make([]*series, len(keys))
+	for i, key := range keys {
+		out[i] = v.series[key]
+	}
+	return out
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	writeHeader(w, v.metricName, v.help, v.kind)
+	for _, s := range v.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", v.metricName, formatLabels(v.labels, s.labelValues, "", ""), formatValue(s.value))
+	}
+}
+
+/*Bu yapı (CounterVec), yalnızca artan bir sayacı etiket değerlerine göre ayrı ayrı tutar.
+Etiket değerleri, tanımdaki etiket adlarıyla aynı sırada verilmelidir. Etiketsiz bir
+sayaç için NewCounter kullanılır.
+*/
+type CounterVec struct{ *vec }
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels, 0)}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter; negative values are ignored because counters never go down
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.get(labelValues).value += delta
+	c.mu.Unlock()
+}
+
+// NewCounter is a CounterVec without labels
+func NewCounter(name, help string) *CounterVec {
+	return NewCounterVec(name, help)
+}
+
+// GaugeVec is a value per label combination that can go up and down
+type GaugeVec struct{ *vec }
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels, 0)}
+	register(g)
+	return g
+}
+
+// NewGauge is a GaugeVec without labels
+func NewGauge(name, help string) *GaugeVec {
+	return NewGaugeVec(name, help)
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).value = value
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).value += delta
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+func (g *GaugeVec) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+/*Bu yapı (HistogramVec), gözlemlenen değerleri (ör. saniye cinsinden süreler) verilen üst
+sınırlara (buckets) göre sayar ve toplamlarını tutar. Prometheus'taki histogram_quantile
+ile yüzdelik değerler hesaplanabilir. Sınırlar artan sırada verilmeli; +Inf otomatik
+eklenir.
+*/
+type HistogramVec struct {
+	*vec
+	buckets []float64
+}
+
+// DurationBuckets fit request latencies from milliseconds to a minute
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels, len(buckets)), buckets: buckets}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues)
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.value++
+	s.sum += value
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.metricName, h.help, h.kind)
+	for _, s := range h.sorted() {
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName,
+				formatLabels(h.labels, s.labelValues, "le", formatValue(bound)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %s\n", h.metricName, formatLabels(h.labels, s.labelValues, "le", "+Inf"), formatValue(s.value))
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, formatLabels(h.labels, s.labelValues, "", ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %s\n", h.metricName, formatLabels(h.labels, s.labelValues, "", ""), formatValue(s.value))
+	}
+}
+
+// funcMetric reads its value when the metrics are written
+type funcMetric struct {
+	metricName string
+	help       string
+	kind       string
+	fn         func() float64
+}
+
+func (f *funcMetric) name() string { return f.metricName }
+
+func (f *funcMetric) write(w io.Writer) {
+	writeHeader(w, f.metricName, f.help, f.kind)
+	fmt.Fprintf(w, "%s %s\n", f.metricName, formatValue(f.fn()))
+}
+
+// NewGaugeFunc registers a gauge whose value is returned by fn at scrape time
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(&funcMetric{metricName: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is returned by fn at scrape time
+func NewCounterFunc(name, help string, fn func() float64) {
+	register(&funcMetric{metricName: name, help: help, kind: "counter", fn: fn})
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabel(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"database/sql"
+	"runtime"
+	"time"
+)
+
+func init() {
+	started := float64(time.Now().Unix())
+	NewGaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch in seconds.",
+		func() float64 { return started })
+	NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.",
+		func() float64 { return float64(runtime.NumGoroutine()) })
+	NewGaugeFunc("go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects.", func() float64 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return float64(stats.HeapAlloc)
+	})
+}
+
+/*Bu fonksiyon, veritabanı bağlantı havuzunun sql.DB.Stats() ile okunan değerlerini
+db_ önekli metrikler olarak kaydeder: açık, kullanımda ve boşta bekleyen bağlantılar,
+havuz sınırı, bağlantı beklemek zorunda kalan sorgu sayısı ve toplam bekleme süresi.
+Değerler her /metrics isteğinde yeniden okunur. Sunucu başlarken bir kez çağrılmalıdır.
+*/
+func RegisterDBStats(db *sql.DB) {
+	stat := func(read func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return read(db.Stats()) }
+	}
+	NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	NewGaugeFunc("db_open_connections", "Number of established connections, in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	NewGaugeFunc("db_in_use_connections", "Number of connections currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	NewGaugeFunc("db_idle_connections", "Number of idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	NewCounterFunc("db_wait_count_total", "Number of queries that waited for a free connection.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	NewCounterFunc("db_wait_duration_seconds_total", "Total time spent waiting for a free connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	NewCounterFunc("db_max_idle_closed_total", "Connections closed because the idle pool was full.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	NewCounterFunc("db_max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
@@ -1,7 +1,6 @@
 package scraper
 
 import (
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
@@ -33,12 +32,12 @@ func (s *ScraperService) ParseShareDate(rawContent string) *time.Time {
 	
 	for _, strategy := range strategies {
 		if date := strategy(rawContent); date != nil {
-			log.Printf("[DATE_PARSER] Extracted share date: %s", date.Format(time.RFC3339))
+			logger.Debug("extracted share date", "share_date", date.Format(time.RFC3339))
 			return date
 		}
 	}
 	
-	log.Printf("[DATE_PARSER] No share date found in content")
+	logger.Debug("no share date found in content")
 	return nil 
 }
 
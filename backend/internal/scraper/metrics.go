@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"strconv"
+
+	"interactive-scraper/internal/metrics"
+)
+
+// fetchBuckets cover Tor fetches, which often take tens of seconds
+var fetchBuckets = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 45, 60, 90, 120}
+
+var (
+	scrapeRunsTotal = metrics.NewCounterVec("scraper_runs_total",
+		"Scrapes by source and outcome (completed or failed).", "source_id", "outcome")
+	scrapeDuration = metrics.NewHistogramVec("scraper_run_duration_seconds",
+		"Duration of a whole scrape of one source, including retries.", fetchBuckets, "outcome")
+	fetchDuration = metrics.NewHistogramVec("scraper_fetch_duration_seconds",
+		"Duration of a single page fetch attempt, by transport.", fetchBuckets, "transport", "outcome")
+	fetchBytesTotal = metrics.NewCounterVec("scraper_fetch_bytes_total",
+		"Bytes of page content fetched, by source.", "source_id")
+	entriesTotal = metrics.NewCounterVec("scraper_entries_total",
+		"Extracted entries by source and result (inserted, skipped as already known, or failed).", "source_id", "result")
+
+	torReady = metrics.NewGauge("tor_ready",
+		"1 if the last Tor readiness check could route traffic, otherwise 0.")
+	torExitIPChangesTotal = metrics.NewCounter("tor_exit_ip_changes_total",
+		"Times the observed Tor exit IP differed from the previously observed one.")
+	torBootstrapProgress = metrics.NewGauge("tor_bootstrap_progress",
+		"Tor bootstrap progress in percent as last read from the control port.")
+	torInstanceUp = metrics.NewGaugeVec("tor_instance_up",
+		"1 if the pooled Tor instance passed its last SOCKS5 check and is not quarantined, otherwise 0.", "instance")
+	torInstanceQuarantinesTotal = metrics.NewCounterVec("tor_instance_quarantines_total",
+		"Times a pooled Tor instance was quarantined after failed fetches.", "instance")
+	torNewnymTotal = metrics.NewCounterVec("tor_newnym_total",
+		"NEWNYM signals sent over the control port, by reason (manual or failures).", "reason")
+
+	aiQueueDepth = metrics.NewGauge("ai_analysis_queue_depth",
+		"AI analyses of new entries that are waiting or running.")
+)
+
+func init() {
+	metrics.NewGaugeFunc("tor_isolation_domains", "Cached Tor HTTP clients, one per Tor instance and SOCKS isolation domain.",
+		func() float64 { return float64(torClientCount()) })
+}
+
+// observeScrape records a finished scrape; state.CompletedAt must be set
+func observeScrape(state *ScrapeState) {
+	scrapeRunsTotal.Inc(strconv.Itoa(state.SourceID), state.Status)
+	scrapeDuration.Observe(state.CompletedAt.Sub(state.StartedAt).Seconds(), state.Status)
+}
@@ -1,77 +1,127 @@
 package scraper
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
 	"interactive-scraper/internal/ai"
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/service"
+	"interactive-scraper/internal/siem"
+	"interactive-scraper/internal/tracing"
 )
 
+var logger = logging.For("scraper")
+
 type ScraperService struct {
 	db        *sql.DB
 	aiService *ai.AIService
+	schedule  config.SchedulerConfig
+	emitter   *siem.Emitter
+	recorder  *audit.Recorder
+	analyses  sync.WaitGroup
+	// heartbeat is the UnixNano time the scheduler last made progress, 0 before Start
+	heartbeat atomic.Int64
 }
 
-func NewScraperService(db *sql.DB) *ScraperService {
+func NewScraperService(db *sql.DB, schedule config.SchedulerConfig, aiService *ai.AIService) *ScraperService {
 	return &ScraperService{
 		db:        db,
-		aiService: ai.NewAIService(),
+		aiService: aiService,
+		schedule:  schedule,
 	}
 }
 
+func (s *ScraperService) SetEmitter(emitter *siem.Emitter) {
+	s.emitter = emitter
+}
+
+func (s *ScraperService) SetRecorder(recorder *audit.Recorder) {
+	s.recorder = recorder
+}
+
 /*Bu Start fonksiyonu, scraper servisinin ana döngüsünü başlatır ve işlem adımlarını şöyle 
 işler: Önce log ile servisin başlatıldığı bildirilir. Ardından Tor ağı için hazır olma durumu 
 WaitForTorReady ile kontrol edilir; eğer Tor hazır değilse, uyarı mesajları loglanır ancak 
 servis yine de çalışmaya devam eder (bu sayede .onion sitelere erişimde hata çıkabilir). Tor 
-hazırsa, başarı mesajı loglanır. Daha sonra bir ticker ile her 30 saniyede bir ScrapeAll 
-çağrısı yapılacak şekilde periyodik tarama başlatılır. İlk tarama döngüye girmeden önce 
+hazırsa, başarı mesajı loglanır. Daha sonra bir ticker ile scheduler.interval ayarında
+(varsayılan 30 saniye) bir ScrapeAll çağrısı yapılacak şekilde periyodik tarama başlatılır. İlk tarama döngüye girmeden önce 
 hemen yapılır. Bu fonksiyon bloklayıcıdır, yani çalıştığı sürece scraper sürekli tarama yapar 
 ve Tor durumunu her döngüde dikkate alır.
 */
 func (s *ScraperService) Start() {
-	log.Println("[SCRAPER] Scraper service starting...")
-
-	log.Println("[SCRAPER] Waiting for Tor to become ready...")
-	if err := WaitForTorReady(20, 3*time.Second); err != nil {
-		log.Printf("[SCRAPER] WARNING: Tor did not become ready: %v", err)
-		log.Println("[SCRAPER] WARNING: Scraper will continue but may fail to access .onion sites")
-		log.Println("[SCRAPER] WARNING: Tor may still be bootstrapping. Scraper will retry on each scrape.")
+	s.beat()
+	logger.Info("scraper service starting, waiting for Tor to become ready")
+	if err := WaitForTorReady(context.Background(), 20, 3*time.Second); err != nil {
+		logger.Warn("Tor did not become ready; scraping continues and .onion sites may fail until Tor finishes bootstrapping",
+			logging.Err(err))
 	} else {
-		log.Println("[SCRAPER] ✓ Tor is ready! Starting scraper...")
+		logger.Info("Tor is ready, starting scraper")
 	}
 
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(s.schedule.Interval.Duration)
 	defer ticker.Stop()
 
-	log.Println("[SCRAPER] Starting initial scrape...")
-	s.ScrapeAll()
+	s.beat()
+	s.ScrapeAll(context.Background())
 
 	for range ticker.C {
-		s.ScrapeAll()
+		s.beat()
+		s.ScrapeAll(context.Background())
+	}
+}
+
+func (s *ScraperService) beat() {
+	s.heartbeat.Store(time.Now().UnixNano())
+}
+
+/*Bu fonksiyon, zamanlayıcının son ilerleme anını (heartbeat) ve bu anın en fazla ne kadar
+eski olabileceğini döndürür. Zamanlayıcı her turun başında ve turdaki her kaynaktan önce
+sinyal verir; iki sinyal arasındaki en uzun süre, bir tur beklemesi ile tek bir kaynağın en
+kötü durumdaki taranma süresidir (tüm fetch denemeleri zaman aşımına uğrar), buna bir dakika
+pay eklenir. Start henüz çağrılmadıysa sıfır zaman döner.
+*/
+func (s *ScraperService) Heartbeat() (last time.Time, maxAge time.Duration) {
+	if n := s.heartbeat.Load(); n != 0 {
+		last = time.Unix(0, n)
 	}
+	perSource := time.Duration(s.schedule.FetchRetries) * (maxFetchTimeout() + 2*s.schedule.RetryDelay.Duration)
+	maxAge = s.schedule.Interval.Duration + s.schedule.SourceDelay.Duration + perSource + time.Minute
+	return last, maxAge
 }
 
-/*ScrapeAll fonksiyonu, veritabanındaki tüm kaynakları sırayla taramak için çalışır; önce log 
-ile fonksiyonun çağrıldığı belirtilir, ardından veritabanından tüm kaynak id’leri çekilir, hata 
+/*ScrapeAll fonksiyonu, veritabanındaki tüm kaynakları sırayla taramak için çalışır; önce tarama 
+turu için bir korelasyon ID'si üretilir ve context'e eklenir (turun tüm logları bu ID'yi
+taşır), ardından veritabanından tüm kaynak id’leri çekilir, hata 
 olursa loglanır ve fonksiyon sonlanır, satırlar tek tek okunarak sourceIDs listesine eklenir 
 ve okuma sırasında hata olursa uyarı loglanır ama diğer kaynaklar işlenmeye devam eder,
  eğer kaynak bulunamazsa uyarı loglanır ve fonksiyon çıkar, her kaynak için 
- ScrapeSource(sourceID) çağrısı yapılır ve kaynaklar arasında 2 saniye bekleme eklenir, 
+ ScrapeSource(sourceID) çağrısı yapılır ve kaynaklar arasında scheduler.source_delay kadar bekleme eklenir, 
  böylece tarama yükü dengelenir ve tüm kaynaklar işlendiğinde tamamlandığı loglanır; 
 kısacası veritabanındaki her kaynağı sırayla tarar, hataları loglar ve tarama ilerleyişini kaydeder.
 */
-func (s *ScraperService) ScrapeAll() {
-	log.Println("[SCRAPER] ScrapeAll() called - fetching sources from database...")
-	
+func (s *ScraperService) ScrapeAll(ctx context.Context) {
+	ctx = logging.WithCorrelationID(ctx, logging.NewCorrelationID())
+	ctx, span := tracing.Start(ctx, "scrape.pass", tracing.KindInternal)
+	defer span.End()
+	started := time.Now()
+
 	var sourceIDs []int
-	rows, err := s.db.Query("SELECT id FROM sources")
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM sources")
 	if err != nil {
-		log.Printf("[SCRAPER] ERROR: Error fetching sources: %v", err)
+		logger.ErrorContext(ctx, "failed to load sources", logging.Err(err))
+		span.RecordError(err)
 		return
 	}
 	defer rows.Close()
@@ -79,36 +129,40 @@ func (s *ScraperService) ScrapeAll() {
 	for rows.Next() {
 		var id int
 		if err := rows.Scan(&id); err != nil {
-			log.Printf("[SCRAPER] ERROR: Error scanning source ID: %v", err)
+			logger.ErrorContext(ctx, "failed to scan source id", logging.Err(err))
 			continue
 		}
 		sourceIDs = append(sourceIDs, id)
 	}
 
-	log.Printf("[SCRAPER] Found %d sources to scrape", len(sourceIDs))
-	
 	if len(sourceIDs) == 0 {
-		log.Println("[SCRAPER] WARNING: No sources found in database. Please add sources first.")
+		logger.WarnContext(ctx, "no sources to scrape, add sources first")
 		return
 	}
+	logger.InfoContext(ctx, "scrape pass started", "sources", len(sourceIDs))
+	span.SetAttributes(tracing.Int("scrape.sources", len(sourceIDs)))
 
 	for i, sourceID := range sourceIDs {
-		log.Printf("[SCRAPER] Scraping source %d/%d (ID: %d)...", i+1, len(sourceIDs), sourceID)
-		s.ScrapeSource(sourceID)
+		if s.heartbeat.Load() != 0 {
+			s.beat()
+		}
+		s.ScrapeSource(ctx, sourceID)
 		if i < len(sourceIDs)-1 {
-			time.Sleep(2 * time.Second)
+			time.Sleep(s.schedule.SourceDelay.Duration)
 		}
 	}
-	
-	log.Printf("[SCRAPER] COMPLETED: ScrapeAll() finished. Processed %d sources.", len(sourceIDs))
+
+	logger.InfoContext(ctx, "scrape pass finished", "sources", len(sourceIDs), "duration", time.Since(started).String())
 }
 
 /*ScrapeSource fonksiyonu, verilen sourceID için kaynak veritabanından çekilip tarama 
-sürecini başlatır ve tamamlar; önce log ile fonksiyon çağrısı belirtilir ve defer ile panic 
+sürecini başlatır ve tamamlar; önce taramaya bir run ID'si verilir (loglarda correlation_id,
+varsa çağıranın ID'si parent_id olarak yazılır, tarama durumunda run_id) ve defer ile panic 
 durumları yakalanır, veritabanından kaynak adı ve URL alınır, hata olursa scrape durumu 
 fail olarak kaydedilir, URL boş veya geçersiz formatta ise hata loglanır ve scrape fail 
-olur, Tor durumu kontrol edilir, hazır değilse belirli denemelerle beklenir, fetch işlemi 
-FetchURLWithRetry ile yapılır, başarısız olursa scrape fail olur, içerik alınırsa 
+olur, kaynak Tor üzerinden çekiliyorsa Tor durumu kontrol edilir, hazır değilse belirli
+denemelerle beklenir, fetch işlemi kaynağın taşıması (transport) ile FetchURLWithRetry
+üzerinden yapılır, başarısız olursa scrape fail olur, içerik alınırsa 
 processFetchedContent ile entry’ler çıkarılır, eğer entry yoksa scrape complete olarak 
 kaydedilir, her entry için önce veritabanında var olup olmadığı kontrol edilir, yoksa eklenir 
 ve eklenenler sayılır, AI servisi etkinse arka planda analiz talebi gönderilir, işlem 
@@ -116,108 +170,141 @@ tamamlandığında tüm entry sayısı ve eklenen entry sayısı loglanır ve sc
 complete olarak güncellenir; kısacası kaynak doğrulanır, Tor hazırsa fetch yapılır, içerik 
 işlenir, entry’ler veritabanına eklenir, hatalar loglanır ve scrape durumu yönetilir
 */
-func (s *ScraperService) ScrapeSource(sourceID int) {
-	log.Printf("[SCRAPER] ScrapeSource called for source ID: %d", sourceID)
-	
-	
-	var sourceName, sourceURL string
+func (s *ScraperService) ScrapeSource(ctx context.Context, sourceID int) {
+	parentID := logging.CorrelationID(ctx)
+	runID := logging.NewCorrelationID()
+	ctx = logging.WithCorrelationID(ctx, runID)
+	log := logger.With("source_id", sourceID)
+	if parentID != "" {
+		log = log.With("parent_id", parentID)
+	}
+
+	ctx, span := tracing.Start(ctx, "scrape.source", tracing.KindInternal,
+		tracing.Int("source.id", sourceID), tracing.String("run_id", runID))
+	defer func() {
+		if state := LastScrape(sourceID); state != nil && state.RunID == runID {
+			span.SetAttributes(tracing.String("scrape.status", state.Status),
+				tracing.Int("scrape.entries_found", state.EntriesFound),
+				tracing.Int("scrape.entries_inserted", state.EntriesInserted))
+			if state.Status == "failed" {
+				span.RecordError(errors.New(state.Error))
+			}
+		}
+		span.End()
+	}()
+
+	var sourceName, sourceURL, sourceTransport, proxyURL string
 	var scrapeStarted bool
-	
+
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("[SCRAPER] PANIC recovered in ScrapeSource for source ID %d: %v", sourceID, r)
+			log.ErrorContext(ctx, "panic recovered in scrape", "panic", fmt.Sprint(r))
 			if scrapeStarted {
 				globalStateManager.failScrape(sourceID, fmt.Errorf("panic: %v", r))
 			}
 		}
 	}()
 
-	err := s.db.QueryRow("SELECT name, url FROM sources WHERE id = $1", sourceID).Scan(&sourceName, &sourceURL)
+	err := s.db.QueryRowContext(ctx, "SELECT name, url, transport, COALESCE(proxy_url, '') FROM sources WHERE id = $1", sourceID).
+		Scan(&sourceName, &sourceURL, &sourceTransport, &proxyURL)
 	if err != nil {
-		log.Printf("[SCRAPER] ERROR: Failed to fetch source ID %d from database: %v", sourceID, err)
+		log.ErrorContext(ctx, "failed to load source", logging.Err(err))
+		span.RecordError(err)
 		globalStateManager.failScrape(sourceID, fmt.Errorf("database error: %v", err))
 		return
 	}
-	
-	
-	globalStateManager.startScrape(sourceID, sourceName)
-	scrapeStarted = true
 
-	log.Printf("[SCRAPER] Source found: ID=%d, Name=%s, URL=%s", sourceID, sourceName, sourceURL)
+	globalStateManager.startScrape(sourceID, sourceName, runID)
+	scrapeStarted = true
+	sourceTransport = service.SourceTransport(sourceURL, sourceTransport)
+	span.SetAttributes(tracing.String("source.name", sourceName), tracing.String("url.full", sourceURL),
+		tracing.String("source.transport", sourceTransport))
+	log = log.With("source_name", sourceName)
+	log.InfoContext(ctx, "scrape started", "url", sourceURL, "transport", sourceTransport)
 
 	if sourceURL == "" {
 		err := fmt.Errorf("source URL is empty")
-		log.Printf("[SCRAPER] ERROR: Source URL is empty for source '%s' (ID: %d). Skipping.", sourceName, sourceID)
+		log.ErrorContext(ctx, "scrape skipped", logging.Err(err))
 		globalStateManager.failScrape(sourceID, err)
 		return
 	}
 
 	if !strings.HasPrefix(sourceURL, "http://") && !strings.HasPrefix(sourceURL, "https://") {
 		err := fmt.Errorf("invalid URL format: must start with http:// or https://")
-		log.Printf("[SCRAPER] ERROR: Invalid URL format for source '%s' (ID: %d). URL must start with http:// or https://. Got: %s", sourceName, sourceID, sourceURL)
+		log.ErrorContext(ctx, "scrape skipped", "url", sourceURL, logging.Err(err))
 		globalStateManager.failScrape(sourceID, err)
 		return
 	}
 
-	status, err := CheckTorReadiness()
-	if err != nil || !status.IsReady {
-		torErr := fmt.Errorf("Tor not ready: %s", status.Message)
-		log.Printf("[SCRAPER] ERROR: Cannot scrape source ID %d - %v", sourceID, torErr)
-		log.Printf("[SCRAPER] Retrying Tor readiness check...")
-		
-		if waitErr := WaitForTorReady(5, 2*time.Second); waitErr != nil {
-			log.Printf("[SCRAPER] ERROR: Tor did not become ready for source ID %d: %v", sourceID, waitErr)
-			globalStateManager.failScrape(sourceID, fmt.Errorf("Tor not ready: %v", waitErr))
-			return
+	if sourceTransport == service.TransportTor {
+		status, err := CheckTorReadiness()
+		if err != nil || !status.IsReady {
+			log.WarnContext(ctx, "Tor not ready, waiting before fetch", "tor_message", status.Message)
+			if waitErr := WaitForTorReady(ctx, 5, 2*time.Second); waitErr != nil {
+				log.ErrorContext(ctx, "Tor did not become ready", logging.Err(waitErr))
+				globalStateManager.failScrape(sourceID, fmt.Errorf("Tor not ready: %v", waitErr))
+				return
+			}
 		}
-		log.Printf("[SCRAPER] Tor became ready, continuing scrape for source ID %d", sourceID)
 	}
 
-	log.Printf("[SCRAPER] Attempting to fetch from %s via Tor...", sourceURL)
-	rawContent, fetchError := FetchURLWithRetry(sourceURL, 3, 5*time.Second)
+	fetchCtx := WithSourceTransport(ctx, sourceTransport, proxyURL)
+	// Each source (or run) gets its own Tor circuits so the monitored sites cannot be correlated
+	fetchCtx = WithTorIsolation(fetchCtx, scrapeIsolationDomain(sourceID, runID))
+	fetchCtx, fetchSpan := tracing.Start(fetchCtx, "scrape.fetch", tracing.KindInternal,
+		tracing.Int("scrape.max_attempts", s.schedule.FetchRetries))
+	rawContent, fetchError := FetchURLWithRetry(fetchCtx, sourceURL, s.schedule.FetchRetries, s.schedule.RetryDelay.Duration)
+	fetchSpan.SetAttributes(tracing.Int("scrape.body_bytes", len(rawContent)))
+	fetchSpan.RecordError(fetchError)
+	fetchSpan.End()
 	if fetchError != nil {
-		log.Printf("[SCRAPER] ERROR: Failed to fetch from %s after retries: %v. Skipping this source.", sourceURL, fetchError)
+		log.ErrorContext(ctx, "fetch failed, skipping source", "url", sourceURL, logging.Err(fetchError))
 		globalStateManager.failScrape(sourceID, fmt.Errorf("fetch failed: %v", fetchError))
 		return
 	}
 
-	log.Printf("[SCRAPER] Successfully fetched content from %s (length: %d bytes)", sourceURL, len(rawContent))
+	log.DebugContext(ctx, "content fetched", "bytes", len(rawContent))
+	sourceLabel := strconv.Itoa(sourceID)
+	fetchBytesTotal.Add(float64(len(rawContent)), sourceLabel)
 
 	if rawContent == "" {
 		err := fmt.Errorf("no content fetched from URL")
-		log.Printf("[SCRAPER] ERROR: No content fetched from %s (source ID: %d). Skipping.", sourceURL, sourceID)
+		log.ErrorContext(ctx, "scrape failed", logging.Err(err))
 		globalStateManager.failScrape(sourceID, err)
 		return
 	}
 
-	log.Printf("[SCRAPER] Processing fetched content from %s (length: %d bytes)", sourceURL, len(rawContent))
-	entries := s.processFetchedContent(sourceName, sourceURL, rawContent)
-		log.Printf("[SCRAPER] Processed %d entries from %s", len(entries), sourceURL)
-	
+	entries := s.processFetchedContent(ctx, sourceName, sourceURL, rawContent)
+
 	if len(entries) == 0 {
-		log.Printf("[SCRAPER] WARNING: No entries extracted from %s after processing (source ID: %d)", sourceURL, sourceID)
+		log.WarnContext(ctx, "no entries extracted", "bytes", len(rawContent))
 		globalStateManager.completeScrape(sourceID, 0, 0)
 		return
 	}
 
-	log.Printf("[SCRAPER] Processing %d entries for source ID %d", len(entries), sourceID)
 	entriesInserted := 0
 
-	for i, entry := range entries {
-		log.Printf("[SCRAPER] Processing entry %d/%d: %s", i+1, len(entries), entry.Title)
-		
+	for _, entry := range entries {
+		insertCtx, insertSpan := tracing.Start(ctx, "scrape.insert", tracing.KindClient,
+			tracing.String("db.system", "postgresql"), tracing.String("db.sql.table", "data_entries"))
 		var exists bool
-		err := s.db.QueryRow(`
+		err := s.db.QueryRowContext(insertCtx, `
 			SELECT EXISTS(SELECT 1 FROM data_entries WHERE source_id = $1 AND title = $2)
 		`, sourceID, entry.Title).Scan(&exists)
 
 		if err != nil {
-			log.Printf("[SCRAPER] ERROR: Failed to check entry existence for '%s': %v", entry.Title, err)
+			log.ErrorContext(ctx, "failed to check whether entry exists", "title", entry.Title, logging.Err(err))
+			entriesTotal.Inc(sourceLabel, "failed")
+			insertSpan.RecordError(err)
+			insertSpan.End()
 			continue
 		}
 
 		if exists {
-			log.Printf("[SCRAPER] Entry already exists, skipping: %s", entry.Title)
+			log.DebugContext(ctx, "entry already known, skipping", "title", entry.Title)
+			entriesTotal.Inc(sourceLabel, "skipped")
+			insertSpan.SetAttributes(tracing.String("scrape.entry_result", "skipped"))
+			insertSpan.End()
 			continue
 		}
 
@@ -229,30 +316,77 @@ func (s *ScraperService) ScrapeSource(sourceID int) {
 			shareDateValue = nil
 		}
 		
-		err = s.db.QueryRow(`
+		err = s.db.QueryRowContext(insertCtx, `
 			INSERT INTO data_entries (source_id, title, cleaned_content, share_date, criticality_score, category)
 			VALUES ($1, $2, $3, $4, $5, $6)
 			RETURNING id
 		`, sourceID, entry.Title, entry.CleanedContent, shareDateValue, entry.CriticalityScore, entry.Category).Scan(&entryID)
 
 		if err != nil {
-			log.Printf("[SCRAPER] ERROR: Failed to insert entry '%s': %v", entry.Title, err)
+			log.ErrorContext(ctx, "failed to insert entry", "title", entry.Title, logging.Err(err))
+			entriesTotal.Inc(sourceLabel, "failed")
+			insertSpan.RecordError(err)
+			insertSpan.End()
 			continue
 		}
+		insertSpan.SetAttributes(tracing.String("scrape.entry_result", "inserted"), tracing.Int("entry.id", entryID))
+		insertSpan.End()
 
 		entriesInserted++
-		log.Printf("[SCRAPER] SUCCESS: New entry inserted - ID: %d, Title: %s, Category: %s, Criticality: %d", 
-			entryID, entry.Title, entry.Category, entry.CriticalityScore)
+		entriesTotal.Inc(sourceLabel, "inserted")
+		log.InfoContext(ctx, "entry inserted", "entry_id", entryID, "title", entry.Title,
+			"category", entry.Category, "criticality", entry.CriticalityScore)
+
+		s.emitter.Emit(siem.Event{
+			Type:             siem.EventNewEntry,
+			EntryID:          entryID,
+			SourceID:         sourceID,
+			SourceName:       sourceName,
+			SourceURL:        sourceURL,
+			Title:            entry.Title,
+			Category:         entry.Category,
+			CriticalityScore: entry.CriticalityScore,
+			Actor:            "system",
+		})
 
 		if s.aiService != nil && s.aiService.IsEnabled() {
-			go s.requestAIAnalysis(entryID, entry.Title, entry.CleanedContent, entry.Category, entry.CriticalityScore)
+			s.analyses.Add(1)
+			aiQueueDepth.Inc()
+			// The analysis outlives the scrape, so it gets its own ID linked to this run
+			jobCtx := logging.WithCorrelationID(context.WithoutCancel(ctx), logging.NewCorrelationID())
+			go func(entryID int, entry ScrapedEntry) {
+				defer s.analyses.Done()
+				defer aiQueueDepth.Dec()
+				s.requestAIAnalysis(jobCtx, runID, entryID, entry.Title, entry.CleanedContent, entry.Category, entry.CriticalityScore)
+			}(entryID, entry)
 		}
 	}
 
-	log.Printf("[SCRAPER] COMPLETED: Source ID %d processed. %d entries inserted, %d entries skipped.", 
-		sourceID, 		entriesInserted, len(entries)-entriesInserted)
-	
+	log.InfoContext(ctx, "scrape completed", "entries_found", len(entries),
+		"entries_inserted", entriesInserted, "entries_skipped", len(entries)-entriesInserted)
+
 	globalStateManager.completeScrape(sourceID, len(entries), entriesInserted)
+
+	if entriesInserted > 0 {
+		s.recorder.Record(audit.Entry{
+			Actor:      audit.ActorSystem,
+			Action:     "scrape",
+			TargetType: audit.TargetSource,
+			TargetID:   fmt.Sprint(sourceID),
+			Details: audit.Snapshot(map[string]interface{}{
+				"source_name": sourceName,
+				"found":       len(entries),
+				"inserted":    entriesInserted,
+				"run_id":      runID,
+			}),
+			Success: true,
+		})
+	}
+}
+
+// WaitForAnalyses blocks until background AI analyses started by past scrapes have finished
+func (s *ScraperService) WaitForAnalyses() {
+	s.analyses.Wait()
 }
 
 /*Bu ScrapedEntry yapısı, bir kaynaktan çekilen ve işlenen her bir veri girdisini temsil eder; 
@@ -277,23 +411,29 @@ calculateContentCriticality ile kritik skor belirleniyor ve ParseShareDate ile p
 tarihi çekiliyor, bunlar ScrapedEntry yapısında paketlenip listeye ekleniyor, eğer içerik çok 
 kısa ise entry oluşturulmadan atlanıyor.
 */
-func (s *ScraperService) processFetchedContent(sourceName, sourceURL, rawContent string) []ScrapedEntry {
+func (s *ScraperService) processFetchedContent(ctx context.Context, sourceName, sourceURL, rawContent string) []ScrapedEntry {
 	entries := []ScrapedEntry{}
 	
-	log.Printf("processFetchedContent: Processing content from %s (length: %d bytes)", sourceURL, len(rawContent))
-	
 	if len(rawContent) > 100 {
-		log.Printf("Content length > 100 bytes, creating entry...")
+		_, span := tracing.Start(ctx, "scrape.clean", tracing.KindInternal, tracing.Int("scrape.body_bytes", len(rawContent)))
 		title := s.extractTitle(rawContent)
 		cleanedContent := s.cleanContent(rawContent)
+		span.SetAttributes(tracing.Int("scrape.cleaned_bytes", len(cleanedContent)))
+		span.End()
 		
-		log.Printf("Extracted title: %s (cleaned content length: %d)", title, len(cleanedContent))
+		logger.DebugContext(ctx, "extracted title", "title", title, "cleaned_bytes", len(cleanedContent))
 		
+		_, span = tracing.Start(ctx, "scrape.classify", tracing.KindInternal)
 		category := s.detectCategory(cleanedContent, title)
 		
 		criticalityScore := s.calculateContentCriticality(cleanedContent, title, category)
+		span.SetAttributes(tracing.String("scrape.category", category), tracing.Int("scrape.criticality", criticalityScore))
+		span.End()
 		
+		_, span = tracing.Start(ctx, "scrape.parse_date", tracing.KindInternal)
 		shareDate := s.ParseShareDate(rawContent)
+		span.SetAttributes(tracing.Bool("scrape.share_date_found", shareDate != nil))
+		span.End()
 		
 		entry := ScrapedEntry{
 			Title:            title,
@@ -304,9 +444,8 @@ func (s *ScraperService) processFetchedContent(sourceName, sourceURL, rawContent
 		}
 		
 		entries = append(entries, entry)
-		log.Printf("Created entry: %s", title)
 	} else {
-		log.Printf("Content too short (%d bytes), skipping entry creation", len(rawContent))
+		logger.DebugContext(ctx, "content too short, no entry created", "url", sourceURL, "bytes", len(rawContent))
 	}
 	
 	return entries
@@ -582,10 +721,11 @@ talebi gönderiyor, eğer analiz başarılı olursa sonucu veritabanındaki ilgi
 hata oluşursa log’a yazıyor ama sistem normal akışına devam ediyor, böylece AI hataları 
 scraping sürecini durdurmuyor ve her giriş için opsiyonel bir ek analiz sağlıyor.
 */
-func (s *ScraperService) requestAIAnalysis(entryID int, title, content, category string, criticalityScore int) {
-	analysis, err := s.aiService.AnalyzeEntry(title, content, category, criticalityScore)
+func (s *ScraperService) requestAIAnalysis(ctx context.Context, runID string, entryID int, title, content, category string, criticalityScore int) {
+	log := logger.With("entry_id", entryID, "run_id", runID)
+	analysis, err := s.aiService.AnalyzeEntry(ctx, title, content, category, criticalityScore)
 	if err != nil {
-		log.Printf("AI analysis failed for entry %d: %v (system continues normally)", entryID, err)
+		log.WarnContext(ctx, "AI analysis failed, entry kept without analysis", logging.Err(err))
 		return
 	}
 
@@ -593,16 +733,20 @@ func (s *ScraperService) requestAIAnalysis(entryID int, title, content, category
 		return
 	}
 
-	_, err = s.db.Exec(`
+	ctx, span := tracing.Start(ctx, "scrape.store_analysis", tracing.KindClient,
+		tracing.String("db.system", "postgresql"), tracing.String("db.sql.table", "data_entries"))
+	_, err = s.db.ExecContext(ctx, `
 		UPDATE data_entries 
 		SET ai_analysis = $1 
 		WHERE id = $2
 	`, analysis, entryID)
+	span.RecordError(err)
+	span.End()
 
 	if err != nil {
-		log.Printf("Error updating AI analysis for entry %d: %v", entryID, err)
+		log.ErrorContext(ctx, "failed to store AI analysis", logging.Err(err))
 	} else {
-		log.Printf("AI analysis added for entry %d", entryID)
+		log.DebugContext(ctx, "AI analysis stored")
 	}
 }
 
@@ -16,6 +16,7 @@ scraping sürecini takip etmesini ve raporlamasını sağlar.
 type ScrapeState struct {
 	SourceID      int       `json:"source_id"`
 	SourceName    string    `json:"source_name"`
+	RunID         string    `json:"run_id"`
 	Status        string    `json:"status"`
 	StartedAt     time.Time `json:"started_at"`
 	CompletedAt   *time.Time `json:"completed_at,omitempty"`
@@ -94,20 +95,34 @@ func GetRecentScrapes(limit int) []*ScrapeState {
 	return result
 }
 
+// LastScrape returns the most recent finished scrape of a source, or nil if it has not been scraped since startup
+func LastScrape(sourceID int) *ScrapeState {
+	globalStateManager.mu.RLock()
+	defer globalStateManager.mu.RUnlock()
+
+	for _, state := range globalStateManager.recentScrapes {
+		if state.SourceID == sourceID {
+			return state
+		}
+	}
+	return nil
+}
+
 /*Bu fonksiyon, ScraperStateManager üzerinde yeni bir tarama süreci başlatır ve bunu 
 thread-safe şekilde kaydeder. mu.Lock() ile yazma işlemi sırasında kilitlenmeyi sağlar, 
 defer mu.Unlock() ile kilidi serbest bırakır. Fonksiyon, verilen sourceID ve sourceName 
-ile bir ScrapeState nesnesi oluşturur, Status alanını "running" olarak ayarlar ve 
+ile bir ScrapeState nesnesi oluşturur, loglardaki correlation_id ile eşleşen runID'yi saklar, Status alanını "running" olarak ayarlar ve 
 StartedAt zamanını şu anki zamanla doldurur. Ardından bu yeni durumu activeScrapes 
 haritasına ekler, böylece sistem kaynağın aktif tarama durumunu izleyebilir.
 */
-func (sm *ScraperStateManager) startScrape(sourceID int, sourceName string) {
+func (sm *ScraperStateManager) startScrape(sourceID int, sourceName, runID string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	
 	state := &ScrapeState{
 		SourceID:   sourceID,
 		SourceName: sourceName,
+		RunID:      runID,
 		Status:     "running",
 		StartedAt:  time.Now(),
 	}
@@ -137,6 +152,7 @@ func (sm *ScraperStateManager) completeScrape(sourceID int, entriesFound, entrie
 	state.CompletedAt = &now
 	state.EntriesFound = entriesFound
 	state.EntriesInserted = entriesInserted
+	observeScrape(state)
 	
 	
 	sm.recentScrapes = append([]*ScrapeState{state}, sm.recentScrapes...)
@@ -168,6 +184,7 @@ func (sm *ScraperStateManager) failScrape(sourceID int, err error) {
 	state.Status = "failed"
 	state.CompletedAt = &now
 	state.Error = err.Error()
+	observeScrape(state)
 	
 	
 	sm.recentScrapes = append([]*ScrapeState{state}, sm.recentScrapes...)
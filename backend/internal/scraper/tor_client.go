@@ -3,57 +3,128 @@ package scraper
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/net/proxy"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/service"
+	"interactive-scraper/internal/tracing"
 )
 
+var torLogger = logging.For("tor")
+
+// torSettings is replaced by Configure at startup, before any Tor client is created
+var torSettings = config.Default().Tor
+
+// Configure sets the Tor SOCKS proxy and fetch timeout used by the package's Tor clients
+func Configure(cfg config.TorConfig) {
+	torClientCacheMutex.Lock()
+	defer torClientCacheMutex.Unlock()
+	torSettings = cfg
+	torClientCache = map[torClientKey]*torClientEntry{}
+	activeTorPool.Store(newTorPool(cfg))
+}
+
+// torIsolationPassword is sent with every isolation domain's SOCKS username; Tor isolates on both
+const torIsolationPassword = "interactive-scraper"
+
+// torClientKey identifies the cached client of one Tor instance and isolation domain
+type torClientKey struct {
+	address string
+	domain  string
+}
+
+// torClientEntry is a cached client of one Tor instance and isolation domain
+type torClientEntry struct {
+	client  *http.Client
+	created time.Time
+}
+
 var (
-	torClientCache     *http.Client
+	// torClientCache is keyed by instance and isolation domain; domain "" is the shared client used by status checks
+	torClientCache      = map[torClientKey]*torClientEntry{}
 	torClientCacheMutex sync.RWMutex
-	torClientCacheTime time.Time
-	torClientCacheTTL  = 5 * time.Minute
+	torClientCacheTTL   = 5 * time.Minute
 )
 
-/*Bu GetTorHTTPClient fonksiyonu, Tor ağı üzerinden HTTP istekleri gönderebilen bir 
-http.Client oluşturuyor ve performans için bir önbellekleme mekanizması kullanıyor; 
-önce önbellekte geçerli bir client var mı kontrol ediyor, varsa onu döndürüyor, 
-yoksa SOCKS5 proxy (varsayılan tor:9050 veya TOR_PROXY ortam değişkeni) üzerinden 
-yeni bir client yaratıyor ve önbelleğe kaydediyor. Böylece Tor üzerinden güvenli ve 
-anonim HTTP istekleri yapmayı sağlıyor ve tekrar tekrar client yaratma maliyetini önlüyor.
+type torIsolationKey struct{}
+
+/*Bu fonksiyon, ctx ile yapılan Tor isteklerinin verilen izolasyon alanının (domain)
+devrelerini kullanmasını sağlar. Her alan Tor'a farklı bir SOCKS5 kullanıcı adıyla
+bağlanır; Tor'un varsayılan olarak açık olan IsolateSOCKSAuth ayarı sayesinde farklı
+kimlik bilgileriyle açılan bağlantılar aynı devreyi paylaşmaz. Böylece izlenen siteler
+ortak bir çıkış rölesi veya devre üzerinden birbiriyle ilişkilendirilemez. Boş alan
+paylaşılan client'ı seçer.
 */
+func WithTorIsolation(ctx context.Context, domain string) context.Context {
+	return context.WithValue(ctx, torIsolationKey{}, domain)
+}
+
+// torIsolation returns the isolation domain set with WithTorIsolation, or ""
+func torIsolation(ctx context.Context) string {
+	domain, _ := ctx.Value(torIsolationKey{}).(string)
+	return domain
+}
+
+// scrapeIsolationDomain picks the isolation domain of a scrape according to tor.isolation
+func scrapeIsolationDomain(sourceID int, runID string) string {
+	switch torSettings.Isolation {
+	case "run":
+		return "run-" + runID
+	case "none":
+		return ""
+	default:
+		return "source-" + strconv.Itoa(sourceID)
+	}
+}
+
+// GetTorHTTPClient returns the shared client of a Tor instance from the pool, which dials without SOCKS credentials
 func GetTorHTTPClient() (*http.Client, error) {
+	return getTorHTTPClient(activeTorPool.Load().pick().address, "")
+}
+
+/*Bu getTorHTTPClient fonksiyonu, Tor ağı üzerinden HTTP istekleri gönderebilen bir 
+http.Client oluşturuyor ve performans için bir önbellekleme mekanizması kullanıyor; 
+önbellek Tor instance'ı ve izolasyon alanına (domain) göre tutuluyor, önce o ikili için
+geçerli bir client var mı kontrol ediyor, varsa onu döndürüyor, yoksa verilen SOCKS5
+proxy adresi üzerinden alana özel kullanıcı adıyla yeni bir client yaratıyor ve
+önbelleğe kaydediyor. Süresi dolan client'lar bu sırada önbellekten atılıyor ve boştaki
+bağlantıları kapatılıyor; böylece her tarama için ayrı alan açan "run" modunda da
+önbellek büyümüyor.
+*/
+func getTorHTTPClient(address, domain string) (*http.Client, error) {
+	key := torClientKey{address, domain}
 	torClientCacheMutex.RLock()
-	if torClientCache != nil && time.Since(torClientCacheTime) < torClientCacheTTL {
-		client := torClientCache
+	if entry := torClientCache[key]; entry != nil && time.Since(entry.created) < torClientCacheTTL {
 		torClientCacheMutex.RUnlock()
-		return client, nil
+		return entry.client, nil
 	}
 	torClientCacheMutex.RUnlock()
 
-	torProxy := os.Getenv("TOR_PROXY")
-	if torProxy == "" {
-		torProxy = "tor:9050"
+	var auth *proxy.Auth
+	if domain != "" {
+		auth = &proxy.Auth{User: domain, Password: torIsolationPassword}
 	}
-	dialer, err := proxy.SOCKS5("tcp", torProxy, nil, proxy.Direct)
+	dialer, err := proxy.SOCKS5("tcp", address, auth, torForwardDialer{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Tor dialer: %v", err)
 	}
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+		return conn, classifyTorDialError(ctx, err)
+	}
 
 	transport := &http.Transport{
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return dialer.Dial(network, addr)
-		},
-		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return dialer.Dial(network, addr)
-		},
+		DialContext:           dial,
+		DialTLSContext:        dial,
 		MaxIdleConns:          10,
 		MaxIdleConnsPerHost:   5,
 		IdleConnTimeout:        90 * time.Second,
@@ -65,17 +136,41 @@ func GetTorHTTPClient() (*http.Client, error) {
 	}
 
 	client := &http.Client{
-		Transport: transport,
-		Timeout:   60 * time.Second,
+		Transport:     transport,
+		Timeout:       torSettings.FetchTimeout.Duration,
+		CheckRedirect: checkRedirectFor(service.TransportTor),
 	}
 	torClientCacheMutex.Lock()
-	torClientCache = client
-	torClientCacheTime = time.Now()
+	for key, entry := range torClientCache {
+		if time.Since(entry.created) >= torClientCacheTTL {
+			entry.client.CloseIdleConnections()
+			delete(torClientCache, key)
+		}
+	}
+	torClientCache[key] = &torClientEntry{client: client, created: time.Now()}
 	torClientCacheMutex.Unlock()
+	torLogger.Debug("created Tor client", "instance", address, "isolation", domain)
 
 	return client, nil
 }
 
+// closeIdleTorConnections drops kept-alive connections so the next requests open streams on new circuits
+func closeIdleTorConnections() {
+	torClientCacheMutex.Lock()
+	defer torClientCacheMutex.Unlock()
+	for _, entry := range torClientCache {
+		entry.client.CloseIdleConnections()
+	}
+	torClientCache = map[torClientKey]*torClientEntry{}
+}
+
+// torClientCount is the number of cached Tor clients, one per instance and isolation domain
+func torClientCount() int {
+	torClientCacheMutex.RLock()
+	defer torClientCacheMutex.RUnlock()
+	return len(torClientCache)
+}
+
 /*Bu TestTorConnection fonksiyonu, Tor ağı üzerinden HTTP isteklerinin çalışıp 
 çalışmadığını kontrol ediyor; önce GetTorHTTPClient() ile Tor üzerinden bağlanabilen bir 
 HTTP client alıyor, sonra http://check.torproject.org adresine GET isteği gönderiyor ve
@@ -107,12 +202,64 @@ func TestTorConnection() error {
 	return nil
 }
 
-func FetchURL(urlString string) (string, error) {
-	client, err := GetTorHTTPClient()
+// FetchURL fetches a page over the source's transport and records the attempt in scraper_fetch_duration_seconds
+func FetchURL(ctx context.Context, urlString string) (string, error) {
+	route := fetchRoute(ctx, urlString)
+	attrs := []tracing.Attr{tracing.String("http.request.method", "GET"), tracing.String("url.full", urlString)}
+	switch route.transport {
+	case service.TransportTor:
+		attrs = append(attrs, tracing.String("tor.isolation", torIsolation(ctx)))
+	case service.TransportProxy:
+		attrs = append(attrs, tracing.String("fetch.proxy", redactProxyURL(route.proxyURL)))
+	}
+	ctx, span := tracing.Start(ctx, route.transport+".fetch", tracing.KindClient, attrs...)
+	defer span.End()
+
+	started := time.Now()
+	content, err := fetchURL(ctx, urlString, route)
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	fetchDuration.Observe(time.Since(started).Seconds(), route.transport, outcome)
+	span.SetAttributes(tracing.Int("http.response.body.size", len(content)))
+	span.RecordError(err)
+	return content, err
+}
+
+/*Bu fonksiyon, sayfayı kaynağın taşımasına uygun client ile çeker. Tor'da havuzdan bir
+instance seçilir, izolasyon alanının client'ı kullanılır ve sonuç instance'ın sağlık
+durumuna ve otomatik NEWNYM sayacına yazılır; I2P, proxy ve direct taşımalarında
+transportClient'ın döndürdüğü client ve zaman aşımı kullanılır. İstek ve yanıtın
+işlenmesi tüm taşımalarda aynıdır.
+*/
+func fetchURL(ctx context.Context, urlString string, route sourceRoute) (string, error) {
+	if route.transport != service.TransportTor {
+		client, timeout, err := transportClient(route)
+		if err != nil {
+			return "", err
+		}
+		return fetchWithClient(ctx, client, timeout, urlString, nil)
+	}
+
+	instance := activeTorPool.Load().pick()
+	tracing.FromContext(ctx).SetAttributes(tracing.String("tor.instance", instance.address))
+	client, err := getTorHTTPClient(instance.address, torIsolation(ctx))
 	if err != nil {
 		return "", err
 	}
 
+	instance.inFlight.Add(1)
+	defer instance.inFlight.Add(-1)
+	return fetchWithClient(ctx, client, torSettings.FetchTimeout.Duration, urlString, func(ctx context.Context, err error) {
+		instance.report(ctx, err)
+		recordTorFetch(ctx, err)
+	})
+}
+
+// fetchWithClient GETs urlString and returns its text body; report, if set, sees whether a response arrived
+func fetchWithClient(ctx context.Context, client *http.Client, timeout time.Duration, urlString string,
+	report func(context.Context, error)) (string, error) {
 	parsedURL, err := url.Parse(urlString)
 	if err != nil {
 		return "", fmt.Errorf("invalid URL: %v", err)
@@ -127,14 +274,19 @@ func FetchURL(urlString string) (string, error) {
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	resp, err := client.Do(req.WithContext(ctx))
+	if report != nil {
+		report(ctx, err)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch URL: %v", err)
 	}
 	defer resp.Body.Close()
+	// Trace context is never propagated to scraped sites, only recorded locally
+	tracing.FromContext(ctx).SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
@@ -148,7 +300,7 @@ func FetchURL(urlString string) (string, error) {
 			strings.HasPrefix(contentType, "application/xml")
 		
 		if !isTextContent {
-			log.Printf("[SCRAPER] WARNING: Rejecting non-text content type: %s from %s", contentType, urlString)
+			logger.WarnContext(ctx, "rejecting non-text content type", "content_type", contentType, "url", urlString)
 			return "", fmt.Errorf("unsupported content type: %s (only text/html accepted)", contentType)
 		}
 	}
@@ -169,14 +321,14 @@ func FetchURL(urlString string) (string, error) {
 		return string(body), nil
 }
 
-func FetchURLWithRetry(urlString string, maxRetries int, retryDelay time.Duration) (string, error) {
+func FetchURLWithRetry(ctx context.Context, urlString string, maxRetries int, retryDelay time.Duration) (string, error) {
 	var lastErr error
 	
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		content, err := FetchURL(urlString)
+		content, err := FetchURL(ctx, urlString)
 		if err == nil {
 			if attempt > 1 {
-				log.Printf("[TOR] Fetch succeeded on attempt %d/%d for %s", attempt, maxRetries, urlString)
+				torLogger.InfoContext(ctx, "fetch succeeded after retry", "attempt", attempt, "max_attempts", maxRetries, "url", urlString)
 			}
 			return content, nil
 		}
@@ -191,19 +343,24 @@ func FetchURLWithRetry(urlString string, maxRetries int, retryDelay time.Duratio
 			strings.Contains(errStr, "temporary")
 		
 		if !isRetryable && attempt < maxRetries {
-			log.Printf("[TOR] Non-retryable error on attempt %d/%d for %s: %v", attempt, maxRetries, urlString, err)
+			torLogger.WarnContext(ctx, "non-retryable fetch error", "attempt", attempt, "max_attempts", maxRetries,
+				"url", urlString, logging.Err(err))
 		} else if attempt < maxRetries {
-			log.Printf("[TOR] Fetch failed on attempt %d/%d for %s: %v. Retrying in %v...", 
-				attempt, maxRetries, urlString, err, retryDelay)
+			torLogger.WarnContext(ctx, "fetch failed, retrying", "attempt", attempt, "max_attempts", maxRetries,
+				"url", urlString, "retry_in", retryDelay.String(), logging.Err(err))
 		}
 		
 		if attempt < maxRetries {
-			status, _ := CheckTorReadiness()
-			if !status.IsReady {
-				log.Printf("[TOR] Tor not ready, waiting before retry...")
-				time.Sleep(retryDelay * 2)
-			} else {
-				time.Sleep(retryDelay)
+			delay := retryDelay
+			if fetchRoute(ctx, urlString).transport == service.TransportTor {
+				status, _ := CheckTorReadiness()
+				if !status.IsReady {
+					torLogger.WarnContext(ctx, "Tor not ready, waiting before retry")
+					delay = retryDelay * 2
+				}
+			}
+			if err := sleepContext(ctx, delay); err != nil {
+				return "", err
 			}
 		}
 	}
@@ -0,0 +1,538 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"interactive-scraper/internal/logging"
+)
+
+// torControlTimeout bounds a control port exchange when the caller's context has no deadline
+const torControlTimeout = 5 * time.Second
+
+// ErrTorControlDisabled is returned while tor.control_address (TOR_CONTROL_ADDR) is empty
+var ErrTorControlDisabled = errors.New("Tor control port is not configured")
+
+// HMAC keys of SAFECOOKIE authentication, see control-spec section 3.24
+var (
+	safeCookieServerKey = []byte("Tor safe cookie authentication server-to-controller hash")
+	safeCookieClientKey = []byte("Tor safe cookie authentication controller-to-server hash")
+)
+
+var (
+	// torFetchFailures counts fetches in a row that failed on a Tor instance or its circuits
+	torFetchFailures atomic.Int64
+	// lastNewnym is the Unix time in nanoseconds of the last NEWNYM, 0 if none was sent
+	lastNewnym atomic.Int64
+)
+
+// TorBootstrap is Tor's status/bootstrap-phase
+type TorBootstrap struct {
+	Progress int    `json:"progress"`
+	Tag      string `json:"tag"`
+	Summary  string `json:"summary"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+// TorCircuit is one line of circuit-status with the number of streams attached to it
+type TorCircuit struct {
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	Purpose   string   `json:"purpose,omitempty"`
+	Path      []string `json:"path"`
+	CreatedAt string   `json:"created_at,omitempty"`
+	// Isolation is the SOCKS username the circuit is isolated for, see WithTorIsolation
+	Isolation string `json:"isolation,omitempty"`
+	Streams   int    `json:"streams"`
+}
+
+/*Bu yapı (TorControlStatus), Tor'un control portundan tek bir bağlantıda okunan durumu
+taşır: Tor sürümü, bootstrap aşaması, devreler (circuit) ve her devreye bağlı stream
+sayıları. ExitIP, en çok stream taşıyan kurulmuş genel amaçlı devrenin çıkış rölesinin
+konsensüsteki adresidir; bu sayede çıkış IP'si dış bir IP-echo servisine istek atmadan
+öğrenilir. Ardışık başarısız fetch sayısı ve son NEWNYM zamanı da otomatik devre
+yenilemenin izlenebilmesi için yanıta eklenir.
+*/
+type TorControlStatus struct {
+	Version             string       `json:"version"`
+	Bootstrap           TorBootstrap `json:"bootstrap"`
+	Circuits            []TorCircuit `json:"circuits"`
+	Streams             int          `json:"streams"`
+	ExitIP              string       `json:"exit_ip,omitempty"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	NewnymAfterFailures int          `json:"newnym_after_failures"`
+	LastNewnym          *time.Time   `json:"last_newnym,omitempty"`
+}
+
+// TorControlEnabled reports whether a control port is configured
+func TorControlEnabled() bool {
+	return torSettings.ControlAddress != ""
+}
+
+// torControlConn is an authenticated control port connection; it is not safe for concurrent use
+type torControlConn struct {
+	conn net.Conn
+	r    *textproto.Reader
+}
+
+// controlReplyLine is one line of a reply; data holds the lines of a "250+key=" data block
+type controlReplyLine struct {
+	text string
+	data []string
+}
+
+/*Bu fonksiyon, tor.control_address adresindeki ControlPort'a bağlanır ve kimlik doğrular.
+Önce PROTOCOLINFO ile Tor'un kabul ettiği yöntemler öğrenilir. Tor kimlik doğrulama
+istemiyorsa (NULL) doğrudan, parola ayarlıysa ve Tor HASHEDPASSWORD kabul ediyorsa
+parolayla, aksi halde cookie dosyasıyla doğrulanır. Cookie için mümkünse SAFECOOKIE
+kullanılır; bu yöntemde cookie'nin kendisi gönderilmez ve Tor'un da cookie'yi bildiği
+HMAC ile doğrulanır. Bağlantının tüm okuma/yazmaları context'in süresiyle (yoksa
+torControlTimeout ile) sınırlıdır.
+*/
+func dialTorControl(ctx context.Context) (*torControlConn, error) {
+	if !TorControlEnabled() {
+		return nil, ErrTorControlDisabled
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", torSettings.ControlAddress)
+	if err != nil {
+		return nil, fmt.Errorf("control port unreachable: %v", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(torControlTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c := &torControlConn{conn: conn, r: textproto.NewReader(bufio.NewReader(conn))}
+	if err := c.authenticate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *torControlConn) Close() error {
+	c.conn.Write([]byte("QUIT\r\n"))
+	return c.conn.Close()
+}
+
+// command sends one command and reads its reply; a reply code other than 2xx is returned as an error
+func (c *torControlConn) command(cmd string) ([]controlReplyLine, error) {
+	if _, err := c.conn.Write([]byte(cmd + "\r\n")); err != nil {
+		return nil, err
+	}
+	var lines []controlReplyLine
+	for {
+		line, err := c.r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) < 4 {
+			return nil, fmt.Errorf("malformed control port reply %q", line)
+		}
+		code, separator, text := line[:3], line[3], line[4:]
+		entry := controlReplyLine{text: text}
+		if separator == '+' {
+			if entry.data, err = c.r.ReadDotLines(); err != nil {
+				return nil, err
+			}
+		}
+		lines = append(lines, entry)
+		if separator != ' ' {
+			continue
+		}
+		if code[0] != '2' {
+			return nil, fmt.Errorf("Tor replied %s %s", code, text)
+		}
+		return lines, nil
+	}
+}
+
+func (c *torControlConn) authenticate() error {
+	lines, err := c.command("PROTOCOLINFO 1")
+	if err != nil {
+		return fmt.Errorf("PROTOCOLINFO failed: %v", err)
+	}
+	methods := map[string]bool{}
+	var cookieFile string
+	for _, line := range lines {
+		if rest, ok := strings.CutPrefix(line.text, "AUTH "); ok {
+			_, keywords := parseControlArgs(rest)
+			for _, method := range strings.Split(keywords["METHODS"], ",") {
+				methods[method] = true
+			}
+			cookieFile = keywords["COOKIEFILE"]
+		}
+	}
+	if torSettings.ControlCookieFile != "" {
+		cookieFile = torSettings.ControlCookieFile
+	}
+
+	switch {
+	case methods["NULL"]:
+		_, err = c.command("AUTHENTICATE")
+	case torSettings.ControlPassword != "" && methods["HASHEDPASSWORD"]:
+		_, err = c.command("AUTHENTICATE " + quoteControl(torSettings.ControlPassword))
+	case methods["SAFECOOKIE"] || methods["COOKIE"]:
+		if cookieFile == "" {
+			return errors.New("Tor did not report a cookie file; set TOR_CONTROL_COOKIE_FILE")
+		}
+		cookie, readErr := os.ReadFile(cookieFile)
+		if readErr != nil {
+			return fmt.Errorf("failed to read control cookie: %v", readErr)
+		}
+		if len(cookie) != 32 {
+			return fmt.Errorf("control cookie %s has %d bytes, expected 32", cookieFile, len(cookie))
+		}
+		if methods["SAFECOOKIE"] {
+			err = c.safeCookieAuth(cookie)
+		} else {
+			_, err = c.command("AUTHENTICATE " + hex.EncodeToString(cookie))
+		}
+	case methods["HASHEDPASSWORD"]:
+		return errors.New("Tor requires a control password; set TOR_CONTROL_PASSWORD")
+	default:
+		offered := make([]string, 0, len(methods))
+		for method := range methods {
+			offered = append(offered, method)
+		}
+		sort.Strings(offered)
+		return fmt.Errorf("no supported control port authentication method (Tor offers %s)", strings.Join(offered, ","))
+	}
+	if err != nil {
+		return fmt.Errorf("control port authentication failed: %v", err)
+	}
+	return nil
+}
+
+func (c *torControlConn) safeCookieAuth(cookie []byte) error {
+	clientNonce := make([]byte, 32)
+	if _, err := rand.Read(clientNonce); err != nil {
+		return err
+	}
+	lines, err := c.command("AUTHCHALLENGE SAFECOOKIE " + hex.EncodeToString(clientNonce))
+	if err != nil {
+		return err
+	}
+	_, keywords := parseControlArgs(strings.TrimPrefix(lines[0].text, "AUTHCHALLENGE "))
+	serverHash, err := hex.DecodeString(keywords["SERVERHASH"])
+	if err != nil {
+		return fmt.Errorf("malformed AUTHCHALLENGE reply: %v", err)
+	}
+	serverNonce, err := hex.DecodeString(keywords["SERVERNONCE"])
+	if err != nil {
+		return fmt.Errorf("malformed AUTHCHALLENGE reply: %v", err)
+	}
+
+	message := make([]byte, 0, len(cookie)+len(clientNonce)+len(serverNonce))
+	message = append(append(append(message, cookie...), clientNonce...), serverNonce...)
+	if !hmac.Equal(serverHash, safeCookieHMAC(safeCookieServerKey, message)) {
+		return errors.New("Tor's SAFECOOKIE server hash does not match the cookie")
+	}
+	_, err = c.command("AUTHENTICATE " + hex.EncodeToString(safeCookieHMAC(safeCookieClientKey, message)))
+	return err
+}
+
+func safeCookieHMAC(key, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// getInfo runs GETINFO and returns the value of each key
+func (c *torControlConn) getInfo(keys ...string) (map[string]string, error) {
+	lines, err := c.command("GETINFO " + strings.Join(keys, " "))
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(keys))
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line.text, "=")
+		if !ok {
+			continue
+		}
+		if line.data != nil {
+			value = strings.Join(line.data, "\n")
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+/*Bu fonksiyon, tek bir control port bağlantısında sürümü, bootstrap aşamasını, devreleri
+ve stream'leri okur; stream'ler bağlı oldukları devreye göre sayılır. Ardından en çok
+stream taşıyan (eşitlikte en yeni) kurulmuş genel amaçlı devrenin çıkış rölesinin adresi
+ns/id ile konsensüsten okunur. Konsensüste röle bulunamazsa ExitIP boş kalır; bu bir hata
+sayılmaz.
+*/
+func (c *torControlConn) status() (*TorControlStatus, error) {
+	info, err := c.getInfo("version", "status/bootstrap-phase", "circuit-status", "stream-status")
+	if err != nil {
+		return nil, err
+	}
+	status := &TorControlStatus{
+		Version:             info["version"],
+		Bootstrap:           parseBootstrapPhase(info["status/bootstrap-phase"]),
+		Circuits:            []TorCircuit{},
+		ConsecutiveFailures: int(torFetchFailures.Load()),
+		NewnymAfterFailures: torSettings.NewnymAfterFailures,
+	}
+	if nanos := lastNewnym.Load(); nanos != 0 {
+		t := time.Unix(0, nanos).UTC()
+		status.LastNewnym = &t
+	}
+
+	streamsPerCircuit := map[string]int{}
+	for _, line := range strings.Split(info["stream-status"], "\n") {
+		// StreamID StreamStatus CircuitID Target
+		if fields := strings.Fields(line); len(fields) >= 3 {
+			streamsPerCircuit[fields[2]]++
+			status.Streams++
+		}
+	}
+	for _, line := range strings.Split(info["circuit-status"], "\n") {
+		if circuit, ok := parseCircuit(line); ok {
+			circuit.Streams = streamsPerCircuit[circuit.ID]
+			status.Circuits = append(status.Circuits, circuit)
+		}
+	}
+
+	var exit *TorCircuit
+	for i := range status.Circuits {
+		circuit := &status.Circuits[i]
+		if circuit.Status != "BUILT" || circuit.Purpose != "GENERAL" || len(circuit.Path) == 0 {
+			continue
+		}
+		if exit == nil || circuit.Streams >= exit.Streams {
+			exit = circuit
+		}
+	}
+	if exit != nil {
+		status.ExitIP, _ = c.relayAddress(exit.Path[len(exit.Path)-1])
+	}
+	return status, nil
+}
+
+// relayAddress returns the IP of a relay given as "$fingerprint~nickname" from its consensus entry
+func (c *torControlConn) relayAddress(relay string) (string, error) {
+	fingerprint := strings.TrimPrefix(relay, "$")
+	if i := strings.IndexAny(fingerprint, "~="); i >= 0 {
+		fingerprint = fingerprint[:i]
+	}
+	info, err := c.getInfo("ns/id/" + fingerprint)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(info["ns/id/"+fingerprint], "\n") {
+		// r nickname identity digest date time IP ORPort DirPort
+		if fields := strings.Fields(line); len(fields) >= 7 && fields[0] == "r" {
+			return fields[6], nil
+		}
+	}
+	return "", fmt.Errorf("relay %s is not in the consensus", fingerprint)
+}
+
+// GetTorBootstrap reads status/bootstrap-phase from the control port
+func GetTorBootstrap(ctx context.Context) (*TorBootstrap, error) {
+	conn, err := dialTorControl(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	info, err := conn.getInfo("status/bootstrap-phase")
+	if err != nil {
+		return nil, err
+	}
+	bootstrap := parseBootstrapPhase(info["status/bootstrap-phase"])
+	torBootstrapProgress.Set(float64(bootstrap.Progress))
+	return &bootstrap, nil
+}
+
+// GetTorControlStatus reads version, bootstrap, circuits and streams from the control port
+func GetTorControlStatus(ctx context.Context) (*TorControlStatus, error) {
+	conn, err := dialTorControl(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	status, err := conn.status()
+	if err != nil {
+		return nil, err
+	}
+	torBootstrapProgress.Set(float64(status.Bootstrap.Progress))
+	return status, nil
+}
+
+/*Bu fonksiyon, control port üzerinden SIGNAL NEWNYM gönderir; Tor bundan sonraki
+bağlantılar için yeni devreler kurar ve böylece çıkış IP'si değişir. Açık keep-alive
+bağlantıları eski devrelerde kalacağı için Tor HTTP client'ının boştaki bağlantıları da
+kapatılır. Tor NEWNYM'i en fazla 10 saniyede bir uygular; daha sık istekler kabul edilir
+ama ertelenir. reason, tor_newnym_total metriğine etiket olarak yazılır (manual, failures).
+*/
+func RequestNewnym(ctx context.Context, reason string) error {
+	conn, err := dialTorControl(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.command("SIGNAL NEWNYM"); err != nil {
+		return fmt.Errorf("NEWNYM failed: %v", err)
+	}
+
+	lastNewnym.Store(time.Now().UnixNano())
+	torFetchFailures.Store(0)
+	torNewnymTotal.Inc(reason)
+	closeIdleTorConnections()
+	torLogger.InfoContext(ctx, "requested new Tor circuits", "reason", reason)
+	return nil
+}
+
+/*Bu fonksiyon, Tor üzerinden yapılan her fetch denemesinin sonucunu kaydeder. err, HTTP
+yanıtı alınamadığında dolu gelir; site bir hata kodu döndürse bile yanıt alınmışsa devre
+çalışıyor demektir ve sayaç sıfırlanır. Yalnızca NEWNYM'in düzeltebileceği hatalar sayılır
+(bkz. isTorCircuitFailure): Tor instance'ına ulaşılamaması ve Tor'un devre kuramadığını
+bildiren SOCKS5 general failure yanıtı. Hedefin kapalı olduğunu bildiren yanıtlar ile
+fetch'in kendi zaman aşımı veya iptali sayılmaz ve sayacı değiştirmez; böylece tek bir
+erişilemeyen site tüm kaynakların devrelerini yeniletmez. Ardışık başarısızlık sayısı
+tor.newnym_after_failures değerine ulaştığında ve control port ayarlıysa NEWNYM istenir. NEWNYM, fetch'in kendi context'i iptal edilmiş olsa bile
+tamamlansın diye ayrı bir süreyle gönderilir.
+*/
+func recordTorFetch(ctx context.Context, err error) {
+	if err == nil {
+		torFetchFailures.Store(0)
+		return
+	}
+	if !isTorCircuitFailure(err) {
+		return
+	}
+	failures := torFetchFailures.Add(1)
+	limit := int64(torSettings.NewnymAfterFailures)
+	if limit <= 0 || failures < limit || !TorControlEnabled() {
+		return
+	}
+	// Only the fetch that reaches the limit first sends NEWNYM
+	if !torFetchFailures.CompareAndSwap(failures, 0) {
+		return
+	}
+
+	torLogger.WarnContext(ctx, "requesting new Tor circuits after failed fetches", "failures", failures)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), torControlTimeout)
+	defer cancel()
+	if err := RequestNewnym(ctx, "failures"); err != nil {
+		torLogger.WarnContext(ctx, "automatic NEWNYM failed", logging.Err(err))
+	}
+}
+
+// socksGeneralFailure is the reply Tor sends when it could not build or attach a circuit for the stream
+const socksGeneralFailure = "general SOCKS server failure"
+
+// isTorCircuitFailure reports whether a fetch failed on the Tor instance or its circuits rather than the target or the caller
+func isTorCircuitFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return isTorInstanceFailure(err) || socksReply(err) == socksGeneralFailure
+}
+
+// parseBootstrapPhase parses e.g. `NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY="Done"`
+func parseBootstrapPhase(value string) TorBootstrap {
+	_, keywords := parseControlArgs(value)
+	progress, _ := strconv.Atoi(keywords["PROGRESS"])
+	return TorBootstrap{
+		Progress: progress,
+		Tag:      keywords["TAG"],
+		Summary:  keywords["SUMMARY"],
+		Warning:  keywords["WARNING"],
+	}
+}
+
+// parseCircuit parses one circuit-status line: ID status [path] keyword arguments
+func parseCircuit(line string) (TorCircuit, bool) {
+	id, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	circuitStatus, rest, _ := strings.Cut(rest, " ")
+	if id == "" || circuitStatus == "" {
+		return TorCircuit{}, false
+	}
+	circuit := TorCircuit{ID: id, Status: circuitStatus, Path: []string{}}
+	// The path is positional and its relay names may contain '=', so it is split off first
+	if strings.HasPrefix(rest, "$") {
+		var path string
+		path, rest, _ = strings.Cut(rest, " ")
+		circuit.Path = strings.Split(path, ",")
+	}
+	_, keywords := parseControlArgs(rest)
+	circuit.Purpose = keywords["PURPOSE"]
+	circuit.CreatedAt = keywords["TIME_CREATED"]
+	circuit.Isolation = keywords["SOCKS_USERNAME"]
+	return circuit, true
+}
+
+// parseControlArgs splits a reply into positional arguments and KEY=value / KEY="quoted value" pairs
+func parseControlArgs(s string) (positional []string, keywords map[string]string) {
+	keywords = map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " ") {
+		space := strings.IndexByte(s, ' ')
+		if space < 0 {
+			space = len(s)
+		}
+		eq := strings.IndexByte(s[:space], '=')
+		if eq < 0 {
+			positional = append(positional, s[:space])
+			s = s[space:]
+			continue
+		}
+		key, rest := s[:eq], s[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest = unquoteControl(rest)
+		} else {
+			end := strings.IndexByte(rest, ' ')
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		keywords[key] = value
+		s = rest
+	}
+	return positional, keywords
+}
+
+// unquoteControl reads a QuotedString from the start of s and returns it with the remainder
+func unquoteControl(s string) (value, rest string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// quoteControl encodes s as a control protocol QuotedString
+func quoteControl(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
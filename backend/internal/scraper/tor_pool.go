@@ -0,0 +1,357 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
+)
+
+// torInstanceCheckTimeout bounds the SOCKS5 check of one instance
+const torInstanceCheckTimeout = 5 * time.Second
+
+/*Bu yapı (torInstance), havuzdaki tek bir Tor SOCKS5 dinleyicisini ve sağlık durumunu
+tutar. healthy, periyodik SOCKS5 kontrolünün son sonucudur; quarantinedUntil ise bu
+instance üzerinde art arda tor.quarantine_after fetch başarısız olduğunda ayarlanır ve o
+zamana kadar instance seçilmez. inFlight, şu an bu instance üzerinden süren fetch
+sayısıdır ve least_loaded seçiminde kullanılır.
+*/
+type torInstance struct {
+	address  string
+	inFlight atomic.Int64
+
+	mu                  sync.Mutex
+	healthy             bool
+	lastCheck           time.Time
+	lastError           string
+	consecutiveFailures int
+	quarantinedUntil    time.Time
+	requests            int64
+	failures            int64
+}
+
+// TorInstanceHealth is the state of one pooled Tor instance as shown by the status API
+type TorInstanceHealth struct {
+	Address             string     `json:"address"`
+	Status              string     `json:"status"`
+	InFlight            int        `json:"in_flight"`
+	Requests            int64      `json:"requests"`
+	Failures            int64      `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	QuarantinedUntil    *time.Time `json:"quarantined_until,omitempty"`
+	LastCheck           *time.Time `json:"last_check,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+type torPool struct {
+	instances []*torInstance
+	balance   string
+	next      atomic.Uint64
+}
+
+// activeTorPool is replaced by Configure; instances start healthy so fetches work before the first check
+var activeTorPool atomic.Pointer[torPool]
+
+func init() {
+	activeTorPool.Store(newTorPool(torSettings))
+}
+
+func newTorPool(cfg config.TorConfig) *torPool {
+	pool := &torPool{balance: cfg.Balance}
+	for _, address := range cfg.SOCKSProxies() {
+		pool.instances = append(pool.instances, &torInstance{address: address, healthy: true})
+	}
+	return pool
+}
+
+// available reports whether the instance passed its last check and is not quarantined
+func (inst *torInstance) available(now time.Time) bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.healthy && !now.Before(inst.quarantinedUntil)
+}
+
+/*Bu fonksiyon, bir fetch için havuzdan bir Tor instance'ı seçer. Yalnızca son kontrolü
+başarılı olan ve karantinada olmayan instance'lar aday olur; tor.balance round_robin ise
+adaylar sırayla, least_loaded ise en az fetch'i süren aday (eşitlikte sıradaki) seçilir.
+Hiç aday yoksa önce kontrolü başarılı karantinadaki instance'lar, onlar da yoksa tüm
+instance'lar arasından sırayla seçilir; düzelmiş olabilecek bir instance'ı denemek, her
+fetch'i hemen başarısız saymaktan iyidir.
+*/
+func (p *torPool) pick() *torInstance {
+	now := time.Now()
+	candidates := make([]*torInstance, 0, len(p.instances))
+	for _, inst := range p.instances {
+		if inst.available(now) {
+			candidates = append(candidates, inst)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, inst := range p.instances {
+			inst.mu.Lock()
+			if inst.healthy {
+				candidates = append(candidates, inst)
+			}
+			inst.mu.Unlock()
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.instances
+	}
+
+	start := int((p.next.Add(1) - 1) % uint64(len(candidates)))
+	best := candidates[start]
+	if p.balance != "least_loaded" {
+		return best
+	}
+	for i := 1; i < len(candidates); i++ {
+		inst := candidates[(start+i)%len(candidates)]
+		if inst.inFlight.Load() < best.inFlight.Load() {
+			best = inst
+		}
+	}
+	return best
+}
+
+/*Bu fonksiyon, bir fetch denemesinin sonucunu instance'ın sağlık durumuna yazar. Yalnızca
+instance'ın kendisinden kaynaklanan hatalar (bağlanılamaması veya SOCKS5 selamlaşmasının
+tamamlanamaması, bkz. torInstanceError) başarısızlık sayılır. Tor'un hedef için döndürdüğü
+SOCKS5 yanıtları (ör. kapalı bir .onion için host unreachable veya TTL expired) instance'ın
+çalıştığını gösterir ve sayacı sıfırlar; fetch'in kendi zaman aşımı ise hiçbir şey
+değiştirmez. Böylece tek bir erişilemeyen kaynak sağlıklı instance'ları karantinaya almaz.
+*/
+func (inst *torInstance) report(ctx context.Context, err error) {
+	inst.mu.Lock()
+	inst.requests++
+	if !isTorInstanceFailure(err) {
+		if err == nil || socksReply(err) != "" {
+			inst.consecutiveFailures = 0
+		}
+		inst.mu.Unlock()
+		return
+	}
+	inst.failures++
+	inst.consecutiveFailures++
+	now := time.Now()
+	quarantine := inst.consecutiveFailures >= torSettings.QuarantineAfter && !now.Before(inst.quarantinedUntil)
+	if quarantine {
+		inst.consecutiveFailures = 0
+		inst.quarantinedUntil = now.Add(torSettings.Quarantine.Duration)
+	}
+	inst.mu.Unlock()
+
+	if quarantine {
+		torInstanceUp.Set(0, inst.address)
+		torInstanceQuarantinesTotal.Inc(inst.address)
+		torLogger.WarnContext(ctx, "quarantining Tor instance after failed fetches", "instance", inst.address,
+			"failures", torSettings.QuarantineAfter, "quarantine", torSettings.Quarantine.String())
+	}
+}
+
+// torInstanceError marks a fetch error caused by the Tor instance rather than the target or the caller's deadline
+type torInstanceError struct {
+	err error
+}
+
+func (e *torInstanceError) Error() string { return e.err.Error() }
+func (e *torInstanceError) Unwrap() error { return e.err }
+
+func isTorInstanceFailure(err error) bool {
+	var instanceErr *torInstanceError
+	return errors.As(err, &instanceErr)
+}
+
+// socksReplyPrefix starts the error x/net's SOCKS5 client returns for a non-success reply code
+const socksReplyPrefix = "unknown error "
+
+// socksReply returns the reply Tor sent about the target, e.g. "host unreachable", or "" for other errors
+func socksReply(err error) string {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Err != nil && strings.HasPrefix(opErr.Err.Error(), socksReplyPrefix) {
+		return strings.TrimPrefix(opErr.Err.Error(), socksReplyPrefix)
+	}
+	return ""
+}
+
+// torForwardDialer dials Tor instances for the SOCKS5 client and marks failures to reach them
+type torForwardDialer struct{}
+
+func (d torForwardDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (torForwardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil && ctx.Err() == nil {
+		err = &torInstanceError{err}
+	}
+	return conn, err
+}
+
+// classifyTorDialError marks a failed SOCKS5 handshake as an instance error, leaving target replies and deadlines alone
+func classifyTorDialError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil || isTorInstanceFailure(err) || socksReply(err) != "" {
+		return err
+	}
+	return &torInstanceError{err}
+}
+
+// check runs the SOCKS5 check and logs when the instance goes down or comes back
+func (inst *torInstance) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, torInstanceCheckTimeout)
+	defer cancel()
+	err := checkSOCKS(ctx, inst.address)
+
+	inst.mu.Lock()
+	wasHealthy := inst.healthy
+	inst.healthy = err == nil
+	inst.lastCheck = time.Now()
+	inst.lastError = ""
+	if err != nil {
+		inst.lastError = err.Error()
+	}
+	inst.mu.Unlock()
+
+	up := 0.0
+	if inst.available(time.Now()) {
+		up = 1
+	}
+	torInstanceUp.Set(up, inst.address)
+	switch {
+	case wasHealthy && err != nil:
+		torLogger.Warn("Tor instance failed its health check", "instance", inst.address, logging.Err(err))
+	case !wasHealthy && err == nil:
+		torLogger.Info("Tor instance recovered", "instance", inst.address)
+	}
+	return err
+}
+
+// checkAll checks every instance in parallel and returns how many are up with the first error
+func (p *torPool) checkAll(ctx context.Context) (int, error) {
+	errs := make([]error, len(p.instances))
+	var wg sync.WaitGroup
+	for i, inst := range p.instances {
+		wg.Add(1)
+		go func(i int, inst *torInstance) {
+			defer wg.Done()
+			errs[i] = inst.check(ctx)
+		}(i, inst)
+	}
+	wg.Wait()
+
+	up := 0
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			up++
+		} else if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", p.instances[i].address, err)
+		}
+	}
+	return up, firstErr
+}
+
+func (p *torPool) health() []TorInstanceHealth {
+	now := time.Now()
+	out := make([]TorInstanceHealth, 0, len(p.instances))
+	for _, inst := range p.instances {
+		inst.mu.Lock()
+		health := TorInstanceHealth{
+			Address:             inst.address,
+			Status:              "up",
+			InFlight:            int(inst.inFlight.Load()),
+			Requests:            inst.requests,
+			Failures:            inst.failures,
+			ConsecutiveFailures: inst.consecutiveFailures,
+			LastError:           inst.lastError,
+		}
+		if !inst.healthy {
+			health.Status = "down"
+		}
+		if now.Before(inst.quarantinedUntil) {
+			health.Status = "quarantined"
+			until := inst.quarantinedUntil.UTC()
+			health.QuarantinedUntil = &until
+		}
+		if !inst.lastCheck.IsZero() {
+			checked := inst.lastCheck.UTC()
+			health.LastCheck = &checked
+		}
+		inst.mu.Unlock()
+		out = append(out, health)
+	}
+	return out
+}
+
+// TorInstances returns the health of every pooled Tor instance
+func TorInstances() []TorInstanceHealth {
+	return activeTorPool.Load().health()
+}
+
+/*Bu fonksiyon, havuzdaki tüm Tor instance'larının SOCKS5 portunu tor.health_interval
+aralıklarla kontrol eder ve sonucu instance'ların sağlık durumuna yazar. Sunucu açılışında
+ScraperService.Start gibi ayrı bir goroutine'de çalıştırılır ve süreç boyunca döner.
+Karantina bu kontrolle kalkmaz; karantinadaki instance süresi dolunca, kontrolü de
+başarılıysa yeniden seçilir.
+*/
+func StartTorHealthChecks() {
+	for {
+		pool := activeTorPool.Load()
+		pool.checkAll(context.Background())
+		time.Sleep(torSettings.HealthInterval.Duration)
+	}
+}
+
+/*Bu fonksiyon, Tor'un SOCKS5 portlarının erişilebilir olduğunu dış bir servise istek
+atmadan doğrular: havuzdaki her instance'a paralel bağlanıp kimlik doğrulamasız SOCKS5
+selamlaşmasını gönderir ve sonuçları instance'ların sağlık durumuna da yazar. En az bir
+instance yanıt veriyorsa başarılıdır; birden fazla instance varsa kaçının çalıştığı açıklama
+olarak döner. Bu kontrol Tor'un devreleri kurup kuramadığını göstermez, yalnızca süreçlerin
+çalıştığını ve bağlantı kabul ettiğini gösterir; readiness kontrolü için yeterli ve ucuzdur.
+*/
+func CheckTorSOCKS(ctx context.Context) (string, error) {
+	pool := activeTorPool.Load()
+	up, err := pool.checkAll(ctx)
+	if len(pool.instances) == 1 {
+		return "", err
+	}
+	detail := fmt.Sprintf("%d of %d instances up", up, len(pool.instances))
+	if up == 0 {
+		return "", fmt.Errorf("%s, %v", detail, err)
+	}
+	return detail, nil
+}
+
+// checkSOCKS sends an unauthenticated SOCKS5 greeting (05 01 00) and expects 05 00 back
+func checkSOCKS(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("SOCKS5 port unreachable: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte{0x05, 0x01, 0x00}); err != nil {
+		return fmt.Errorf("SOCKS5 greeting failed: %v", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("SOCKS5 greeting failed: %v", err)
+	}
+	if reply[0] != 0x05 || reply[1] != 0x00 {
+		return fmt.Errorf("unexpected SOCKS5 greeting reply %x", reply)
+	}
+	return nil
+}
@@ -3,12 +3,12 @@ package scraper
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
-	"os"
 	"strings"
 	"time"
+
+	"interactive-scraper/internal/logging"
 )
 
 // TorReadinessStatus represents the readiness state of Tor
@@ -19,12 +19,23 @@ type TorReadinessStatus struct {
 }
 
 // CheckTorReadiness checks if Tor is fully ready (SOCKS5 port + bootstrap complete)
-// Returns true only when Tor is ready to handle requests
+// Returns true only when Tor is ready to handle requests; the result is exported as tor_ready.
+// With a control port the bootstrap phase is read from Tor instead of probed with a test fetch
 func CheckTorReadiness() (*TorReadinessStatus, error) {
-	torProxy := os.Getenv("TOR_PROXY")
-	if torProxy == "" {
-		torProxy = "tor:9050"
+	status, err := checkTorReadiness()
+	if err == nil && status.IsReady {
+		torReady.Set(1)
+	} else {
+		torReady.Set(0)
+	}
+	return status, err
+}
+
+func checkTorReadiness() (*TorReadinessStatus, error) {
+	if TorControlEnabled() {
+		return checkTorReadinessControl()
 	}
+	torProxy := activeTorPool.Load().pick().address
 
 	// Split host:port
 	host, port, err := net.SplitHostPort(torProxy)
@@ -47,7 +58,7 @@ func CheckTorReadiness() (*TorReadinessStatus, error) {
 
 	// Step 2: Verify Tor can actually route traffic (bootstrap check)
 	// We do this by attempting a simple connection through Tor
-	client, err := GetTorHTTPClient()
+	client, err := getTorHTTPClient(torProxy, "")
 	if err != nil {
 		return &TorReadinessStatus{
 			IsReady:      false,
@@ -109,27 +120,57 @@ func CheckTorReadiness() (*TorReadinessStatus, error) {
 	}, nil
 }
 
+// checkTorReadinessControl reports Tor as ready once status/bootstrap-phase reaches 100%
+func checkTorReadinessControl() (*TorReadinessStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), torControlTimeout)
+	defer cancel()
+
+	bootstrap, err := GetTorBootstrap(ctx)
+	if err != nil {
+		return &TorReadinessStatus{
+			IsReady:      false,
+			BootstrapPct: 0,
+			Message:      fmt.Sprintf("Tor control port check failed: %v", err),
+		}, nil
+	}
+
+	status := &TorReadinessStatus{
+		IsReady:      bootstrap.Progress == 100,
+		BootstrapPct: bootstrap.Progress,
+		Message:      "Tor is bootstrapped and ready",
+	}
+	if !status.IsReady {
+		status.Message = fmt.Sprintf("Tor bootstrap at %d%%: %s", bootstrap.Progress, bootstrap.Summary)
+	}
+	if bootstrap.Warning != "" {
+		status.Message += fmt.Sprintf(" (warning: %s)", bootstrap.Warning)
+	}
+	return status, nil
+}
+
 // WaitForTorReady waits for Tor to become ready with exponential backoff
 // Returns error only if max retries exceeded
-func WaitForTorReady(maxRetries int, initialDelay time.Duration) error {
-	log.Printf("[TOR] Waiting for Tor to become ready...")
+func WaitForTorReady(ctx context.Context, maxRetries int, initialDelay time.Duration) error {
+	torLogger.InfoContext(ctx, "waiting for Tor to become ready")
 	
 	delay := initialDelay
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		status, err := CheckTorReadiness()
 		if err != nil {
-			log.Printf("[TOR] Error checking readiness (attempt %d/%d): %v", attempt, maxRetries, err)
+			torLogger.WarnContext(ctx, "Tor readiness check failed", "attempt", attempt, "max_attempts", maxRetries, logging.Err(err))
 		} else if status.IsReady {
-			log.Printf("[TOR] ✓ Tor is ready! Bootstrap: %d%%, Message: %s", status.BootstrapPct, status.Message)
+			torLogger.InfoContext(ctx, "Tor is ready", "bootstrap_pct", status.BootstrapPct, "tor_message", status.Message)
 			return nil
 		} else {
-			log.Printf("[TOR] Tor not ready yet (attempt %d/%d): Bootstrap: %d%%, Message: %s", 
-				attempt, maxRetries, status.BootstrapPct, status.Message)
+			torLogger.InfoContext(ctx, "Tor not ready yet", "attempt", attempt, "max_attempts", maxRetries,
+				"bootstrap_pct", status.BootstrapPct, "tor_message", status.Message)
 		}
 
 		if attempt < maxRetries {
-			log.Printf("[TOR] Retrying in %v...", delay)
-			time.Sleep(delay)
+			torLogger.DebugContext(ctx, "retrying Tor readiness check", "retry_in", delay.String())
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
+			}
 			// Exponential backoff: 3s, 5s, 8s, 12s, etc.
 			delay = time.Duration(float64(delay) * 1.5)
 			if delay > 15*time.Second {
@@ -141,3 +182,15 @@ func WaitForTorReady(maxRetries int, initialDelay time.Duration) error {
 	return fmt.Errorf("Tor did not become ready after %d attempts", maxRetries)
 }
 
+
+// sleepContext waits for d, returning early with the context's error if it is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
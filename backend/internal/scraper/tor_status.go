@@ -7,16 +7,19 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
 	"time"
+
+	"interactive-scraper/internal/logging"
 )
 
 type TorStatus struct {
 	IsConnected bool   `json:"is_connected"`
 	ExitIP      string `json:"exit_ip"`
 	Message     string `json:"message"`
+	// Instances is the live health of every pooled Tor instance; it is not cached
+	Instances []TorInstanceHealth `json:"instances"`
 }
 
 var (
@@ -25,9 +28,30 @@ var (
 	torStatusCacheTime  time.Time
 	torStatusCacheTTLDisconnected = 1 * time.Second
 	torStatusCacheTTLConnected    = 15 * time.Second
+	// lastExitIP is the last exit IP seen, guarded by torStatusCacheMutex
+	lastExitIP string
 )
 
-func CheckTorStatus() (*TorStatus, error) {
+/*Bu fonksiyon, arayüzdeki Tor göstergesi için bağlantı durumunu ve çıkış IP'sini döndürür;
+sonuç bağlıyken 15, değilken 1 saniye önbellekte tutulur. Control port ayarlıysa durum
+Tor'dan okunur: bootstrap tamamlanmış ve kurulmuş bir genel amaçlı devre varsa bağlı
+sayılır, çıkış IP'si de o devrenin çıkış rölesinden alınır. Control port yoksa çıkış IP'si
+Tor üzerinden IP-echo servislerine paralel istekler atılarak bulunur. Yanıta havuzdaki her
+Tor instance'ının anlık sağlık durumu da eklenir; bu kısım önbelleğe alınmaz. ctx,
+loglara isteğin kimliğini taşır; sonuç paylaşıldığı için isteğin iptali kontrolü durdurmaz.
+*/
+func CheckTorStatus(ctx context.Context) (*TorStatus, error) {
+	status, err := checkTorStatus(ctx)
+	if status == nil {
+		return nil, err
+	}
+	// status may be the cached value, so the instances go on a copy
+	withInstances := *status
+	withInstances.Instances = TorInstances()
+	return &withInstances, err
+}
+
+func checkTorStatus(requestCtx context.Context) (*TorStatus, error) {
 	torStatusCacheMutex.RLock()
 	if torStatusCache != nil {
 		ttl := torStatusCacheTTLDisconnected
@@ -42,10 +66,11 @@ func CheckTorStatus() (*TorStatus, error) {
 	}
 	torStatusCacheMutex.RUnlock()
 
-	torProxy := os.Getenv("TOR_PROXY")
-	if torProxy == "" {
-		torProxy = "tor:9050"
+	if TorControlEnabled() {
+		return storeTorStatus(torStatusFromControl()), nil
 	}
+
+	torProxy := activeTorPool.Load().pick().address
 	host, port := torProxy, "9050"
 	if h, p, err := net.SplitHostPort(torProxy); err == nil {
 		host, port = h, p
@@ -65,7 +90,7 @@ func CheckTorStatus() (*TorStatus, error) {
 	}
 	_ = conn.Close()
 
-	client, err := GetTorHTTPClient()
+	client, err := getTorHTTPClient(torProxy, "")
 	if err != nil {
 		return &TorStatus{
 			IsConnected: false,
@@ -78,7 +103,7 @@ func CheckTorStatus() (*TorStatus, error) {
 	var mu sync.Mutex
 	ipCh := make(chan string, 1)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestCtx), 10*time.Second)
 	defer cancel()
 	validateIP := func(ip string) bool {
 		ip = strings.TrimSpace(ip)
@@ -212,23 +237,22 @@ func CheckTorStatus() (*TorStatus, error) {
 		exitIP = strings.ReplaceAll(exitIP, "'", "")
 		exitIP = strings.TrimSpace(exitIP)
 		if exitIP == "" {
-			fmt.Printf("[TOR] Warning: Received empty IP from service\n")
+			torLogger.WarnContext(requestCtx, "IP check service returned an empty exit IP")
 		} else {
-			fmt.Printf("[TOR] Successfully retrieved exit IP: %s\n", exitIP)
+			torLogger.InfoContext(requestCtx, "retrieved Tor exit IP", "exit_ip", exitIP)
 		}
 	case <-ctx.Done():
 		exitIP = ""
-		fmt.Printf("[TOR] Timeout: No IP received from any service after 10 seconds. Last error: %v\n", lastError)
+		mu.Lock()
+		err := lastError
+		mu.Unlock()
+		torLogger.WarnContext(requestCtx, "no IP check service answered within 10s", logging.Err(err))
 	}
 	
 	isConnected := exitIP != ""
 	
 	message := "Tor connection active"
 	if !isConnected {
-		torProxy := os.Getenv("TOR_PROXY")
-		if torProxy == "" {
-			torProxy = "tor:9050"
-		}
 		if lastError != nil {
 			message = fmt.Sprintf("Tor connected but IP check failed: %v. Tor proxy: %s", lastError, torProxy)
 		} else {
@@ -236,17 +260,44 @@ func CheckTorStatus() (*TorStatus, error) {
 		}
 	}
 
-	status := &TorStatus{
+	return storeTorStatus(&TorStatus{
 		IsConnected: isConnected,
 		ExitIP:      exitIP,
 		Message:     message,
-	}
+	}), nil
+}
 
+// storeTorStatus caches status and counts exit IP changes
+func storeTorStatus(status *TorStatus) *TorStatus {
 	torStatusCacheMutex.Lock()
+	defer torStatusCacheMutex.Unlock()
+	if status.ExitIP != "" {
+		if lastExitIP != "" && lastExitIP != status.ExitIP {
+			torExitIPChangesTotal.Inc()
+		}
+		lastExitIP = status.ExitIP
+	}
 	torStatusCache = status
 	torStatusCacheTime = time.Now()
-	torStatusCacheMutex.Unlock()
+	return status
+}
 
-	return status, nil
+func torStatusFromControl() *TorStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), torControlTimeout)
+	defer cancel()
+
+	control, err := GetTorControlStatus(ctx)
+	if err != nil {
+		return &TorStatus{Message: fmt.Sprintf("Tor control port check failed: %v", err)}
+	}
+	if control.Bootstrap.Progress < 100 {
+		return &TorStatus{Message: fmt.Sprintf("Tor bootstrap at %d%%: %s", control.Bootstrap.Progress, control.Bootstrap.Summary)}
+	}
+	for _, circuit := range control.Circuits {
+		if circuit.Status == "BUILT" && circuit.Purpose == "GENERAL" {
+			return &TorStatus{IsConnected: true, ExitIP: control.ExitIP, Message: "Tor connection active"}
+		}
+	}
+	return &TorStatus{Message: "Tor is bootstrapped but has no built circuits yet"}
 }
 
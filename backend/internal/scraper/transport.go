@@ -0,0 +1,163 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/service"
+)
+
+// transportSettings is replaced by ConfigureTransports at startup, before any fetch
+var transportSettings = config.Default().Transport
+
+// transportClientKey identifies the cached client of one non-Tor transport and proxy
+type transportClientKey struct {
+	transport string
+	proxy     string
+}
+
+var (
+	// transportClientCache holds the I2P, proxy and direct clients; Tor clients live in torClientCache
+	transportClientCache      = map[transportClientKey]*http.Client{}
+	transportClientCacheMutex sync.Mutex
+)
+
+// ConfigureTransports sets the I2P proxy and the timeouts of the clients that do not go through Tor
+func ConfigureTransports(cfg config.TransportConfig) {
+	transportClientCacheMutex.Lock()
+	defer transportClientCacheMutex.Unlock()
+	transportSettings = cfg
+	for _, client := range transportClientCache {
+		client.CloseIdleConnections()
+	}
+	transportClientCache = map[transportClientKey]*http.Client{}
+}
+
+// sourceRoute is how the source being fetched is reached, as stored on the source
+type sourceRoute struct {
+	transport string
+	proxyURL  string
+}
+
+type sourceRouteKey struct{}
+
+/*Bu fonksiyon, ctx ile yapılan fetch'lerin kaynağın taşıması (transport) üzerinden
+yapılmasını sağlar: tor için Tor havuzu, i2p için transport.i2p_proxy ile verilen I2P
+router'ının HTTP proxy'si, proxy için kaynağın kendi proxy_url'i, direct için doğrudan
+bağlantı kullanılır. Taşıma verilmemiş bir ctx Tor kullanır. Adres .onion veya .i2p ise
+fetch sırasında service.SourceTransport kuralıyla yine Tor'a veya I2P'ye zorlanır; böylece
+veritabanında elle değiştirilmiş bir kaynak da gizli servis adresini açık internete
+sızdıramaz.
+*/
+func WithSourceTransport(ctx context.Context, transport, proxyURL string) context.Context {
+	return context.WithValue(ctx, sourceRouteKey{}, sourceRoute{transport: transport, proxyURL: proxyURL})
+}
+
+// fetchRoute resolves the transport of urlString for the source set with WithSourceTransport
+func fetchRoute(ctx context.Context, urlString string) sourceRoute {
+	route, _ := ctx.Value(sourceRouteKey{}).(sourceRoute)
+	route.transport = service.SourceTransport(urlString, route.transport)
+	if route.transport != service.TransportProxy {
+		route.proxyURL = ""
+	}
+	return route
+}
+
+/*Bu fonksiyon, Tor dışındaki bir taşıma için http.Client döndürür. I2P ve proxy
+taşımaları istekleri net/http'nin proxy desteğiyle (http, https, socks5, socks5h)
+gönderir, direct ise ortamdaki HTTP_PROXY ayarlarını da yok sayarak doğrudan bağlanır.
+Client'lar taşıma ve proxy adresine göre önbellekte tutulur; aynı proxy'yi kullanan
+kaynaklar bağlantıları paylaşır. İstek zaman aşımı da döner: I2P için
+transport.i2p_timeout, diğerleri için transport.fetch_timeout.
+*/
+func transportClient(route sourceRoute) (*http.Client, time.Duration, error) {
+	transportClientCacheMutex.Lock()
+	defer transportClientCacheMutex.Unlock()
+
+	timeout := transportSettings.FetchTimeout.Duration
+	proxyAddress := route.proxyURL
+	switch route.transport {
+	case service.TransportI2P:
+		if transportSettings.I2PProxy == "" {
+			return nil, 0, fmt.Errorf("I2P is not configured, set transport.i2p_proxy (I2P_PROXY)")
+		}
+		timeout = transportSettings.I2PTimeout.Duration
+		proxyAddress = transportSettings.I2PProxy
+	case service.TransportProxy:
+		if proxyAddress == "" {
+			return nil, 0, fmt.Errorf("source uses transport proxy but has no proxy_url")
+		}
+	case service.TransportDirect:
+		proxyAddress = ""
+	default:
+		return nil, 0, fmt.Errorf("unknown transport %q", route.transport)
+	}
+
+	key := transportClientKey{route.transport, proxyAddress}
+	if client := transportClientCache[key]; client != nil {
+		return client, timeout, nil
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:          10,
+		MaxIdleConnsPerHost:   5,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: 5 * time.Second,
+	}
+	if proxyAddress != "" {
+		proxyURL, err := url.Parse(proxyAddress)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	client := &http.Client{Transport: transport, Timeout: timeout, CheckRedirect: checkRedirectFor(route.transport)}
+	transportClientCache[key] = client
+	logger.Debug("created fetch client", "transport", route.transport, "proxy", redactProxyURL(proxyAddress))
+	return client, timeout, nil
+}
+
+// maxRedirects matches net/http's default redirect limit
+const maxRedirects = 10
+
+// checkRedirectFor refuses redirects to hosts that need another transport, e.g. a clearnet page redirecting to a .onion
+func checkRedirectFor(transport string) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errors.New("stopped after 10 redirects")
+		}
+		if target := service.SourceTransport(req.URL.String(), transport); target != transport {
+			return fmt.Errorf("refusing redirect to %s: it is only reachable over %s, not %s", req.URL.Host, target, transport)
+		}
+		return nil
+	}
+}
+
+// redactProxyURL hides the password of a proxy URL before it is logged or traced
+func redactProxyURL(proxyAddress string) string {
+	u, err := url.Parse(proxyAddress)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// maxFetchTimeout is the longest a single fetch attempt can take on any transport
+func maxFetchTimeout() time.Duration {
+	timeout := torSettings.FetchTimeout.Duration
+	for _, t := range []time.Duration{transportSettings.I2PTimeout.Duration, transportSettings.FetchTimeout.Duration} {
+		if t > timeout {
+			timeout = t
+		}
+	}
+	return timeout
+}
@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"interactive-scraper/internal/service"
+)
+
+func TestCheckRedirectFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport string
+		target    string
+		wantErr   string
+	}{
+		{"clearnet to clearnet", service.TransportDirect, "https://example.org/next", ""},
+		{"clearnet to onion over direct", service.TransportDirect, "http://abcdefghijklmnop.onion/", "only reachable over tor"},
+		{"clearnet to i2p over proxy", service.TransportProxy, "http://forum.i2p/", "only reachable over i2p"},
+		{"onion to onion over tor", service.TransportTor, "http://other.onion/", ""},
+		{"onion to clearnet over tor", service.TransportTor, "https://example.org/", ""},
+		{"i2p to onion", service.TransportI2P, "http://abcdefghijklmnop.onion/", "only reachable over tor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checkRedirectFor(tt.transport)(req, []*http.Request{req})
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.org/", nil)
+	if err := checkRedirectFor(service.TransportDirect)(req, make([]*http.Request, maxRedirects)); err == nil {
+		t.Error("redirect chain longer than maxRedirects was followed")
+	}
+}
+
+func TestFetchRoute(t *testing.T) {
+	proxied := WithSourceTransport(context.Background(), service.TransportProxy, "http://proxy:3128")
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		url       string
+		transport string
+		proxyURL  string
+	}{
+		{"no source defaults to tor", context.Background(), "https://example.org", service.TransportTor, ""},
+		{"proxy source", proxied, "https://example.org", service.TransportProxy, "http://proxy:3128"},
+		{"onion drops the proxy", proxied, "http://abcdefghijklmnop.onion", service.TransportTor, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := fetchRoute(tt.ctx, tt.url)
+			if route.transport != tt.transport || route.proxyURL != tt.proxyURL {
+				t.Errorf("fetchRoute = %+v, want transport %q proxy %q", route, tt.transport, tt.proxyURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyExpired  = errors.New("API key has expired")
+)
+
+// apiKeyPrefix marks the keys so they are easy to spot in logs and secret scanners
+const apiKeyPrefix = "isk_"
+
+const (
+	defaultAPIKeyDays = 90
+	maxAPIKeyDays     = 365
+	// apiKeyTouchInterval limits last-used writes to one per key per interval
+	apiKeyTouchInterval = time.Minute
+)
+
+type APIKey struct {
+	ID         int          `json:"id"`
+	UserID     int          `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []Permission `json:"scopes"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	LastUsedIP string       `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty"`
+}
+
+type CreateAPIKeyInput struct {
+	Name          string       `json:"name"`
+	Scopes        []Permission `json:"scopes"`
+	ExpiresInDays int          `json:"expires_in_days"`
+}
+
+// CreatedAPIKey carries the plain key, which is only available right after creation
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"api_key"`
+}
+
+// APIKeyPrincipal is the caller behind a valid API key
+type APIKeyPrincipal struct {
+	KeyID    int
+	UserID   int
+	Username string
+	Role     string
+	Scopes   []Permission
+}
+
+type APIKeyService struct {
+	db *sql.DB
+}
+
+func NewAPIKeyService(db *sql.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, scopes, created_at, expires_at, last_used_at, COALESCE(last_used_ip, ''), revoked_at`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (APIKey, error) {
+	var key APIKey
+	var scopes []string
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, pq.Array(&scopes), &key.CreatedAt,
+		&key.ExpiresAt, &lastUsed, &key.LastUsedIP, &revoked); err != nil {
+		return key, err
+	}
+	key.Scopes = make([]Permission, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = Permission(scope)
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		key.RevokedAt = &revoked.Time
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) ListUserKeys(userID int) ([]APIKey, error) {
+	rows, err := s.db.Query(`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+/*Bu fonksiyon, kullanıcı adına yeni bir API anahtarı üretir. Kapsamlar (scopes) yetki
+adlarıdır ve kullanıcının rolünün sahip olmadığı bir yetki istenemez; read yetkisi her
+anahtara eklenir çünkü tüm API rotaları bunu gerektirir. Anahtar "isk_<önek>_<gizli>"
+biçimindedir; veritabanında yalnızca SHA-256 özeti ve tanımlama için önek tutulur, düz
+anahtar yalnızca bu fonksiyonun dönüşünde bir kez görülebilir.
+*/
+func (s *APIKeyService) CreateKey(userID int, input CreateAPIKeyInput) (*CreatedAPIKey, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" || len(input.Name) > 100 {
+		return nil, fmt.Errorf("%w: name is required (max 100 characters)", ErrInvalidAPIKey)
+	}
+	if input.ExpiresInDays == 0 {
+		input.ExpiresInDays = defaultAPIKeyDays
+	}
+	if input.ExpiresInDays < 1 || input.ExpiresInDays > maxAPIKeyDays {
+		return nil, fmt.Errorf("%w: expires_in_days must be between 1 and %d", ErrInvalidAPIKey, maxAPIKeyDays)
+	}
+
+	var role string
+	err := s.db.QueryRow(`SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string{string(PermRead)}
+	for _, scope := range input.Scopes {
+		if !HasPermission(role, scope) {
+			return nil, fmt.Errorf("%w: scope %q is not granted to role %s", ErrInvalidAPIKey, scope, role)
+		}
+		if scope != PermRead && !containsString(scopes, string(scope)) {
+			scopes = append(scopes, string(scope))
+		}
+	}
+
+	prefix, err := randomToken(6)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	plain := apiKeyPrefix + prefix + "_" + secret
+
+	row := s.db.QueryRow(`
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(days => $6))
+		RETURNING `+apiKeyColumns,
+		userID, input.Name, prefix, hashToken(plain), pq.Array(scopes), input.ExpiresInDays)
+	key, err := scanAPIKey(row)
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedAPIKey{APIKey: key, Key: plain}, nil
+}
+
+// RevokeKey revokes one of the user's keys; revoked keys stay listed for auditing
+func (s *APIKeyService) RevokeKey(userID, keyID int) error {
+	result, err := s.db.Exec(`
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1 AND user_id = $2
+	`, keyID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// revokeUserAPIKeys revokes every active key of the user, e.g. when an admin signs them out everywhere
+func revokeUserAPIKeys(db execer, userID int) (int, error) {
+	result, err := db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+/*Bu fonksiyon, X-API-Key başlığıyla gelen anahtarı doğrular. Anahtarın özeti aranır;
+iptal edilmiş veya süresi dolmuş anahtarlar reddedilir. Rol her istekte kullanıcı
+tablosundan okunur ve anahtarın kapsamları rolün güncel yetkileriyle kesiştirilir; böylece
+rolü düşürülen kullanıcının anahtarları da hemen kısıtlanır. Son kullanım zamanı ve IP,
+veritabanı yükünü sınırlamak için en fazla dakikada bir güncellenir.
+*/
+func (s *APIKeyService) Authenticate(plain, ip string) (*APIKeyPrincipal, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	principal := &APIKeyPrincipal{}
+	var scopes []string
+	var expired, revoked bool
+	err := s.db.QueryRow(`
+		SELECT k.id, k.user_id, u.username, u.role, k.scopes, k.expires_at <= NOW(), k.revoked_at IS NOT NULL
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1
+	`, hashToken(plain)).Scan(&principal.KeyID, &principal.UserID, &principal.Username, &principal.Role,
+		pq.Array(&scopes), &expired, &revoked)
+	if err == sql.ErrNoRows || (err == nil && revoked) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, ErrAPIKeyExpired
+	}
+
+	for _, scope := range scopes {
+		if HasPermission(principal.Role, Permission(scope)) {
+			principal.Scopes = append(principal.Scopes, Permission(scope))
+		}
+	}
+
+	if _, err := s.db.Exec(`
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $3) OR last_used_ip IS DISTINCT FROM $2)
+	`, principal.KeyID, nullIfEmpty(ip), apiKeyTouchInterval.Seconds()); err != nil {
+		return nil, err
+	}
+
+	return principal, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
+)
+
+// minSecretLength is the shortest HS256 secret accepted, in bytes
+const minSecretLength = 32
+
+// defaultTOTPIssuer is the account label shown in authenticator apps
+const defaultTOTPIssuer = "Interactive Scraper"
+
+/*Bu yapı (AuthConfig), JWT imzalama anahtarlarını ve token ömürlerini tutar. Keys,
+kid → gizli anahtar eşlemesidir; yeni token'lar ActiveKeyID ile imzalanır, diğer
+anahtarlar yalnızca doğrulama için tutulur. Böylece anahtar rotasyonunda yeni anahtar
+aktif yapılırken eski anahtarla imzalanmış token'lar süreleri dolana kadar geçerli kalır.
+*/
+type AuthConfig struct {
+	Keys        map[string][]byte
+	ActiveKeyID string
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+	TOTPIssuer  string
+}
+
+/*Bu fonksiyon, merkezi yapılandırmadaki auth bölümünden kimlik doğrulama ayarlarını
+oluşturur. Anahtarlar jwt_keys (JWT_KEYS, "kid:secret,kid:secret") veya jwt_keys_file
+(JWT_KEYS_FILE, her satırda bir "kid:secret", # ile başlayan satırlar yorum) ile birden
+fazla; jwt_secret (JWT_SECRET) veya jwt_secret_file (JWT_SECRET_FILE) ile "default"
+kid'i altında tek bir anahtar olarak verilebilir. jwt_active_key_id verilmezse listedeki
+ilk anahtar aktif kabul edilir. Hiç anahtar verilmezse her açılışta rastgele bir anahtar
+üretilir ve uyarı loglanır; bu durumda yeniden başlatma sonrası erişim token'ları
+geçersiz olur ancak oturumlar refresh token ile yenilenebilir.
+*/
+func AuthConfigFrom(settings config.AuthConfig) (AuthConfig, error) {
+	cfg := AuthConfig{
+		Keys:        map[string][]byte{},
+		ActiveKeyID: settings.JWTActiveKeyID,
+		AccessTTL:   settings.AccessTTL.Duration,
+		RefreshTTL:  settings.RefreshTTL.Duration,
+		TOTPIssuer:  settings.TOTPIssuer,
+	}
+
+	var order []string
+	addKeys := func(source, list string) error {
+		keys, ids, err := ParseKeyList(list)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		for _, id := range ids {
+			if _, exists := cfg.Keys[id]; exists {
+				return fmt.Errorf("%s: duplicate key id %q", source, id)
+			}
+			cfg.Keys[id] = keys[id]
+			order = append(order, id)
+		}
+		return nil
+	}
+
+	if settings.JWTKeys != "" {
+		if err := addKeys("JWT_KEYS", settings.JWTKeys); err != nil {
+			return cfg, err
+		}
+	}
+	if path := settings.JWTKeysFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read JWT_KEYS_FILE: %v", err)
+		}
+		if err := addKeys("JWT_KEYS_FILE", string(data)); err != nil {
+			return cfg, err
+		}
+	}
+
+	secret := settings.JWTSecret
+	if path := settings.JWTSecretFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read JWT_SECRET_FILE: %v", err)
+		}
+		secret = strings.TrimSpace(string(data))
+	}
+	if secret != "" {
+		if err := addKeys("JWT_SECRET", "default:"+secret); err != nil {
+			return cfg, err
+		}
+	}
+
+	if len(cfg.Keys) == 0 {
+		key := make([]byte, minSecretLength)
+		if _, err := rand.Read(key); err != nil {
+			return cfg, err
+		}
+		logging.For("auth").Warn("no JWT signing key configured (JWT_SECRET, JWT_KEYS), using a random key; access tokens will not survive a restart")
+		cfg.Keys["ephemeral"] = key
+		order = append(order, "ephemeral")
+	}
+	if cfg.ActiveKeyID == "" {
+		cfg.ActiveKeyID = order[0]
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (cfg AuthConfig) Validate() error {
+	if _, ok := cfg.Keys[cfg.ActiveKeyID]; !ok {
+		return fmt.Errorf("active JWT key id %q is not configured", cfg.ActiveKeyID)
+	}
+	for id, key := range cfg.Keys {
+		if len(key) < minSecretLength {
+			return fmt.Errorf("JWT key %q is too short (%d bytes, need at least %d)", id, len(key), minSecretLength)
+		}
+	}
+	if cfg.AccessTTL < time.Minute {
+		return fmt.Errorf("access token lifetime must be at least 1m, got %s", cfg.AccessTTL)
+	}
+	if cfg.RefreshTTL < cfg.AccessTTL {
+		return fmt.Errorf("refresh token lifetime (%s) must not be shorter than access token lifetime (%s)", cfg.RefreshTTL, cfg.AccessTTL)
+	}
+	return nil
+}
+
+// ParseKeyList parses "kid:secret" pairs separated by commas or newlines, keeping their order
+func ParseKeyList(s string) (map[string][]byte, []string, error) {
+	keys := map[string][]byte{}
+	var ids []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		id := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || id == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, nil, fmt.Errorf("invalid key entry %q (expected kid:secret)", truncateSecret(item))
+		}
+		if _, exists := keys[id]; exists {
+			return nil, nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		keys[id] = []byte(strings.TrimSpace(parts[1]))
+		ids = append(ids, id)
+	}
+	return keys, ids, nil
+}
+
+// truncateSecret keeps error messages from echoing a whole secret
+func truncateSecret(s string) string {
+	if i := strings.Index(s, ":"); i >= 0 {
+		return s[:i+1] + "***"
+	}
+	if len(s) > 4 {
+		return s[:4] + "***"
+	}
+	return s
+}
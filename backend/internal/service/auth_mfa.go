@@ -0,0 +1,376 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"interactive-scraper/internal/database"
+)
+
+var (
+	ErrInvalidMFACode   = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken  = errors.New("invalid or expired two-factor login token")
+	ErrMFAState         = errors.New("invalid two-factor state")
+	ErrMFARequiredByAll = errors.New("two-factor authentication is required for all users and cannot be disabled")
+)
+
+const (
+	mfaTokenPurpose   = "mfa"
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+	// recoveryAlphabet avoids characters that are easy to confuse when typed
+	recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	mfaSettingKey    = "require_mfa"
+)
+
+type MFAStatus struct {
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFARequired reports whether an admin has required two-factor authentication for every user
+func (s *AuthService) MFARequired() bool {
+	if s.db == nil {
+		return false
+	}
+	var value string
+	if err := s.db.QueryRow(`SELECT value FROM app_settings WHERE key = $1`, mfaSettingKey).Scan(&value); err != nil {
+		return false
+	}
+	return value == "true"
+}
+
+func (s *AuthService) SetMFARequired(required bool) error {
+	_, err := s.db.Exec(`
+		INSERT INTO app_settings (key, value, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`, mfaSettingKey, fmt.Sprint(required))
+	return err
+}
+
+func (s *AuthService) mfaChallenge(user sessionUser) (*TokenPair, error) {
+	now := time.Now()
+	token, err := s.sign(&Claims{
+		UserID:   user.id,
+		Username: user.username,
+		Purpose:  mfaTokenPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{MFARequired: true, MFAToken: token, PasswordChangeRequired: user.passwordChange}, nil
+}
+
+// ParseMFAToken validates the token returned by the password step of a two-step login
+func (s *AuthService) ParseMFAToken(token string) (*Claims, error) {
+	claims, err := s.parse(token)
+	if err != nil || claims.Purpose != mfaTokenPurpose {
+		return nil, ErrInvalidMFAToken
+	}
+	return claims, nil
+}
+
+/*Bu fonksiyon, iki adımlı girişin ikinci adımıdır: MFA token'ındaki kullanıcı için TOTP
+kodunu veya kullanılmamış bir kurtarma kodunu doğrular ve ancak bundan sonra oturum açıp
+erişim ve refresh token'larını üretir. Aynı TOTP kodu (zaman adımı) ikinci kez kabul
+edilmez; kurtarma kodları tek kullanımlıktır.
+*/
+func (s *AuthService) VerifyMFA(challenge *Claims, code string, meta SessionMeta) (*TokenPair, error) {
+	user := sessionUser{id: challenge.UserID}
+	err := s.db.QueryRow(`
+		SELECT username, role, must_change_password, totp_enabled FROM users WHERE id = $1
+	`, user.id).Scan(&user.username, &user.role, &user.passwordChange, &user.mfaEnabled)
+	if err == sql.ErrNoRows || (err == nil && !user.mfaEnabled) {
+		return nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSecondFactor(user.id, code, true); err != nil {
+		return nil, err
+	}
+	return s.openSession(user, meta)
+}
+
+// totpStepReplayed reports whether a code of step, or a later one, was already accepted; the UPDATE re-checks it atomically
+func totpStepReplayed(lastStep sql.NullInt64, step int64) bool {
+	return lastStep.Valid && step <= lastStep.Int64
+}
+
+// checkSecondFactor accepts a current TOTP code, or a recovery code when allowRecovery is set
+func (s *AuthService) checkSecondFactor(userID int, code string, allowRecovery bool) error {
+	var secret sql.NullString
+	var lastStep sql.NullInt64
+	if err := s.db.QueryRow(`SELECT totp_secret, totp_last_step FROM users WHERE id = $1`, userID).Scan(&secret, &lastStep); err != nil {
+		return err
+	}
+
+	if step, ok := verifyTOTP(secret.String, code, time.Now()); ok && secret.Valid {
+		if totpStepReplayed(lastStep, step) {
+			return ErrInvalidMFACode
+		}
+		result, err := s.db.Exec(`
+			UPDATE users SET totp_last_step = $1
+			WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)
+		`, step, userID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	if !allowRecovery {
+		return ErrInvalidMFACode
+	}
+	result, err := s.db.Exec(`
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *AuthService) GetMFAStatus(userID int) (*MFAStatus, error) {
+	status := &MFAStatus{Required: s.MFARequired()}
+	err := s.db.QueryRow(`
+		SELECT totp_enabled, totp_secret IS NOT NULL AND NOT totp_enabled,
+		       (SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = u.id AND used_at IS NULL)
+		FROM users u WHERE id = $1
+	`, userID).Scan(&status.Enabled, &status.Pending, &status.RecoveryCodesRemaining)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return status, err
+}
+
+/*Bu fonksiyon, TOTP kaydını başlatır: yeni bir gizli anahtar üretip kullanıcıya bekleyen
+(henüz etkin olmayan) anahtar olarak kaydeder ve kimlik doğrulayıcı uygulamada QR kodu
+olarak gösterilecek otpauth:// URI'sini döner. Anahtar, ConfirmTOTP ile geçerli bir kod
+doğrulanana kadar girişte kullanılmaz. Tekrar çağrılırsa bekleyen anahtar yenilenir.
+*/
+func (s *AuthService) EnrollTOTP(userID int) (*MFAEnrollment, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var username string
+	var enabled bool
+	err = s.db.QueryRow(`
+		UPDATE users SET totp_secret = CASE WHEN totp_enabled THEN totp_secret ELSE $1 END
+		WHERE id = $2
+		RETURNING username, totp_enabled
+	`, secret, userID).Scan(&username, &enabled)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is already enabled", ErrMFAState)
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(s.config.TOTPIssuer, username, secret),
+	}, nil
+}
+
+/*Bu fonksiyon, bekleyen TOTP anahtarını uygulamadan okunan kodla doğrular ve iki adımlı
+doğrulamayı etkinleştirir. Etkinleştirmeyle birlikte yeni kurtarma kodları üretilir ve
+yalnızca bu yanıtta düz metin olarak döner; veritabanında hash'leri saklanır.
+*/
+func (s *AuthService) ConfirmTOTP(userID int, code string) ([]string, error) {
+	var secret sql.NullString
+	var enabled bool
+	err := s.db.QueryRow(`SELECT totp_secret, totp_enabled FROM users WHERE id = $1`, userID).Scan(&secret, &enabled)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabled || !secret.Valid {
+		return nil, fmt.Errorf("%w: no pending enrollment, start one with POST /api/users/me/2fa/enroll", ErrMFAState)
+	}
+
+	step, ok := verifyTOTP(secret.String, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET totp_enabled = TRUE, totp_last_step = $1
+		WHERE id = $2 AND totp_secret = $3 AND NOT totp_enabled
+	`, step, userID, secret.String)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("%w: enrollment changed concurrently", ErrMFAState)
+	}
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// RegenerateRecoveryCodes invalidates all recovery codes and returns new ones; code must be a current TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	status, err := s.GetMFAStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Enabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is not enabled", ErrMFAState)
+	}
+	if err := s.checkSecondFactor(userID, code, false); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+/*Bu fonksiyon, kullanıcının kendi iki adımlı doğrulamasını kapatır. Şifre ve geçerli bir
+TOTP ya da kurtarma kodu gerekir. Yönetici 2FA'yı herkes için zorunlu kıldıysa kapatma
+reddedilir.
+*/
+func (s *AuthService) DisableTOTP(userID int, password, code string) error {
+	if s.MFARequired() {
+		return ErrMFARequiredByAll
+	}
+
+	var passwordHash string
+	var enabled bool
+	err := s.db.QueryRow(`SELECT password_hash, totp_enabled FROM users WHERE id = $1`, userID).Scan(&passwordHash, &enabled)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return fmt.Errorf("%w: two-factor authentication is not enabled", ErrMFAState)
+	}
+	if !database.CheckPasswordHash(password, passwordHash) {
+		return ErrWrongPassword
+	}
+	if err := s.checkSecondFactor(userID, code, true); err != nil {
+		return err
+	}
+	return s.ResetMFA(userID)
+}
+
+// ResetMFA removes a user's TOTP secret and recovery codes, e.g. when an admin handles a lost device
+func (s *AuthService) ResetMFA(userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = NULL WHERE id = $1
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		result, err := tx.Exec(`
+			INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, userID, hashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a code formatted as xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	var sb strings.Builder
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -1,24 +1,38 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"interactive-scraper/internal/database"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrSessionRevoked      = errors.New("session has been revoked")
+)
+
 type AuthService struct {
-	db          *sql.DB
-	jwtSecret   []byte
+	db     *sql.DB
+	config AuthConfig
 }
 
-func NewAuthService() *AuthService {
-	secret := "your-secret-key-change-in-production"
-	return &AuthService{
-		jwtSecret: []byte(secret),
+func NewAuthService(config AuthConfig) (*AuthService, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.TOTPIssuer == "" {
+		config.TOTPIssuer = defaultTOTPIssuer
 	}
+	return &AuthService{config: config}, nil
 }
 
 func (s *AuthService) SetDB(db *sql.DB) {
@@ -31,58 +45,252 @@ type LoginRequest struct {
 }
 
 type Claims struct {
-	Username string `json:"username"`
+	UserID         int    `json:"user_id"`
+	Username       string `json:"username"`
+	Role           string `json:"role"`
+	SessionID      string `json:"sid,omitempty"`
+	PasswordChange bool   `json:"pwd_change,omitempty"`
+	MFASetup       bool   `json:"mfa_setup,omitempty"`
+	// Purpose is set on tokens that are not access tokens, e.g. "mfa" for the second login step
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func (s *AuthService) Login(username, password string) (string, error) {
+/*Bu yapı (TokenPair), giriş ve yenileme yanıtıdır; Token kısa ömürlü erişim token'ıdır.
+Kullanıcıda iki adımlı doğrulama açıksa şifre doğrulandıktan sonra token'lar yerine
+MFARequired ve ikinci adımda kullanılacak MFAToken döner.
+*/
+type TokenPair struct {
+	Token                  string     `json:"token,omitempty"`
+	TokenType              string     `json:"token_type,omitempty"`
+	ExpiresIn              int        `json:"expires_in,omitempty"`
+	RefreshToken           string     `json:"refresh_token,omitempty"`
+	RefreshUntil           *time.Time `json:"refresh_expires_at,omitempty"`
+	PasswordChangeRequired bool       `json:"password_change_required"`
+	MFASetupRequired       bool       `json:"mfa_setup_required"`
+	MFARequired            bool       `json:"mfa_required,omitempty"`
+	MFAToken               string     `json:"mfa_token,omitempty"`
+}
+
+// sessionUser is the user a token is issued for
+type sessionUser struct {
+	id             int
+	username       string
+	role           string
+	passwordChange bool
+	mfaEnabled     bool
+	// external users sign in through the identity provider, which owns their password and MFA
+	external bool
+}
+
+// SessionMeta describes the client a session was opened from
+type SessionMeta struct {
+	IP        string
+	UserAgent string
+}
+
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int        `json:"user_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+}
+
+/*Bu fonksiyon, kullanıcı adı ve şifreyi doğrular ve yeni bir oturum açar. Her oturum
+user_sessions tablosunda bir satırdır; refresh token yalnızca SHA-256 hash'i olarak
+saklanır. Dönen erişim token'ı kısa ömürlüdür ve oturum kimliğini (sid) taşır. Kullanıcıda
+TOTP açıksa oturum açılmaz; bunun yerine VerifyMFA ile kullanılacak kısa ömürlü bir
+MFA token'ı döner.
+*/
+func (s *AuthService) Login(username, password string, meta SessionMeta) (*TokenPair, error) {
 	if s.db == nil {
-		return "", errors.New("database not initialized")
+		return nil, errors.New("database not initialized")
 	}
 
+	var user sessionUser
 	var passwordHash string
 	err := s.db.QueryRow(`
-		SELECT password_hash 
-		FROM users 
-		WHERE username = $1
-	`, username).Scan(&passwordHash)
+		SELECT id, username, password_hash, role, must_change_password, totp_enabled
+		FROM users
+		WHERE username = $1 AND auth_provider = 'local'
+	`, username).Scan(&user.id, &user.username, &passwordHash, &user.role, &user.passwordChange, &user.mfaEnabled)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", errors.New("invalid credentials")
+			return nil, ErrInvalidCredentials
 		}
-		return "", err
+		return nil, err
 	}
 
 	if !database.CheckPasswordHash(password, passwordHash) {
-		return "", errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.mfaEnabled {
+		return s.mfaChallenge(user)
 	}
+	return s.openSession(user, meta)
+}
 
-	// Generate JWT token
-	expirationTime := time.Now().Add(24 * time.Hour)
+// openSession stores a new session for an authenticated user and issues its tokens
+func (s *AuthService) openSession(user sessionUser, meta SessionMeta) (*TokenPair, error) {
+	s.db.Exec(`DELETE FROM user_sessions WHERE expires_at < NOW() - INTERVAL '7 days'`)
+
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(s.config.RefreshTTL)
+	_, err = s.db.Exec(`
+		INSERT INTO user_sessions (id, user_id, refresh_token_hash, expires_at, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, sessionID, user.id, hashToken(refreshToken), expiresAt, nullIfEmpty(meta.IP), nullIfEmpty(truncateString(meta.UserAgent, 500)))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(user, sessionID, refreshToken, expiresAt)
+}
+
+/*Bu fonksiyon, refresh token ile yeni bir erişim token'ı üretir. Refresh token her
+kullanımda yenisiyle değiştirilir (rotasyon); eski token bir daha kullanılamaz. Rol
+bilgisi veritabanından yeniden okunduğu için kullanıcının rolü değiştiyse yeni erişim
+token'ı güncel rolü taşır. İptal edilmiş, süresi dolmuş veya bilinmeyen token'lar
+ErrInvalidRefreshToken döner.
+*/
+func (s *AuthService) Refresh(refreshToken string, meta SessionMeta) (*TokenPair, error) {
+	return s.rotate("us.refresh_token_hash = $1", hashToken(refreshToken), meta)
+}
+
+// ReissueSession rotates the given session's tokens, e.g. after a password change cleared pwd_change
+func (s *AuthService) ReissueSession(sessionID string, meta SessionMeta) (*TokenPair, error) {
+	return s.rotate("us.id = $1", sessionID, meta)
+}
+
+func (s *AuthService) rotate(condition string, arg interface{}, meta SessionMeta) (*TokenPair, error) {
+	if s.db == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	next, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	var user sessionUser
+	var sessionID string
+	var expiresAt time.Time
+	err = s.db.QueryRow(`
+		UPDATE user_sessions us
+		SET refresh_token_hash = $2,
+		    last_used_at = NOW(),
+		    ip = COALESCE($3, us.ip)
+		FROM users u
+		WHERE u.id = us.user_id
+		  AND `+condition+`
+		  AND us.revoked_at IS NULL
+		  AND us.expires_at > NOW()
+		RETURNING us.id, us.expires_at, u.id, u.username, u.role, u.must_change_password, u.totp_enabled, u.auth_provider <> 'local'
+	`, arg, hashToken(next), nullIfEmpty(meta.IP)).Scan(&sessionID, &expiresAt,
+		&user.id, &user.username, &user.role, &user.passwordChange, &user.mfaEnabled, &user.external)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(user, sessionID, next, expiresAt)
+}
+
+func (s *AuthService) issue(user sessionUser, sessionID, refreshToken string, refreshUntil time.Time) (*TokenPair, error) {
+	now := time.Now()
 	claims := &Claims{
-		Username: username,
+		UserID:         user.id,
+		Username:       user.username,
+		Role:           user.role,
+		SessionID:      sessionID,
+		PasswordChange: user.passwordChange,
+		MFASetup:       !user.external && !user.mfaEnabled && s.MFARequired(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.AccessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(s.jwtSecret)
+	tokenString, err := s.sign(claims)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return tokenString, nil
+	return &TokenPair{
+		Token:                  tokenString,
+		TokenType:              "Bearer",
+		ExpiresIn:              int(s.config.AccessTTL.Seconds()),
+		RefreshToken:           refreshToken,
+		RefreshUntil:           &refreshUntil,
+		PasswordChangeRequired: user.passwordChange,
+		MFASetupRequired:       claims.MFASetup,
+	}, nil
+}
+
+// sign signs claims with the active key and records its kid in the header
+func (s *AuthService) sign(claims *Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = s.config.ActiveKeyID
+	return token.SignedString(s.config.Keys[s.config.ActiveKeyID])
 }
 
+/*Bu fonksiyon, erişim token'ını doğrular. İmza anahtarı token başlığındaki kid ile
+seçilir; kid içermeyen veya yapılandırılmamış bir kid taşıyan token'lar reddedilir.
+Yalnızca HS256 kabul edilir. Veritabanı bağlıysa token'ın oturumu da kontrol edilir;
+böylece çıkış yapılan veya yönetici tarafından iptal edilen oturumların erişim token'ları
+süreleri dolmadan da geçersiz olur.
+*/
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+
+	if s.db != nil {
+		var active bool
+		err := s.db.QueryRow(`
+			SELECT revoked_at IS NULL AND expires_at > NOW() FROM user_sessions WHERE id = $1
+		`, claims.SessionID).Scan(&active)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrSessionRevoked
+		}
+	}
+
+	return claims, nil
+}
+
+func (s *AuthService) parse(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return s.jwtSecret, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.config.Keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -95,3 +303,126 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+/*Bu fonksiyon, çıkış işlemini yapar: verilen refresh token'ın ve/veya erişim token'ındaki
+oturumun (sessionID) iptal edilmesini sağlar. İptal edilen oturum bir daha yenilenemez.
+Oturum sahibinin ID'si ve kullanıcı adı döner; hiçbir oturum bulunamazsa
+ErrInvalidRefreshToken döner.
+*/
+func (s *AuthService) Logout(refreshToken, sessionID string) (int, string, error) {
+	if s.db == nil {
+		return 0, "", errors.New("database not initialized")
+	}
+
+	var tokenHash interface{}
+	if refreshToken != "" {
+		tokenHash = hashToken(refreshToken)
+	}
+	var userID int
+	var username string
+	err := s.db.QueryRow(`
+		WITH revoked AS (
+			UPDATE user_sessions SET revoked_at = NOW()
+			WHERE (refresh_token_hash = $1 OR id = $2) AND revoked_at IS NULL
+			RETURNING user_id
+		)
+		SELECT u.id, u.username FROM revoked r JOIN users u ON u.id = r.user_id LIMIT 1
+	`, tokenHash, nullIfEmpty(sessionID)).Scan(&userID, &username)
+	if err == sql.ErrNoRows {
+		return 0, "", ErrInvalidRefreshToken
+	}
+	return userID, username, err
+}
+
+func (s *AuthService) ListUserSessions(userID int) ([]Session, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, created_at, last_used_at, expires_at, revoked_at, COALESCE(ip, ''), COALESCE(user_agent, '')
+		FROM user_sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastUsedAt,
+			&session.ExpiresAt, &revokedAt, &session.IP, &session.UserAgent); err != nil {
+			return nil, err
+		}
+		if revokedAt.Valid {
+			session.RevokedAt = &revokedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+/*Bu fonksiyon, kullanıcının tüm açık oturumlarını ve API anahtarlarını tek bir işlemde
+iptal eder ve kaç oturum ile kaç anahtarın iptal edildiğini döner. Anahtarlar da
+X-API-Key ile kimlik doğrulamanın bir yolu olduğu için, "tüm oturumları kapat" ele
+geçirilmiş bir hesabı ancak anahtarlarla birlikte gerçekten dışarıda bırakır.
+*/
+func (s *AuthService) RevokeUserSessions(userID int) (sessions, apiKeys int, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	if sessions, err = revokeUserSessions(tx, userID); err != nil {
+		return 0, 0, err
+	}
+	if apiKeys, err = revokeUserAPIKeys(tx, userID); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return sessions, apiKeys, nil
+}
+
+func revokeUserSessions(db execer, userID int) (int, error) {
+	result, err := db.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func truncateString(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
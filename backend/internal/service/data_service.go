@@ -1,19 +1,31 @@
 package service
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"html"
+	"strconv"
+	"strings"
 	"time"
+
+	"interactive-scraper/internal/siem"
 )
 
 type DataService struct {
-	db *sql.DB
+	db      *sql.DB
+	emitter *siem.Emitter
 }
 
 func NewDataService(db *sql.DB) *DataService {
 	return &DataService{db: db}
 }
 
+func (s *DataService) SetEmitter(emitter *siem.Emitter) {
+	s.emitter = emitter
+}
+
 func (s *DataService) GetDB() *sql.DB {
 	return s.db
 }
@@ -30,6 +42,38 @@ type DataEntry struct {
 	Category        string     `json:"category"`
 	AIAnalysis      *string    `json:"ai_analysis,omitempty"` // Optional AI interpretation
 	CreatedAt       time.Time  `json:"created_at"`
+	TriageStatus    string     `json:"triage_status"`
+	AssigneeID      *int       `json:"assignee_id"`
+	Assignee        *string    `json:"assignee"`
+	TriageUpdatedAt *time.Time `json:"triage_updated_at"`
+	Tags            []TagRef   `json:"tags"`
+	Rank            *float64   `json:"rank,omitempty"`            // Full-text search relevance
+	TitleHighlight  *string    `json:"title_highlight,omitempty"` // HTML-escaped title with <mark> around matches
+	Snippet         *string    `json:"snippet,omitempty"`         // HTML-escaped content fragments with <mark> around matches
+}
+
+// highlightStart and highlightStop are random so that stored text cannot forge them; markHighlights turns them into <mark>
+var highlightStart, highlightStop = highlightSentinel(), highlightSentinel()
+
+var (
+	titleHeadlineOptions  = fmt.Sprintf("HighlightAll=true, StartSel=%s, StopSel=%s", highlightStart, highlightStop)
+	searchHeadlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=3, MaxWords=35, MinWords=15, FragmentDelimiter=\" … \"",
+		highlightStart, highlightStop)
+	highlightMarker = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+)
+
+// highlightSentinel is a hex token, so it needs no quoting in ts_headline options and survives HTML escaping
+func highlightSentinel() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate highlight marker: %v", err))
+	}
+	return "hl" + hex.EncodeToString(b)
+}
+
+// markHighlights escapes ts_headline output, which is raw scraped text, and only then adds the <mark> tags
+func markHighlights(headline string) string {
+	return highlightMarker.Replace(html.EscapeString(headline))
 }
 
 type CategoryStats struct {
@@ -37,6 +81,13 @@ type CategoryStats struct {
 	Count    int    `json:"count"`
 }
 
+type TagStats struct {
+	TagID int    `json:"tag_id"`
+	Tag   string `json:"tag"`
+	Color string `json:"color"`
+	Count int    `json:"count"`
+}
+
 type CriticalityDistribution struct {
 	Range  string `json:"range"`
 	Count  int    `json:"count"`
@@ -46,134 +97,236 @@ type DashboardStats struct {
 	TotalEntries      int                      `json:"total_entries"`
 	TotalSources      int                      `json:"total_sources"`
 	CategoryStats     []CategoryStats          `json:"category_stats"`
+	TagStats          []TagStats               `json:"tag_stats"`
 	CriticalityDist   []CriticalityDistribution `json:"criticality_distribution"`
 	RecentEntries     []DataEntry              `json:"recent_entries"`
 	TimeSeriesData    []TimeSeriesData         `json:"time_series_data,omitempty"`
 	AIAnalysisStatus  *AIAnalysisStatus        `json:"ai_analysis_status,omitempty"`
 }
 
-func (s *DataService) GetAllEntries(page, pageSize int, category, search string) ([]DataEntry, int, error) {
-	offset := (page - 1) * pageSize
+/*Bu fonksiyon, kayıtları EntryFilter'daki filtrelere göre listeler. Arama metni
+verildiğinde search_vector üzerinde tam metin arama yapılır, varsayılan sıralama
+alaka puanı olur ve her kayıt için eşleşmeleri <mark> ile işaretlenmiş başlık ve
+içerik parçaları döndürülür; bu parçaların geri kalanı HTML olarak kaçışlanır, yani
+doğrudan innerHTML ile gösterilebilir. Sıralama her zaman id ile tamamlanır; böylece aynı
+değere sahip kayıtlar arasında da kararlı bir sıra oluşur ve next_cursor ile
+sonraki sayfa, scraper yeni kayıt ekliyor olsa bile kaymadan alınabilir.
+*/
+func (s *DataService) GetAllEntries(filter EntryFilter, page PageRequest) (*EntryPage, error) {
+	if page.PageSize < 1 {
+		page.PageSize = 20
+	}
+	if page.PageSize > 500 {
+		page.PageSize = 500
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
 
-	query := `
-		SELECT e.id, e.source_id, s.name, s.url, e.title, e.cleaned_content, 
-		       e.share_date, e.criticality_score, e.category, e.ai_analysis, e.created_at
-		FROM data_entries e
-		JOIN sources s ON e.source_id = s.id
-		WHERE 1=1
-	`
-	args := []interface{}{}
-	argIndex := 1
+	q, err := buildEntryQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	sortName, order, err := resolveSort(filter, q.tsQuery != "")
+	if err != nil {
+		return nil, err
+	}
+	sortColumn := entrySortColumns[sortName]
 
-	if category != "" {
-		query += fmt.Sprintf(" AND e.category = $%d", argIndex)
-		args = append(args, category)
-		argIndex++
+	var total int
+	countQuery := "SELECT COUNT(*)" + q.from + q.whereClause()
+	if err := s.db.QueryRow(countQuery, q.args...).Scan(&total); err != nil {
+		return nil, err
 	}
 
-	if search != "" {
-		query += fmt.Sprintf(" AND (e.title ILIKE $%d OR e.cleaned_content ILIKE $%d)", argIndex, argIndex)
-		args = append(args, "%"+search+"%")
-		argIndex++
+	selectExtra := fmt.Sprintf(`,
+		       (%s)::text AS sort_key`, sortColumn.expr)
+	if q.tsQuery != "" {
+		selectExtra += fmt.Sprintf(`,
+		       ts_rank_cd(e.search_vector, q) AS rank,
+		       ts_headline(%s::regconfig, e.title, q, '%s') AS title_highlight,
+		       ts_headline(%s::regconfig, e.cleaned_content, q, '%s') AS snippet`,
+			q.langArg, titleHeadlineOptions, q.langArg, searchHeadlineOptions)
 	}
 
-	query += " ORDER BY e.created_at DESC"
-	
-	countQuery := "SELECT COUNT(*) FROM (" + query + ") as count_query"
-	var total int
-	err := s.db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
+	comparator := "<"
+	if order == "asc" {
+		comparator = ">"
 	}
+	offset := (page.Page - 1) * page.PageSize
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	args = append(args, pageSize, offset)
+	if page.Cursor != "" {
+		cursor, err := decodeEntryCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sortName || cursor.Order != order {
+			return nil, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidCursor)
+		}
+		q.where = append(q.where, fmt.Sprintf("(%s, e.id) %s (%s::%s, %s)",
+			sortColumn.expr, comparator, q.arg(cursor.Key), sortColumn.sqlType, q.arg(cursor.ID)))
+		offset = 0
+	}
+
+	query := "\n\t\tSELECT " + entryColumns + selectExtra + q.from + q.whereClause() +
+		fmt.Sprintf("\n\t\tORDER BY %s %s, e.id %s", sortColumn.expr, order, order) +
+		fmt.Sprintf("\n\t\tLIMIT %s OFFSET %s", q.arg(page.PageSize+1), q.arg(offset))
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.Query(query, q.args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 
-	var entries []DataEntry
+	result := &EntryPage{Entries: []DataEntry{}, Total: total}
+	var lastKey string
 	for rows.Next() {
-		var entry DataEntry
-		var shareDate sql.NullTime
-		var aiAnalysis sql.NullString
-		err := rows.Scan(
-			&entry.ID, &entry.SourceID, &entry.SourceName, &entry.SourceURL,
-			&entry.Title, &entry.CleanedContent, &shareDate,
-			&entry.CriticalityScore, &entry.Category, &aiAnalysis, &entry.CreatedAt,
-		)
+		var sortKey string
+		var rank sql.NullFloat64
+		var titleHighlight, snippet sql.NullString
+		extra := []interface{}{&sortKey}
+		if q.tsQuery != "" {
+			extra = append(extra, &rank, &titleHighlight, &snippet)
+		}
+
+		entry, err := scanEntry(rows, extra...)
 		if err != nil {
-			continue
+			return nil, err
 		}
-		if shareDate.Valid {
-			entry.ShareDate = &shareDate.Time
+		if len(result.Entries) == page.PageSize {
+			last := result.Entries[len(result.Entries)-1]
+			result.NextCursor = encodeEntryCursor(entryCursor{Sort: sortName, Order: order, Key: lastKey, ID: last.ID})
+			break
 		}
-		if aiAnalysis.Valid && aiAnalysis.String != "" {
-			entry.AIAnalysis = &aiAnalysis.String
+
+		if rank.Valid {
+			entry.Rank = &rank.Float64
 		}
-		entries = append(entries, entry)
+		if titleHighlight.Valid {
+			marked := markHighlights(titleHighlight.String)
+			entry.TitleHighlight = &marked
+		}
+		if snippet.Valid {
+			marked := markHighlights(snippet.String)
+			entry.Snippet = &marked
+		}
+		result.Entries = append(result.Entries, entry)
+		lastKey = sortKey
 	}
 
-	return entries, total, nil
+	return result, rows.Err()
 }
 
 func (s *DataService) GetEntryByID(id int) (*DataEntry, error) {
-	var entry DataEntry
-	var shareDate sql.NullTime
-	var aiAnalysis sql.NullString
-
-	err := s.db.QueryRow(`
-		SELECT e.id, e.source_id, s.name, s.url, e.title, e.cleaned_content,
-		       e.share_date, e.criticality_score, e.category, e.ai_analysis, e.created_at
-		FROM data_entries e
-		JOIN sources s ON e.source_id = s.id
+	entry, err := scanEntry(s.db.QueryRow(`
+		SELECT `+entryColumns+entryFrom+`
 		WHERE e.id = $1
-	`, id).Scan(
-		&entry.ID, &entry.SourceID, &entry.SourceName, &entry.SourceURL,
-		&entry.Title, &entry.CleanedContent, &shareDate,
-		&entry.CriticalityScore, &entry.Category, &aiAnalysis, &entry.CreatedAt,
-	)
+	`, id))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if shareDate.Valid {
-		entry.ShareDate = &shareDate.Time
-	}
+	return &entry, nil
+}
 
-	if aiAnalysis.Valid && aiAnalysis.String != "" {
-		entry.AIAnalysis = &aiAnalysis.String
-	}
+// queryRower is implemented by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
 
-	return &entry, nil
+func (s *DataService) UpdateCriticality(id int, score int, actor string) error {
+	event, err := updateCriticality(s.db, id, score, actor)
+	if err == sql.ErrNoRows {
+		return ErrEntryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		s.emitter.Emit(*event)
+	}
+	return nil
 }
 
-func (s *DataService) UpdateCriticality(id int, score int) error {
+/*Bu fonksiyon, tek bir kaydın kritiklik puanını verilen bağlantı veya transaction
+üzerinden günceller. Eski değer aynı sorguda kilitlenerek okunur; puan gerçekten
+değiştiyse SIEM'e gönderilecek değişiklik olayı, değişmediyse nil döner. Kaynağı
+olmayan (source_id NULL) kayıtlar da güncellenir; olayda kaynak alanları boş kalır. Kayıt
+yoksa sql.ErrNoRows döner; olayın gönderilmesi çağıranın sorumluluğundadır, böylece toplu
+işlemlerde olaylar yalnızca transaction başarıyla tamamlandıktan sonra gönderilir.
+*/
+func updateCriticality(q queryRower, id int, score int, actor string) (*siem.Event, error) {
 	if score < 0 || score > 100 {
-		return fmt.Errorf("criticality score must be between 0 and 100")
+		return nil, fmt.Errorf("criticality score must be between 0 and 100")
 	}
 
-	_, err := s.db.Exec(`
-		UPDATE data_entries 
-		SET criticality_score = $1 
-		WHERE id = $2
-	`, score, id)
+	var oldScore int
+	var event siem.Event
+	err := q.QueryRow(`
+		UPDATE data_entries e
+		SET criticality_score = $1
+		FROM (SELECT id, source_id, criticality_score FROM data_entries WHERE id = $2 FOR UPDATE) old
+		LEFT JOIN sources s ON s.id = old.source_id
+		WHERE e.id = old.id
+		RETURNING old.criticality_score, e.title, e.category, COALESCE(e.source_id, 0), COALESCE(s.name, ''), COALESCE(s.url, '')
+	`, score, id).Scan(&oldScore, &event.Title, &event.Category, &event.SourceID, &event.SourceName, &event.SourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if oldScore == score {
+		return nil, nil
+	}
+	event.Type = siem.EventCriticalityChange
+	event.EntryID = id
+	event.CriticalityScore = score
+	event.OldValue = strconv.Itoa(oldScore)
+	event.NewValue = strconv.Itoa(score)
+	event.Actor = actor
+	return &event, nil
+}
 
-	return err
+func (s *DataService) UpdateCategory(id int, category string, actor string) error {
+	event, err := updateCategory(s.db, id, category, actor)
+	if err == sql.ErrNoRows {
+		return ErrEntryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		s.emitter.Emit(*event)
+	}
+	return nil
 }
 
-func (s *DataService) UpdateCategory(id int, category string) error {
-	_, err := s.db.Exec(`
-		UPDATE data_entries 
-		SET category = $1 
-		WHERE id = $2
-	`, category, id)
+// updateCategory is the category counterpart of updateCriticality
+func updateCategory(q queryRower, id int, category string, actor string) (*siem.Event, error) {
+	var oldCategory string
+	var event siem.Event
+	err := q.QueryRow(`
+		UPDATE data_entries e
+		SET category = $1
+		FROM (SELECT id, source_id, category FROM data_entries WHERE id = $2 FOR UPDATE) old
+		LEFT JOIN sources s ON s.id = old.source_id
+		WHERE e.id = old.id
+		RETURNING old.category, e.title, e.criticality_score, COALESCE(e.source_id, 0), COALESCE(s.name, ''), COALESCE(s.url, '')
+	`, category, id).Scan(&oldCategory, &event.Title, &event.CriticalityScore, &event.SourceID, &event.SourceName, &event.SourceURL)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	if oldCategory == category {
+		return nil, nil
+	}
+	event.Type = siem.EventCategoryChange
+	event.EntryID = id
+	event.Category = category
+	event.OldValue = oldCategory
+	event.NewValue = category
+	event.Actor = actor
+	return &event, nil
 }
 
 func (s *DataService) GetDashboardStats() (*DashboardStats, error) {
@@ -210,6 +363,27 @@ func (s *DataService) GetDashboardStats() (*DashboardStats, error) {
 		}
 	}
 
+	// Tag stats
+	tagRows, err := s.db.Query(`
+		SELECT t.id, t.name, t.color, COUNT(et.entry_id) as count
+		FROM tags t
+		JOIN entry_tags et ON et.tag_id = t.id
+		GROUP BY t.id, t.name, t.color
+		ORDER BY count DESC, t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	stats.TagStats = []TagStats{}
+	for tagRows.Next() {
+		var ts TagStats
+		if err := tagRows.Scan(&ts.TagID, &ts.Tag, &ts.Color, &ts.Count); err == nil {
+			stats.TagStats = append(stats.TagStats, ts)
+		}
+	}
+
 	// Criticality distribution
 	criticalityRanges := []struct {
 		label string
@@ -239,10 +413,7 @@ func (s *DataService) GetDashboardStats() (*DashboardStats, error) {
 
 	// Recent entries (last 10)
 	rows, err = s.db.Query(`
-		SELECT e.id, e.source_id, s.name, s.url, e.title, e.cleaned_content,
-		       e.share_date, e.criticality_score, e.category, e.ai_analysis, e.created_at
-		FROM data_entries e
-		JOIN sources s ON e.source_id = s.id
+		SELECT ` + entryColumns + entryFrom + `
 		ORDER BY e.created_at DESC
 		LIMIT 10
 	`)
@@ -252,20 +423,7 @@ func (s *DataService) GetDashboardStats() (*DashboardStats, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var entry DataEntry
-		var shareDate sql.NullTime
-		var aiAnalysis sql.NullString
-		if err := rows.Scan(
-			&entry.ID, &entry.SourceID, &entry.SourceName, &entry.SourceURL,
-			&entry.Title, &entry.CleanedContent, &shareDate,
-			&entry.CriticalityScore, &entry.Category, &aiAnalysis, &entry.CreatedAt,
-		); err == nil {
-			if shareDate.Valid {
-				entry.ShareDate = &shareDate.Time
-			}
-			if aiAnalysis.Valid && aiAnalysis.String != "" {
-				entry.AIAnalysis = &aiAnalysis.String
-			}
+		if entry, err := scanEntry(rows); err == nil {
 			stats.RecentEntries = append(stats.RecentEntries, entry)
 		}
 	}
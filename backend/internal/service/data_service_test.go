@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestMarkHighlights(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     string
+	}{
+		{"plain match", "a " + highlightStart + "leak" + highlightStop + " here", "a <mark>leak</mark> here"},
+		{"markup in content", `<img src=x onerror=alert(1)> ` + highlightStart + "leak" + highlightStop,
+			"&lt;img src=x onerror=alert(1)&gt; <mark>leak</mark>"},
+		{"forged mark tags", "<mark>fake</mark> " + highlightStart + "real" + highlightStop,
+			"&lt;mark&gt;fake&lt;/mark&gt; <mark>real</mark>"},
+		{"quotes and ampersands", `"AT&T" ` + highlightStart + "dump" + highlightStop, "&#34;AT&amp;T&#34; <mark>dump</mark>"},
+		{"no match", "nothing to see", "nothing to see"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markHighlights(tt.headline); got != tt.want {
+				t.Errorf("markHighlights(%q) = %q, want %q", tt.headline, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightSentinelsAreRandom(t *testing.T) {
+	if highlightStart == highlightStop || highlightSentinel() == highlightSentinel() {
+		t.Fatal("highlight sentinels must differ")
+	}
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"interactive-scraper/internal/siem"
+)
+
+// maxBulkEntries caps how many entries a single bulk request may touch
+const maxBulkEntries = 5000
+
+var ErrInvalidBulkRequest = errors.New("invalid bulk request")
+
+const (
+	BulkSetCategory    = "set_category"
+	BulkSetCriticality = "set_criticality"
+	BulkSetStatus      = "set_status"
+	BulkAddTags        = "add_tags"
+	BulkRemoveTags     = "remove_tags"
+	BulkDelete         = "delete"
+)
+
+/*Bu yapı (BulkRequest), birden fazla kayda aynı işlemin uygulanması isteğini temsil
+eder. Hedef kayıtlar ya IDs ile açıkça ya da Filter ile (GET /api/entries ile aynı
+filtreler) seçilir; ikisi birlikte verilemez. Action'a göre ilgili değer alanı
+(Category, Criticality, Status, TagIDs/TagNames) doldurulmalıdır. AllowPartial false
+ise (varsayılan) tek bir kayıtta hata olması tüm işlemi geri alır; true ise hatalı
+kayıtlar atlanır ve diğerleri kaydedilir.
+*/
+type BulkRequest struct {
+	IDs          []int        `json:"ids"`
+	Filter       *EntryFilter `json:"filter"`
+	Action       string       `json:"action"`
+	Category     string       `json:"category"`
+	Criticality  *int         `json:"criticality"`
+	Status       string       `json:"status"`
+	TagIDs       []int        `json:"tag_ids"`
+	TagNames     []string     `json:"tag_names"`
+	AllowPartial bool         `json:"allow_partial"`
+}
+
+type BulkItemResult struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"` // updated, unchanged, failed
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkResult struct {
+	Action    string           `json:"action"`
+	Committed bool             `json:"committed"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+func validateBulkRequest(req BulkRequest) error {
+	if len(req.IDs) > 0 && req.Filter != nil {
+		return fmt.Errorf("%w: use either ids or filter, not both", ErrInvalidBulkRequest)
+	}
+	if len(req.IDs) == 0 && req.Filter == nil {
+		return fmt.Errorf("%w: ids or filter is required", ErrInvalidBulkRequest)
+	}
+	if len(req.IDs) > maxBulkEntries {
+		return fmt.Errorf("%w: at most %d entries can be changed at once", ErrInvalidBulkRequest, maxBulkEntries)
+	}
+
+	switch req.Action {
+	case BulkSetCategory:
+		if req.Category == "" {
+			return fmt.Errorf("%w: category is required", ErrInvalidBulkRequest)
+		}
+	case BulkSetCriticality:
+		if req.Criticality == nil || *req.Criticality < 0 || *req.Criticality > 100 {
+			return fmt.Errorf("%w: criticality must be between 0 and 100", ErrInvalidBulkRequest)
+		}
+	case BulkSetStatus:
+		if !IsValidTriageStatus(req.Status) {
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidBulkRequest, req.Status)
+		}
+	case BulkAddTags, BulkRemoveTags:
+		if len(req.TagIDs) == 0 && len(req.TagNames) == 0 {
+			return fmt.Errorf("%w: tag_ids or tag_names is required", ErrInvalidBulkRequest)
+		}
+	case BulkDelete:
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidBulkRequest, req.Action)
+	}
+	return nil
+}
+
+/*Bu fonksiyon, toplu işlemi tek bir transaction içinde uygular. Filtre verilmişse
+hedef kayıtlar aynı transaction içinde filtreyle seçilir ve maxBulkEntries sınırı
+aşılırsa işlem reddedilir. Her kayıt kendi SAVEPOINT'i içinde işlenir; böylece bir
+kayıttaki hata (ör. izin verilmeyen triage geçişi) transaction'ı bozmadan o kayda ait
+sonuç olarak raporlanır. Hata varsa ve AllowPartial false ise transaction geri alınır
+ve Committed false döner. SIEM olayları yalnızca commit başarılı olduktan sonra
+gönderilir.
+*/
+func (s *DataService) BulkUpdate(req BulkRequest, actorID int, actor string) (*BulkResult, error) {
+	if err := validateBulkRequest(req); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := req.IDs
+	if req.Filter != nil {
+		if ids, err = selectEntryIDs(tx, *req.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var tagIDs []int64
+	if req.Action == BulkAddTags || req.Action == BulkRemoveTags {
+		if tagIDs, err = resolveTagIDs(tx, req.TagIDs, req.TagNames, req.Action == BulkAddTags); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BulkResult{Action: req.Action, Total: len(ids), Results: make([]BulkItemResult, 0, len(ids))}
+	var events []siem.Event
+	statusChanges := make(map[int][2]string)
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`SAVEPOINT bulk_item`); err != nil {
+			return nil, err
+		}
+
+		changed, event, err := applyBulkAction(tx, req, id, tagIDs, actorID, actor, statusChanges)
+		item := BulkItemResult{ID: id, Status: "unchanged"}
+		if err != nil {
+			if _, rbErr := tx.Exec(`ROLLBACK TO SAVEPOINT bulk_item`); rbErr != nil {
+				return nil, rbErr
+			}
+			item.Status = "failed"
+			item.Error = bulkErrorMessage(err)
+			result.Failed++
+		} else {
+			if _, err := tx.Exec(`RELEASE SAVEPOINT bulk_item`); err != nil {
+				return nil, err
+			}
+			if changed {
+				item.Status = "updated"
+			}
+			if event != nil {
+				events = append(events, *event)
+			}
+			result.Succeeded++
+		}
+		result.Results = append(result.Results, item)
+	}
+
+	if result.Failed > 0 && !req.AllowPartial {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	result.Committed = true
+
+	for _, event := range events {
+		s.emitter.Emit(event)
+	}
+	s.emitStatusChanges(statusChanges, actor)
+	return result, nil
+}
+
+func applyBulkAction(tx *sql.Tx, req BulkRequest, id int, tagIDs []int64, actorID int, actor string,
+	statusChanges map[int][2]string) (bool, *siem.Event, error) {
+	switch req.Action {
+	case BulkSetCategory:
+		event, err := updateCategory(tx, id, req.Category, actor)
+		return event != nil, event, err
+	case BulkSetCriticality:
+		event, err := updateCriticality(tx, id, *req.Criticality, actor)
+		return event != nil, event, err
+	case BulkSetStatus:
+		previous, _, err := transitionStatus(tx, id, req.Status, actorID)
+		if err != nil {
+			return false, nil, err
+		}
+		if previous == req.Status {
+			return false, nil, nil
+		}
+		statusChanges[id] = [2]string{previous, req.Status}
+		return true, nil, nil
+	case BulkAddTags, BulkRemoveTags:
+		if err := requireEntry(tx, id); err != nil {
+			return false, nil, err
+		}
+		var n int
+		var err error
+		if req.Action == BulkAddTags {
+			n, err = addEntryTags(tx, []int{id}, tagIDs)
+		} else {
+			n, err = removeEntryTags(tx, []int{id}, tagIDs)
+		}
+		return n > 0, nil, err
+	case BulkDelete:
+		res, err := tx.Exec(`DELETE FROM data_entries WHERE id = $1`, id)
+		if err != nil {
+			return false, nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return false, nil, ErrEntryNotFound
+		}
+		return true, nil, nil
+	}
+	return false, nil, fmt.Errorf("%w: unknown action %q", ErrInvalidBulkRequest, req.Action)
+}
+
+func requireEntry(tx *sql.Tx, id int) error {
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM data_entries WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+func bulkErrorMessage(err error) string {
+	if err == sql.ErrNoRows {
+		return ErrEntryNotFound.Error()
+	}
+	return err.Error()
+}
+
+/*Bu fonksiyon, filtreye uyan kayıtların ID'lerini transaction içinde seçer. Sonuç
+maxBulkEntries değerini aşarsa, kullanıcının yanlışlıkla çok geniş bir filtreyle tüm
+veriyi değiştirmesini önlemek için hata döner.
+*/
+func selectEntryIDs(tx *sql.Tx, filter EntryFilter) ([]int, error) {
+	q, err := buildEntryQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query("SELECT e.id"+q.from+q.whereClause()+
+		fmt.Sprintf("\n\t\tORDER BY e.id\n\t\tLIMIT %d", maxBulkEntries+1), q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > maxBulkEntries {
+		return nil, fmt.Errorf("%w: filter matches more than %d entries", ErrInvalidBulkRequest, maxBulkEntries)
+	}
+	return ids, rows.Err()
+}
+
+func (s *DataService) emitStatusChanges(changes map[int][2]string, actor string) {
+	if s.emitter == nil || len(changes) == 0 {
+		return
+	}
+	ids := make([]int64, 0, len(changes))
+	for id := range changes {
+		ids = append(ids, int64(id))
+	}
+	rows, err := s.db.Query(`
+		SELECT `+entryColumns+entryFrom+`
+		WHERE e.id = ANY($1::int[])
+	`, pq.Array(ids))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return
+		}
+		change := changes[entry.ID]
+		s.emitter.Emit(triageEvent(siem.EventStatusChange, &entry, change[0], change[1], actor))
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateBulkRequest(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	ids := []int{1, 2, 3}
+
+	tests := []struct {
+		name    string
+		req     BulkRequest
+		wantErr bool
+	}{
+		{"set category", BulkRequest{IDs: ids, Action: BulkSetCategory, Category: "Data Leak"}, false},
+		{"set category by filter", BulkRequest{Filter: &EntryFilter{Category: "Spam"}, Action: BulkSetCategory, Category: "Other"}, false},
+		{"set category without category", BulkRequest{IDs: ids, Action: BulkSetCategory}, true},
+		{"set criticality", BulkRequest{IDs: ids, Action: BulkSetCriticality, Criticality: intPtr(0)}, false},
+		{"set criticality upper bound", BulkRequest{IDs: ids, Action: BulkSetCriticality, Criticality: intPtr(100)}, false},
+		{"set criticality missing", BulkRequest{IDs: ids, Action: BulkSetCriticality}, true},
+		{"set criticality negative", BulkRequest{IDs: ids, Action: BulkSetCriticality, Criticality: intPtr(-1)}, true},
+		{"set criticality too high", BulkRequest{IDs: ids, Action: BulkSetCriticality, Criticality: intPtr(101)}, true},
+		{"set status", BulkRequest{IDs: ids, Action: BulkSetStatus, Status: TriageInReview}, false},
+		{"set unknown status", BulkRequest{IDs: ids, Action: BulkSetStatus, Status: "done-ish"}, true},
+		{"add tags by id", BulkRequest{IDs: ids, Action: BulkAddTags, TagIDs: []int{4}}, false},
+		{"remove tags by name", BulkRequest{IDs: ids, Action: BulkRemoveTags, TagNames: []string{"apt"}}, false},
+		{"add tags without tags", BulkRequest{IDs: ids, Action: BulkAddTags}, true},
+		{"delete", BulkRequest{IDs: ids, Action: BulkDelete}, false},
+		{"unknown action", BulkRequest{IDs: ids, Action: "archive"}, true},
+		{"empty action", BulkRequest{IDs: ids}, true},
+		{"no target", BulkRequest{Action: BulkDelete}, true},
+		{"ids and filter", BulkRequest{IDs: ids, Filter: &EntryFilter{}, Action: BulkDelete}, true},
+		{"at the limit", BulkRequest{IDs: make([]int, maxBulkEntries), Action: BulkDelete}, false},
+		{"over the limit", BulkRequest{IDs: make([]int, maxBulkEntries+1), Action: BulkDelete}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBulkRequest(tt.req)
+			if tt.wantErr && !errors.Is(err, ErrInvalidBulkRequest) {
+				t.Errorf("error = %v, want ErrInvalidBulkRequest", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"interactive-scraper/internal/database"
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+/*Bu yapı (EntryFilter), kayıt listesinde uygulanabilecek tüm filtreleri ve sıralama
+tercihini bir arada tutar. Boş bırakılan alanlar filtre uygulanmadığı anlamına gelir;
+işaretçi tipli alanlar sayesinde "0" veya "false" gibi değerler de bilinçli olarak
+filtrelenebilir. JSON etiketleri, filtrenin kayıtlı aramalar gibi yerlerde olduğu gibi
+saklanabilmesini sağlar.
+*/
+type EntryFilter struct {
+	Category       string     `json:"category,omitempty"`
+	Search         string     `json:"search,omitempty"`
+	MinCriticality *int       `json:"min_criticality,omitempty"`
+	MaxCriticality *int       `json:"max_criticality,omitempty"`
+	SourceIDs      []int      `json:"source_ids,omitempty"`
+	ShareDateFrom  *time.Time `json:"share_date_from,omitempty"`
+	ShareDateTo    *time.Time `json:"share_date_to,omitempty"`
+	CreatedFrom    *time.Time `json:"created_from,omitempty"`
+	CreatedTo      *time.Time `json:"created_to,omitempty"`
+	HasAIAnalysis  *bool      `json:"has_ai_analysis,omitempty"`
+	TriageStatuses []string   `json:"triage_status,omitempty"`
+	AssigneeID     *int       `json:"assignee_id,omitempty"` // 0 matches unassigned entries
+	Tags           []string   `json:"tags,omitempty"`        // entry must carry every listed tag
+	Sort           string     `json:"sort,omitempty"`  // created_at, criticality, share_date, title, relevance
+	Order          string     `json:"order,omitempty"` // asc, desc
+
+	// matchedIn restricts results to entries recorded by a saved search run
+	matchedIn *savedSearchRun
+}
+
+/*Bu yapı (PageRequest), sayfalama isteğini temsil eder. Cursor boşsa klasik
+page/pageSize (OFFSET) sayfalaması kullanılır; doluysa bir önceki yanıtta dönen
+next_cursor değerinden itibaren keyset sayfalaması yapılır ve Page yok sayılır.
+*/
+type PageRequest struct {
+	Page     int
+	PageSize int
+	Cursor   string
+}
+
+type EntryPage struct {
+	Entries    []DataEntry `json:"entries"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// entrySortColumns maps sort names to the expression used for ordering and its SQL type
+var entrySortColumns = map[string]struct {
+	expr    string
+	sqlType string
+}{
+	"created_at":  {"e.created_at", "timestamp"},
+	"criticality": {"e.criticality_score", "integer"},
+	"share_date":  {"COALESCE(e.share_date, '-infinity'::timestamp)", "timestamp"},
+	"title":       {"e.title", "text"},
+	"relevance":   {"ts_rank_cd(e.search_vector, q)::float8", "float8"},
+}
+
+type entryCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Key   string `json:"k"`
+	ID    int    `json:"id"`
+}
+
+func encodeEntryCursor(c entryCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeEntryCursor(s string) (*entryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c entryCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+/*Bu yapı (entryQuery), EntryFilter'dan üretilen SQL parçalarını ve parametreleri tutar.
+FROM ve WHERE kısımları hem listeleme hem sayım hem de filtreye göre toplu işlem
+gibi sorgularda aynen kullanılabilir; parametre numaraları arg metodu ile sırayla
+verildiği için parçalar birleştirilirken numaralar karışmaz.
+*/
+type entryQuery struct {
+	args    []interface{}
+	from    string
+	where   []string
+	tsQuery string
+	langArg string
+}
+
+func (q *entryQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *entryQuery) whereClause() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return "\n\t\tWHERE " + strings.Join(q.where, "\n\t\t  AND ")
+}
+
+/*Bu fonksiyon, filtreyi doğrular ve SQL parçalarına çevirir. Kritiklik aralığı 0-100
+dışında ya da min > max ise, tarih aralıkları ters verilmişse hata döner. Arama metni
+varsa tam metin arama sorgusu FROM kısmına "q" adıyla eklenir; böylece hem WHERE
+koşulu hem de alaka puanı aynı tsquery üzerinden hesaplanır.
+*/
+func buildEntryQuery(f EntryFilter) (*entryQuery, error) {
+	q := &entryQuery{
+		from: entryFrom,
+	}
+
+	tsQuery, err := ParseSearchQuery(f.Search)
+	if err != nil {
+		return nil, err
+	}
+	if tsQuery != "" {
+		q.tsQuery = tsQuery
+		q.langArg = q.arg(database.SearchLanguage())
+		q.from += fmt.Sprintf(",\n\t\t     to_tsquery(%s::regconfig, %s) q", q.langArg, q.arg(tsQuery))
+		q.where = append(q.where, "e.search_vector @@ q")
+	}
+
+	if f.Category != "" {
+		q.where = append(q.where, "e.category = "+q.arg(f.Category))
+	}
+
+	if f.MinCriticality != nil && (*f.MinCriticality < 0 || *f.MinCriticality > 100) {
+		return nil, fmt.Errorf("%w: min_criticality must be between 0 and 100", ErrInvalidFilter)
+	}
+	if f.MaxCriticality != nil && (*f.MaxCriticality < 0 || *f.MaxCriticality > 100) {
+		return nil, fmt.Errorf("%w: max_criticality must be between 0 and 100", ErrInvalidFilter)
+	}
+	if f.MinCriticality != nil && f.MaxCriticality != nil && *f.MinCriticality > *f.MaxCriticality {
+		return nil, fmt.Errorf("%w: min_criticality cannot be greater than max_criticality", ErrInvalidFilter)
+	}
+	if f.MinCriticality != nil {
+		q.where = append(q.where, "e.criticality_score >= "+q.arg(*f.MinCriticality))
+	}
+	if f.MaxCriticality != nil {
+		q.where = append(q.where, "e.criticality_score <= "+q.arg(*f.MaxCriticality))
+	}
+
+	if len(f.SourceIDs) > 0 {
+		ids := make([]int64, len(f.SourceIDs))
+		for i, id := range f.SourceIDs {
+			ids[i] = int64(id)
+		}
+		q.where = append(q.where, "e.source_id = ANY("+q.arg(pq.Array(ids))+"::int[])")
+	}
+
+	if err := addTimeRange(q, "e.share_date", "share_date", f.ShareDateFrom, f.ShareDateTo); err != nil {
+		return nil, err
+	}
+	if err := addTimeRange(q, "e.created_at", "created", f.CreatedFrom, f.CreatedTo); err != nil {
+		return nil, err
+	}
+
+	if len(f.TriageStatuses) > 0 {
+		for _, status := range f.TriageStatuses {
+			if !IsValidTriageStatus(status) {
+				return nil, fmt.Errorf("%w: unknown triage status %s", ErrInvalidFilter, status)
+			}
+		}
+		q.where = append(q.where, "e.triage_status = ANY("+q.arg(pq.Array(f.TriageStatuses))+"::text[])")
+	}
+	if f.AssigneeID != nil {
+		if *f.AssigneeID == 0 {
+			q.where = append(q.where, "e.assignee_id IS NULL")
+		} else {
+			q.where = append(q.where, "e.assignee_id = "+q.arg(*f.AssigneeID))
+		}
+	}
+
+	for _, tag := range f.Tags {
+		q.where = append(q.where, `EXISTS (
+			SELECT 1 FROM entry_tags et JOIN tags t ON t.id = et.tag_id
+			WHERE et.entry_id = e.id AND LOWER(t.name) = LOWER(`+q.arg(tag)+`))`)
+	}
+
+	if f.matchedIn != nil {
+		q.where = append(q.where, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM saved_search_matches m
+			WHERE m.entry_id = e.id AND m.saved_search_id = %s AND m.matched_at = %s)`,
+			q.arg(f.matchedIn.searchID), q.arg(f.matchedIn.at)))
+	}
+
+	if f.HasAIAnalysis != nil {
+		if *f.HasAIAnalysis {
+			q.where = append(q.where, "(e.ai_analysis IS NOT NULL AND e.ai_analysis != '')")
+		} else {
+			q.where = append(q.where, "(e.ai_analysis IS NULL OR e.ai_analysis = '')")
+		}
+	}
+
+	return q, nil
+}
+
+func addTimeRange(q *entryQuery, column, name string, from, to *time.Time) error {
+	if from != nil && to != nil && from.After(*to) {
+		return fmt.Errorf("%w: %s_from cannot be after %s_to", ErrInvalidFilter, name, name)
+	}
+	if from != nil {
+		q.where = append(q.where, column+" >= "+q.arg(*from))
+	}
+	if to != nil {
+		q.where = append(q.where, column+" <= "+q.arg(*to))
+	}
+	return nil
+}
+
+// resolveSort returns the effective sort name and direction for a filter
+func resolveSort(f EntryFilter, hasSearch bool) (string, string, error) {
+	sortName := f.Sort
+	if sortName == "" {
+		sortName = "created_at"
+		if hasSearch {
+			sortName = "relevance"
+		}
+	}
+	if _, ok := entrySortColumns[sortName]; !ok {
+		return "", "", fmt.Errorf("%w: unknown sort field %s", ErrInvalidFilter, sortName)
+	}
+	if sortName == "relevance" && !hasSearch {
+		return "", "", fmt.Errorf("%w: sort by relevance requires a search query", ErrInvalidFilter)
+	}
+
+	order := strings.ToLower(f.Order)
+	switch order {
+	case "":
+		order = "desc"
+		if sortName == "title" {
+			order = "asc"
+		}
+	case "asc", "desc":
+	default:
+		return "", "", fmt.Errorf("%w: unknown sort order %s", ErrInvalidFilter, f.Order)
+	}
+	return sortName, order, nil
+}
+
+// entryColumns is the column list scanned by scanEntry, selected from entryFrom.
+// source_id is nullable; entries without a source come back with source 0 and an empty name and URL.
+const entryColumns = `e.id, COALESCE(e.source_id, 0), COALESCE(s.name, ''), COALESCE(s.url, ''), e.title, e.cleaned_content,
+		       e.share_date, e.criticality_score, e.category, e.ai_analysis, e.created_at,
+		       e.triage_status, e.assignee_id, au.username, e.triage_updated_at,
+		       (` + entryTagsSubquery + `)`
+
+const entryFrom = `
+		FROM data_entries e
+		LEFT JOIN sources s ON s.id = e.source_id
+		LEFT JOIN users au ON au.id = e.assignee_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+/*Bu fonksiyon, entryColumns sırasıyla seçilmiş bir satırı DataEntry yapısına okur.
+extra ile verilen hedefler, standart sütunlardan sonra gelen ek sütunlar (ör. arama
+puanı) için kullanılır. NULL olabilen share_date, ai_analysis ve atanan kullanıcı
+alanları burada işaretçilere dönüştürülür.
+*/
+func scanEntry(row rowScanner, extra ...interface{}) (DataEntry, error) {
+	var entry DataEntry
+	var shareDate sql.NullTime
+	var aiAnalysis, assignee sql.NullString
+	var assigneeID sql.NullInt64
+	var triageUpdatedAt sql.NullTime
+	var tags []byte
+	dest := append([]interface{}{
+		&entry.ID, &entry.SourceID, &entry.SourceName, &entry.SourceURL,
+		&entry.Title, &entry.CleanedContent, &shareDate,
+		&entry.CriticalityScore, &entry.Category, &aiAnalysis, &entry.CreatedAt,
+		&entry.TriageStatus, &assigneeID, &assignee, &triageUpdatedAt, &tags,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return entry, err
+	}
+	if shareDate.Valid {
+		entry.ShareDate = &shareDate.Time
+	}
+	if aiAnalysis.Valid && aiAnalysis.String != "" {
+		entry.AIAnalysis = &aiAnalysis.String
+	}
+	if assigneeID.Valid {
+		id := int(assigneeID.Int64)
+		entry.AssigneeID = &id
+		entry.Assignee = &assignee.String
+	}
+	if triageUpdatedAt.Valid {
+		entry.TriageUpdatedAt = &triageUpdatedAt.Time
+	}
+	if err := json.Unmarshal(tags, &entry.Tags); err != nil {
+		return entry, fmt.Errorf("decode entry tags: %w", err)
+	}
+	return entry, nil
+}
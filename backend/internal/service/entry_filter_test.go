@@ -0,0 +1,129 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEntryCursorRoundTrip(t *testing.T) {
+	tests := []entryCursor{
+		{Sort: "created_at", Order: "desc", Key: "2024-06-01T12:00:00.123456Z", ID: 42},
+		{Sort: "criticality", Order: "asc", Key: "85", ID: 1},
+		{Sort: "share_date", Order: "desc", Key: "-infinity", ID: 7},
+		{Sort: "title", Order: "asc", Key: `LockBit "leak" & co / ürün`, ID: 1 << 30},
+		{Sort: "relevance", Order: "desc", Key: "0.0607927", ID: 3},
+		{Sort: "title", Order: "asc", Key: "", ID: 9},
+	}
+	for _, want := range tests {
+		t.Run(want.Sort+"_"+want.Order, func(t *testing.T) {
+			encoded := encodeEntryCursor(want)
+			if _, err := base64.RawURLEncoding.DecodeString(encoded); err != nil {
+				t.Fatalf("cursor %q is not URL-safe base64: %v", encoded, err)
+			}
+			got, err := decodeEntryCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeEntryCursor(%q) error: %v", encoded, err)
+			}
+			if *got != want {
+				t.Errorf("round trip = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeEntryCursorRejectsGarbage(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"padded standard base64", base64.StdEncoding.EncodeToString([]byte(`{"s":"title"}`)) + "="},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("hello"))},
+		{"wrong json type", base64.RawURLEncoding.EncodeToString([]byte(`{"id":"seven"}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeEntryCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeEntryCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
+
+func TestResolveSort(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    EntryFilter
+		hasSearch bool
+		sort      string
+		order     string
+		wantErr   bool
+	}{
+		{"default", EntryFilter{}, false, "created_at", "desc", false},
+		{"default when searching", EntryFilter{}, true, "relevance", "desc", false},
+		{"title defaults to ascending", EntryFilter{Sort: "title"}, false, "title", "asc", false},
+		{"order is case insensitive", EntryFilter{Sort: "criticality", Order: "ASC"}, false, "criticality", "asc", false},
+		{"relevance needs a search", EntryFilter{Sort: "relevance"}, false, "", "", true},
+		{"unknown sort", EntryFilter{Sort: "id; DROP TABLE entries"}, false, "", "", true},
+		{"unknown order", EntryFilter{Order: "sideways"}, false, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, order, err := resolveSort(tt.filter, tt.hasSearch)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidFilter) {
+					t.Errorf("error = %v, want ErrInvalidFilter", err)
+				}
+				return
+			}
+			if err != nil || sort != tt.sort || order != tt.order {
+				t.Errorf("resolveSort = %q, %q, %v, want %q, %q", sort, order, err, tt.sort, tt.order)
+			}
+		})
+	}
+}
+
+// Without Postgres this checks the SQL shape: an inner join on sources would drop entries with a NULL source_id
+func TestEntryQueryKeepsEntriesWithoutSource(t *testing.T) {
+	for _, column := range []string{"COALESCE(e.source_id, 0)", "COALESCE(s.name, '')", "COALESCE(s.url, '')"} {
+		if !strings.Contains(entryColumns, column) {
+			t.Errorf("entryColumns does not select %s", column)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter EntryFilter
+	}{
+		{"no filter", EntryFilter{}},
+		{"search", EntryFilter{Search: "ransomware"}},
+		{"category and score", EntryFilter{Category: "leak", MinCriticality: intPtr(50), Tags: []string{"apt"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := buildEntryQuery(tt.filter)
+			if err != nil {
+				t.Fatalf("buildEntryQuery error: %v", err)
+			}
+			// The count and export queries reuse q.from, so they keep the same rows
+			if !strings.Contains(q.from, "LEFT JOIN sources s ON s.id = e.source_id") {
+				t.Errorf("from clause does not LEFT JOIN sources:%s", q.from)
+			}
+			if strings.Contains(strings.Replace(q.from, "LEFT JOIN sources", "", 1), "JOIN sources") {
+				t.Errorf("from clause still inner joins sources:%s", q.from)
+			}
+			for _, clause := range q.where {
+				if sourceColumn.MatchString(clause) {
+					t.Errorf("where clause %q filters on the source row", clause)
+				}
+			}
+		})
+	}
+}
+
+var sourceColumn = regexp.MustCompile(`\bs\.`)
+
+func intPtr(v int) *int { return &v }
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExportFormat = errors.New("invalid export format")
+
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+	ExportSTIX = "stix"
+)
+
+// exportPageSize is how many entries are read per query while streaming an export
+const exportPageSize = 500
+
+var exportCSVHeader = []string{"id", "source_id", "source_name", "source_url", "title", "category",
+	"criticality_score", "share_date", "created_at", "triage_status", "assignee", "tags", "ai_analysis", "content"}
+
+// IsValidExportFormat reports whether ExportEntries accepts format
+func IsValidExportFormat(format string) bool {
+	return format == ExportJSON || format == ExportCSV || format == ExportSTIX
+}
+
+/*Bu fonksiyon, filtreye uyan kayıtları verilen formatta w'ye yazar ve yazılan kayıt
+sayısını döndürür. Kayıtlar oluşturulma zamanına göre eskiden yeniye, keyset
+sayfalamasıyla parça parça okunur; böylece büyük dışa aktarımlar belleğe tamamen
+alınmadan akıtılır. Sayfalar GetAllEntries yerine buildExportQuery ile okunur: toplam
+sayım ve arama vurguları dışa aktarımda kullanılmadığından her sayfada tüm tabloyu
+sayan COUNT(*) ve ts_headline çalıştırılmaz. json bir kayıt dizisi, csv başlık satırlı
+bir tablo, stix ise STIX 2.1 bundle'ı üretir.
+*/
+func (s *DataService) ExportEntries(w io.Writer, format string, filter EntryFilter) (int, error) {
+	var writer entryWriter
+	switch format {
+	case ExportJSON:
+		writer = &jsonEntryWriter{w: w}
+	case ExportCSV:
+		writer = &csvEntryWriter{w: csv.NewWriter(w)}
+	case ExportSTIX:
+		writer = &stixEntryWriter{w: w, sources: map[string]string{}}
+	default:
+		return 0, fmt.Errorf("%w: %q (expected json, csv or stix)", ErrInvalidExportFormat, format)
+	}
+
+	if err := writer.begin(); err != nil {
+		return 0, err
+	}
+	count := 0
+	var after *exportKey
+	for {
+		query, args, err := buildExportQuery(filter, after)
+		if err != nil {
+			return count, err
+		}
+		n, last, err := s.exportPage(writer, query, args)
+		count += n
+		if err != nil {
+			return count, err
+		}
+		if n < exportPageSize {
+			break
+		}
+		after = &last
+	}
+	return count, writer.end()
+}
+
+// exportKey is the created_at (as text, like the entry cursor) and id of the last exported entry
+type exportKey struct {
+	createdAt string
+	id        int
+}
+
+// buildExportQuery selects the next exportPageSize entries after the given key, oldest first
+func buildExportQuery(filter EntryFilter, after *exportKey) (string, []interface{}, error) {
+	q, err := buildEntryQuery(filter)
+	if err != nil {
+		return "", nil, err
+	}
+	sortColumn := entrySortColumns["created_at"]
+	if after != nil {
+		q.where = append(q.where, fmt.Sprintf("(%s, e.id) > (%s::%s, %s)",
+			sortColumn.expr, q.arg(after.createdAt), sortColumn.sqlType, q.arg(after.id)))
+	}
+	query := "\n\t\tSELECT " + entryColumns + ",\n\t\t       (" + sortColumn.expr + ")::text" +
+		q.from + q.whereClause() +
+		fmt.Sprintf("\n\t\tORDER BY %s, e.id", sortColumn.expr) +
+		fmt.Sprintf("\n\t\tLIMIT %s", q.arg(exportPageSize))
+	return query, q.args, nil
+}
+
+// exportPage writes one page and returns how many entries it had and the key of the last one
+func (s *DataService) exportPage(writer entryWriter, query string, args []interface{}) (int, exportKey, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return 0, exportKey{}, err
+	}
+	defer rows.Close()
+
+	n := 0
+	var last exportKey
+	for rows.Next() {
+		entry, err := scanEntry(rows, &last.createdAt)
+		if err != nil {
+			return n, last, err
+		}
+		if err := writer.write(entry); err != nil {
+			return n, last, err
+		}
+		last.id = entry.ID
+		n++
+	}
+	return n, last, rows.Err()
+}
+
+type entryWriter interface {
+	begin() error
+	write(entry DataEntry) error
+	end() error
+}
+
+type jsonEntryWriter struct {
+	w     io.Writer
+	wrote bool
+}
+
+func (j *jsonEntryWriter) begin() error {
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonEntryWriter) write(entry DataEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	sep := "\n"
+	if j.wrote {
+		sep = ",\n"
+	}
+	j.wrote = true
+	_, err = io.WriteString(j.w, sep+string(data))
+	return err
+}
+
+func (j *jsonEntryWriter) end() error {
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+type csvEntryWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvEntryWriter) begin() error {
+	return c.w.Write(exportCSVHeader)
+}
+
+func (c *csvEntryWriter) write(entry DataEntry) error {
+	tags := make([]string, len(entry.Tags))
+	for i, tag := range entry.Tags {
+		tags[i] = tag.Name
+	}
+	return c.w.Write([]string{
+		strconv.Itoa(entry.ID),
+		strconv.Itoa(entry.SourceID),
+		csvText(entry.SourceName),
+		csvText(entry.SourceURL),
+		csvText(entry.Title),
+		csvText(entry.Category),
+		strconv.Itoa(entry.CriticalityScore),
+		formatOptionalTime(entry.ShareDate),
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+		entry.TriageStatus,
+		csvText(derefString(entry.Assignee)),
+		csvText(strings.Join(tags, ";")),
+		csvText(derefString(entry.AIAnalysis)),
+		csvText(entry.CleanedContent),
+	})
+}
+
+// csvText prefixes scraped text that a spreadsheet would run as a formula with a quote, so it stays text
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvEntryWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// stixNamespace is the UUIDv5 namespace the STIX 2.1 spec defines for deterministic identifiers
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// stixProducerCreated is fixed so every export carries the same version of the producer identity
+var stixProducerCreated = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+/*Bu yapı (stixEntryWriter), kayıtları STIX 2.1 bundle'ı olarak yazar. Her kayıt bir
+"report" nesnesine, kaydın kaynağının adresi ise bir "url" gözlemlenebilir nesnesine
+dönüşür ve report bu nesneye object_refs ile bağlanır. Kimlikler kayıt ve kaynak
+adresinden UUIDv5 ile türetildiği için aynı kayıt her dışa aktarımda aynı STIX
+kimliğini alır; alıcı sistemler tekrar içe aktarmada kopya oluşturmaz, nesneyi günceller.
+*/
+type stixEntryWriter struct {
+	w        io.Writer
+	producer string
+	wrote    bool
+	sources  map[string]string // source URL -> url object id
+}
+
+func (s *stixEntryWriter) begin() error {
+	bundleID, err := randomUUID()
+	if err != nil {
+		return err
+	}
+	s.producer = stixID("identity", `{"name":"interactive-scraper"}`)
+	if _, err := io.WriteString(s.w, `{"type":"bundle","id":"bundle--`+bundleID+`","objects":[`); err != nil {
+		return err
+	}
+	return s.object(map[string]interface{}{
+		"type":           "identity",
+		"spec_version":   "2.1",
+		"id":             s.producer,
+		"created":        stixTime(stixProducerCreated),
+		"modified":       stixTime(stixProducerCreated),
+		"name":           "Interactive Scraper",
+		"identity_class": "system",
+	})
+}
+
+func (s *stixEntryWriter) object(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sep := "\n"
+	if s.wrote {
+		sep = ",\n"
+	}
+	s.wrote = true
+	_, err = io.WriteString(s.w, sep+string(data))
+	return err
+}
+
+func (s *stixEntryWriter) write(entry DataEntry) error {
+	urlID, ok := s.sources[entry.SourceURL]
+	if !ok {
+		value, _ := json.Marshal(map[string]string{"value": entry.SourceURL})
+		urlID = stixID("url", string(value))
+		s.sources[entry.SourceURL] = urlID
+		if err := s.object(map[string]interface{}{
+			"type":         "url",
+			"spec_version": "2.1",
+			"id":           urlID,
+			"value":        entry.SourceURL,
+		}); err != nil {
+			return err
+		}
+	}
+
+	published := entry.CreatedAt
+	if entry.ShareDate != nil {
+		published = *entry.ShareDate
+	}
+	modified := entry.CreatedAt
+	if entry.TriageUpdatedAt != nil && entry.TriageUpdatedAt.After(modified) {
+		modified = *entry.TriageUpdatedAt
+	}
+	labels := []string{}
+	if entry.Category != "" {
+		labels = append(labels, entry.Category)
+	}
+	for _, tag := range entry.Tags {
+		labels = append(labels, tag.Name)
+	}
+
+	report := map[string]interface{}{
+		"type":           "report",
+		"spec_version":   "2.1",
+		"id":             stixID("report", fmt.Sprintf(`{"entry_id":%d}`, entry.ID)),
+		"created_by_ref": s.producer,
+		"created":        stixTime(entry.CreatedAt),
+		"modified":       stixTime(modified),
+		"name":           entry.Title,
+		"description":    entry.CleanedContent,
+		"published":      stixTime(published),
+		"report_types":   []string{"threat-report"},
+		"object_refs":    []string{urlID},
+		"external_references": []map[string]string{
+			{"source_name": entry.SourceName, "url": entry.SourceURL},
+		},
+		"x_criticality_score": entry.CriticalityScore,
+		"x_triage_status":     entry.TriageStatus,
+	}
+	if len(labels) > 0 {
+		report["labels"] = labels
+	}
+	if entry.AIAnalysis != nil && *entry.AIAnalysis != "" {
+		report["x_ai_analysis"] = *entry.AIAnalysis
+	}
+	return s.object(report)
+}
+
+func (s *stixEntryWriter) end() error {
+	_, err := io.WriteString(s.w, "\n]}\n")
+	return err
+}
+
+func stixTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// stixID builds a deterministic "<type>--<uuidv5>" identifier from the object's identifying properties
+func stixID(objectType, name string) string {
+	h := sha1.New()
+	h.Write(stixNamespace[:])
+	h.Write([]byte(name))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+	return objectType + "--" + formatUUID(u)
+}
+
+func randomUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u), nil
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"LockBit leak", "LockBit leak"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1 555", "'+1 555"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := csvText(tt.in); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCSVEntryWriterNeutralisesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w := &csvEntryWriter{w: csv.NewWriter(&buf)}
+	entry := DataEntry{ID: 1, SourceID: 2, Title: "=cmd|'/c calc'!A1", CleanedContent: "@import", CreatedAt: time.Unix(0, 0)}
+	if err := w.write(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.end(); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := csv.NewReader(&buf).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record[4] != "'=cmd|'/c calc'!A1" || record[13] != "'@import" {
+		t.Errorf("formula cells were not neutralised: title %q, content %q", record[4], record[13])
+	}
+	if record[0] != "1" || record[1] != "2" {
+		t.Errorf("numeric cells changed: %q, %q", record[0], record[1])
+	}
+}
+
+func TestBuildExportQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    EntryFilter
+		after     *exportKey
+		wantWhere string
+	}{
+		{"first page", EntryFilter{}, nil, ""},
+		{"next page", EntryFilter{}, &exportKey{createdAt: "2024-06-01 12:00:00.123456", id: 42},
+			"(e.created_at, e.id) > ($1::timestamp, $2)"},
+		{"search skips highlighting", EntryFilter{Search: "lockbit"}, &exportKey{createdAt: "2024-06-01 12:00:00", id: 7},
+			"(e.created_at, e.id) > ($3::timestamp, $4)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := buildExportQuery(tt.filter, tt.after)
+			if err != nil {
+				t.Fatalf("buildExportQuery error: %v", err)
+			}
+			for _, unwanted := range []string{"COUNT(", "ts_headline", "ts_rank", "OFFSET"} {
+				if strings.Contains(query, unwanted) {
+					t.Errorf("export query contains %s:%s", unwanted, query)
+				}
+			}
+			if tt.wantWhere != "" && !strings.Contains(query, tt.wantWhere) {
+				t.Errorf("export query is missing %q:%s", tt.wantWhere, query)
+			}
+			if !strings.Contains(query, "ORDER BY e.created_at, e.id") {
+				t.Errorf("export query is not ordered oldest first:%s", query)
+			}
+			limit := fmt.Sprintf("LIMIT $%d", len(args))
+			if !strings.HasSuffix(query, limit) || args[len(args)-1] != exportPageSize {
+				t.Errorf("export query should end with %s bound to %d, got args %v:%s", limit, exportPageSize, args, query)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"interactive-scraper/internal/config"
+)
+
+/*Bu yapı (ThrottleConfig), giriş denemesi sınırlama ayarlarını tutar. Bir kullanıcı adı
+için MaxAttempts, bir IP adresi için MaxAttemptsPerIP başarısız denemeden sonra anahtar
+kilitlenir. Kilit süresi LockoutBase ile başlar ve her yeni başarısız denemede iki katına
+çıkar (en fazla LockoutMax). FailureWindow boyunca başarısız deneme olmazsa sayaç sıfırlanır.
+*/
+type ThrottleConfig struct {
+	MaxAttempts      int
+	MaxAttemptsPerIP int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+	FailureWindow    time.Duration
+}
+
+// ThrottleConfigFrom maps the limits section of the central configuration, which is validated there
+func ThrottleConfigFrom(limits config.LimitsConfig) ThrottleConfig {
+	return ThrottleConfig{
+		MaxAttempts:      limits.LoginMaxAttempts,
+		MaxAttemptsPerIP: limits.LoginMaxAttemptsPerIP,
+		LockoutBase:      limits.LoginLockoutBase.Duration,
+		LockoutMax:       limits.LoginLockoutMax.Duration,
+		FailureWindow:    limits.LoginFailureWindow.Duration,
+	}
+}
+
+type attemptState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+/*Bu yapı (LoginThrottle), başarısız giriş denemelerini kullanıcı adı ve IP adresine göre
+bellekte sayar. Sayaçlar süreç yeniden başlatıldığında sıfırlanır; amaç kaba kuvvet
+denemelerini yavaşlatmaktır. Bilinmeyen kullanıcı adları da sayıldığı için kilitlenme
+davranışı kullanıcı adının var olup olmadığını ele vermez.
+*/
+type LoginThrottle struct {
+	config    ThrottleConfig
+	mu        sync.Mutex
+	attempts  map[string]*attemptState
+	lastSweep time.Time
+}
+
+func NewLoginThrottle(config ThrottleConfig) *LoginThrottle {
+	return &LoginThrottle{
+		config:   config,
+		attempts: map[string]*attemptState{},
+	}
+}
+
+func userKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns how long the caller must wait before another attempt, or 0 if allowed
+func (t *LoginThrottle) Check(username, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range []string{userKey(username), ipKey(ip)} {
+		if state, ok := t.attempts[key]; ok && state.lockedUntil.After(now) {
+			if d := state.lockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+/*Bu fonksiyon, başarısız bir denemeyi hem kullanıcı adı hem IP için kaydeder ve
+anahtarlardan biri kilitlendiyse kilidin süresini döner. Eşik aşıldıktan sonraki her
+başarısız deneme kilit süresini ikiye katlar: LockoutBase, 2×LockoutBase, 4×LockoutBase...
+*/
+func (t *LoginThrottle) Failure(username, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.sweep(now)
+
+	var lock time.Duration
+	for key, limit := range map[string]int{
+		userKey(username): t.config.MaxAttempts,
+		ipKey(ip):         t.config.MaxAttemptsPerIP,
+	} {
+		state, ok := t.attempts[key]
+		if !ok || state.idle(now, t.config.FailureWindow) {
+			state = &attemptState{}
+			t.attempts[key] = state
+		}
+		state.failures++
+		state.lastFailure = now
+
+		if state.failures >= limit {
+			d := t.lockoutDuration(state.failures - limit)
+			state.lockedUntil = now.Add(d)
+			if d > lock {
+				lock = d
+			}
+		}
+	}
+	return lock
+}
+
+// idle reports whether window has passed since the last failure or the end of the lockout
+func (s *attemptState) idle(now time.Time, window time.Duration) bool {
+	last := s.lastFailure
+	if s.lockedUntil.After(last) {
+		last = s.lockedUntil
+	}
+	return now.Sub(last) > window
+}
+
+// Success clears the username counter; the IP counter only decays with time
+func (t *LoginThrottle) Success(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.attempts, userKey(username))
+}
+
+func (t *LoginThrottle) lockoutDuration(excess int) time.Duration {
+	d := t.config.LockoutBase
+	for i := 0; i < excess && d < t.config.LockoutMax; i++ {
+		d *= 2
+	}
+	if d > t.config.LockoutMax {
+		d = t.config.LockoutMax
+	}
+	return d
+}
+
+// sweep drops idle entries so the map cannot grow without bound
+func (t *LoginThrottle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < time.Minute {
+		return
+	}
+	t.lastSweep = now
+	for key, state := range t.attempts {
+		if state.idle(now, t.config.FailureWindow) {
+			delete(t.attempts, key)
+		}
+	}
+}
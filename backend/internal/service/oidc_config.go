@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"interactive-scraper/internal/config"
+)
+
+/*Bu yapı (OIDCConfig), OpenID Connect ile tek oturum açma (SSO) ayarlarını tutar.
+IssuerURL boşsa SSO kapalıdır. RoleMapping, kimlik sağlayıcıdaki grup adından role
+eşlemedir; kullanıcının eşleşen grupları arasından en yetkili rol seçilir. Hiçbir grup
+eşleşmezse DefaultRole kullanılır; DefaultRole boşsa kullanıcının girişi reddedilir.
+*/
+type OIDCConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	ProviderName  string
+	UsernameClaim string
+	GroupsClaim   string
+	RoleMapping   map[string]string
+	DefaultRole   string
+}
+
+func (cfg OIDCConfig) Enabled() bool {
+	return cfg.IssuerURL != ""
+}
+
+/*Bu fonksiyon, config.OIDCConfig bölümünü SSO ayarlarına çevirir. oidc.role_mapping
+"grup:rol" çiftlerinin virgülle ayrılmış listesidir, ör. "cti-admins:admin,cti-team:analyst";
+grup adlarının büyük/küçük harfi korunur. İstemci gizli anahtarı oidc.client_secret veya
+oidc.client_secret_file ile verilebilir; gizli anahtar yoksa istemci, yalnızca PKCE
+kullanan public client olarak davranır. Adreslerin biçimi config.Validate'te, rollerin
+geçerliliği burada denetlenir.
+*/
+func OIDCConfigFrom(settings config.OIDCConfig) (OIDCConfig, error) {
+	cfg := OIDCConfig{
+		IssuerURL:     strings.TrimRight(settings.IssuerURL, "/"),
+		ClientID:      settings.ClientID,
+		ClientSecret:  settings.ClientSecret,
+		RedirectURL:   settings.RedirectURL,
+		Scopes:        strings.Fields(strings.ReplaceAll(settings.Scopes, ",", " ")),
+		ProviderName:  settings.ProviderName,
+		UsernameClaim: settings.UsernameClaim,
+		GroupsClaim:   settings.GroupsClaim,
+		RoleMapping:   map[string]string{},
+		DefaultRole:   settings.DefaultRole,
+	}
+	if !cfg.Enabled() {
+		return cfg, nil
+	}
+
+	if settings.ClientSecretFile != "" {
+		data, err := os.ReadFile(settings.ClientSecretFile)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read oidc.client_secret_file (OIDC_CLIENT_SECRET_FILE): %v", err)
+		}
+		cfg.ClientSecret = strings.TrimSpace(string(data))
+	}
+	for _, item := range strings.Split(settings.RoleMapping, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		// Group names may contain ":" (e.g. URNs), so the role is after the last one
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return cfg, fmt.Errorf("invalid oidc.role_mapping (OIDC_ROLE_MAPPING) entry %q (expected group:role)", item)
+		}
+		cfg.RoleMapping[strings.TrimSpace(item[:i])] = strings.TrimSpace(item[i+1:])
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (cfg OIDCConfig) Validate() error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	if u, err := url.Parse(cfg.IssuerURL); err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("invalid OIDC_ISSUER_URL: %s", cfg.IssuerURL)
+	}
+	if cfg.ClientID == "" {
+		return fmt.Errorf("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+	}
+	if u, err := url.Parse(cfg.RedirectURL); err != nil || !u.IsAbs() {
+		return fmt.Errorf("OIDC_REDIRECT_URL must be an absolute URL ending in /api/auth/oidc/callback, got %q", cfg.RedirectURL)
+	}
+	if !containsString(cfg.Scopes, "openid") {
+		return fmt.Errorf("OIDC_SCOPES must include openid")
+	}
+	for group, role := range cfg.RoleMapping {
+		if !IsValidRole(role) {
+			return fmt.Errorf("OIDC_ROLE_MAPPING: unknown role %q for group %q", role, group)
+		}
+	}
+	if cfg.DefaultRole != "" && !IsValidRole(cfg.DefaultRole) {
+		return fmt.Errorf("invalid OIDC_DEFAULT_ROLE: %s", cfg.DefaultRole)
+	}
+	return nil
+}
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrOIDCDisabled      = errors.New("single sign-on is not configured")
+	ErrOIDCState         = errors.New("invalid or expired single sign-on request, please try again")
+	ErrOIDCAccessDenied  = errors.New("your identity provider groups do not grant access to this application")
+	ErrOIDCUserConflict  = errors.New("a local account with this username already exists")
+	ErrInvalidLoginCode  = errors.New("invalid or expired login code")
+	errOIDCProviderReply = errors.New("identity provider returned an invalid response")
+)
+
+const (
+	// authProviderOIDC is the users.auth_provider value of single sign-on accounts
+	authProviderOIDC = "oidc"
+
+	oidcRequestTTL   = 10 * time.Minute
+	oidcLoginCodeTTL = time.Minute
+	oidcMetadataTTL  = time.Hour
+	// oidcMaxRequests caps pending logins so unauthenticated callers cannot grow the map without bound
+	oidcMaxRequests = 10000
+	// oidcKeyRefetchInterval limits JWKS refetches triggered by unknown key ids
+	oidcKeyRefetchInterval = time.Minute
+)
+
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcRequest is a login that was sent to the identity provider and has not come back yet
+type oidcRequest struct {
+	verifier string
+	nonce    string
+	expires  time.Time
+}
+
+type oidcLoginCode struct {
+	tokens  *TokenPair
+	expires time.Time
+}
+
+// OIDCIdentity is the user described by a verified ID token
+type OIDCIdentity struct {
+	UserID   int
+	Subject  string
+	Username string
+	Groups   []string
+	Role     string
+}
+
+type OIDCService struct {
+	db     *sql.DB
+	auth   *AuthService
+	config OIDCConfig
+	client *http.Client
+
+	mu           sync.Mutex
+	provider     *oidcProvider
+	providerTime time.Time
+	keys         map[string]interface{}
+	keysTime     time.Time
+	requests     map[string]oidcRequest
+	loginCodes   map[string]oidcLoginCode
+}
+
+func NewOIDCService(db *sql.DB, auth *AuthService, config OIDCConfig) *OIDCService {
+	return &OIDCService{
+		db:         db,
+		auth:       auth,
+		config:     config,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		requests:   map[string]oidcRequest{},
+		loginCodes: map[string]oidcLoginCode{},
+	}
+}
+
+func (s *OIDCService) Enabled() bool {
+	return s != nil && s.config.Enabled()
+}
+
+func (s *OIDCService) ProviderName() string {
+	return s.config.ProviderName
+}
+
+/*Bu fonksiyon, kimlik sağlayıcıya yönlendirilecek yetkilendirme (authorization code)
+URL'sini üretir. Her giriş için rastgele state, nonce ve PKCE code_verifier oluşturulur;
+code_verifier'ın SHA-256 özeti code_challenge olarak gönderilir. State, geri dönüşte
+isteği eşlemek için döndürülür ve handler tarafından tarayıcıya çerez olarak da yazılır.
+*/
+func (s *OIDCService) StartLogin() (authURL, state string, err error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+	provider, err := s.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	if state, err = randomToken(24); err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	s.mu.Lock()
+	s.sweepLocked()
+	if len(s.requests) >= oidcMaxRequests {
+		s.mu.Unlock()
+		return "", "", errors.New("too many pending single sign-on requests, please try again later")
+	}
+	s.requests[state] = oidcRequest{verifier: verifier, nonce: nonce, expires: time.Now().Add(oidcRequestTTL)}
+	s.mu.Unlock()
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", s.config.ClientID)
+	values.Set("redirect_uri", s.config.RedirectURL)
+	values.Set("scope", strings.Join(s.config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.AuthorizationEndpoint + separator + values.Encode(), state, nil
+}
+
+/*Bu fonksiyon, kimlik sağlayıcıdan dönen yetkilendirme kodunu PKCE code_verifier ile
+token'a çevirir, ID token'ı doğrular, kullanıcıyı ilk girişte otomatik oluşturur (JIT) ve
+bir oturum açar. Oturum token'ları URL'de taşınmasın diye doğrudan döndürülmez; bunun
+yerine bir dakika geçerli, tek kullanımlık bir giriş kodu döner ve frontend bu kodu
+ExchangeLoginCode ile token'lara çevirir. Kimlik bilgisi hata durumunda da denetim kaydı
+için döndürülür.
+*/
+func (s *OIDCService) CompleteLogin(state, code string, meta SessionMeta) (string, *OIDCIdentity, error) {
+	if !s.Enabled() {
+		return "", nil, ErrOIDCDisabled
+	}
+
+	s.mu.Lock()
+	request, ok := s.requests[state]
+	delete(s.requests, state)
+	s.mu.Unlock()
+	if !ok || time.Now().After(request.expires) || code == "" {
+		return "", nil, ErrOIDCState
+	}
+
+	provider, err := s.discover()
+	if err != nil {
+		return "", nil, err
+	}
+	idToken, accessToken, err := s.exchangeCode(provider, code, request.verifier)
+	if err != nil {
+		return "", nil, err
+	}
+	identity, err := s.verifyIDToken(provider, idToken, request.nonce, accessToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	identity.Role = s.mapRole(identity.Groups)
+	if identity.Role == "" {
+		return "", identity, ErrOIDCAccessDenied
+	}
+
+	user, err := s.provision(identity)
+	if err != nil {
+		return "", identity, err
+	}
+	tokens, err := s.auth.openSession(user, meta)
+	if err != nil {
+		return "", identity, err
+	}
+
+	loginCode, err := randomToken(32)
+	if err != nil {
+		return "", identity, err
+	}
+	s.mu.Lock()
+	s.loginCodes[hashToken(loginCode)] = oidcLoginCode{tokens: tokens, expires: time.Now().Add(oidcLoginCodeTTL)}
+	s.mu.Unlock()
+	return loginCode, identity, nil
+}
+
+// ExchangeLoginCode returns the session tokens of a finished SSO login, once
+func (s *OIDCService) ExchangeLoginCode(code string) (*TokenPair, error) {
+	key := hashToken(code)
+	s.mu.Lock()
+	login, ok := s.loginCodes[key]
+	delete(s.loginCodes, key)
+	s.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return nil, ErrInvalidLoginCode
+	}
+	return login.tokens, nil
+}
+
+func (s *OIDCService) sweepLocked() {
+	now := time.Now()
+	for state, request := range s.requests {
+		if now.After(request.expires) {
+			delete(s.requests, state)
+		}
+	}
+	for code, login := range s.loginCodes {
+		if now.After(login.expires) {
+			delete(s.loginCodes, code)
+		}
+	}
+}
+
+// discover loads the provider metadata from /.well-known/openid-configuration and caches it
+func (s *OIDCService) discover() (*oidcProvider, error) {
+	s.mu.Lock()
+	if s.provider != nil && time.Since(s.providerTime) < oidcMetadataTTL {
+		provider := s.provider
+		s.mu.Unlock()
+		return provider, nil
+	}
+	s.mu.Unlock()
+
+	var provider oidcProvider
+	if err := s.getJSON(s.config.IssuerURL+"/.well-known/openid-configuration", "", &provider); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimRight(provider.Issuer, "/") != s.config.IssuerURL {
+		return nil, fmt.Errorf("OIDC discovery failed: issuer %q does not match OIDC_ISSUER_URL", provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", errOIDCProviderReply)
+	}
+
+	s.mu.Lock()
+	s.provider = &provider
+	s.providerTime = time.Now()
+	s.mu.Unlock()
+	return &provider, nil
+}
+
+func (s *OIDCService) exchangeCode(provider *oidcProvider, code, verifier string) (idToken, accessToken string, err error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.config.RedirectURL)
+	form.Set("client_id", s.config.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(s.config.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("OIDC token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", "", fmt.Errorf("OIDC token request failed: %w", errOIDCProviderReply)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", "", fmt.Errorf("OIDC token request failed: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", "", fmt.Errorf("OIDC token response has no id_token: %w", errOIDCProviderReply)
+	}
+	return body.IDToken, body.AccessToken, nil
+}
+
+/*Bu fonksiyon, ID token'ın imzasını sağlayıcının JWKS anahtarlarıyla, iss, aud, exp ve
+nonce alanlarını da bu giriş isteğine göre doğrular. Grup veya kullanıcı adı claim'i
+ID token'da yoksa ve sağlayıcı userinfo uç noktası sunuyorsa bu bilgiler oradan okunur.
+*/
+func (s *OIDCService) verifyIDToken(provider *oidcProvider, idToken, nonce, accessToken string) (*OIDCIdentity, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(s.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.signingKey(provider, kid)
+	}); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if claimString(claims, "nonce") != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	if azp := claimString(claims, "azp"); azp != "" && azp != s.config.ClientID {
+		return nil, errors.New("invalid ID token: authorized party mismatch")
+	}
+	subject := claimString(claims, "sub")
+	if subject == "" {
+		return nil, errors.New("invalid ID token: missing sub")
+	}
+
+	_, hasGroups := claims[s.config.GroupsClaim]
+	if (!hasGroups || claimString(claims, s.config.UsernameClaim) == "") && provider.UserinfoEndpoint != "" && accessToken != "" {
+		userinfo := map[string]interface{}{}
+		if err := s.getJSON(provider.UserinfoEndpoint, accessToken, &userinfo); err == nil && claimString(userinfo, "sub") == subject {
+			for key, value := range userinfo {
+				if _, ok := claims[key]; !ok {
+					claims[key] = value
+				}
+			}
+		}
+	}
+
+	username := claimString(claims, s.config.UsernameClaim)
+	if username == "" {
+		username = claimString(claims, "email")
+	}
+	if username == "" {
+		username = subject
+	}
+
+	return &OIDCIdentity{
+		Subject:  subject,
+		Username: sanitizeUsername(username),
+		Groups:   claimStrings(claims[s.config.GroupsClaim]),
+	}, nil
+}
+
+// signingKey returns the provider key with the given kid, refetching the JWKS when it is unknown
+func (s *OIDCService) signingKey(provider *oidcProvider, kid string) (interface{}, error) {
+	s.mu.Lock()
+	keys, fetched := s.keys, s.keysTime
+	s.mu.Unlock()
+
+	if key := pickKey(keys, kid); key != nil {
+		return key, nil
+	}
+	if time.Since(fetched) < oidcKeyRefetchInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.getJSON(provider.JWKSURI, "", &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	keys = map[string]interface{}{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	s.mu.Lock()
+	s.keys, s.keysTime = keys, time.Now()
+	s.mu.Unlock()
+
+	if key := pickKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// pickKey finds a key by kid; tokens without a kid are accepted only if the set has a single key
+func pickKey(keys map[string]interface{}, kid string) interface{} {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return keys[kid]
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid JWK %q", k.Kid)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func (s *OIDCService) getJSON(endpoint, bearer string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// mapRole returns the strongest role mapped from the groups, or the default role
+func (s *OIDCService) mapRole(groups []string) string {
+	role := ""
+	for _, group := range groups {
+		if mapped, ok := s.config.RoleMapping[group]; ok && roleRank[mapped] > roleRank[role] {
+			role = mapped
+		}
+	}
+	if role == "" {
+		role = s.config.DefaultRole
+	}
+	return role
+}
+
+/*Bu fonksiyon, SSO kullanıcısını users tablosunda bulur veya ilk girişte oluşturur.
+Kullanıcı, kimlik sağlayıcıdaki değişmez sub değeriyle eşlenir; kullanıcı adı yalnızca
+ilk girişte belirlenir. Rol her girişte gruplardan yeniden hesaplanır, böylece rol
+yönetimi kimlik sağlayıcıda yapılır. Aynı adla yerel bir hesap varsa hesaplar otomatik
+birleştirilmez, ErrOIDCUserConflict döner. SSO hesaplarının kullanılabilir bir şifresi
+yoktur; şifreyle giriş yapamazlar.
+*/
+func (s *OIDCService) provision(identity *OIDCIdentity) (sessionUser, error) {
+	user := sessionUser{external: true}
+	err := s.db.QueryRow(`
+		UPDATE users SET role = $3, updated_at = CASE WHEN role = $3 THEN updated_at ELSE NOW() END
+		WHERE auth_provider = $1 AND external_id = $2
+		RETURNING id, username, role
+	`, authProviderOIDC, identity.Subject, identity.Role).Scan(&user.id, &user.username, &user.role)
+	if err == nil {
+		identity.UserID, identity.Username = user.id, user.username
+		return user, nil
+	}
+	if err != sql.ErrNoRows {
+		return user, err
+	}
+
+	err = s.db.QueryRow(`
+		INSERT INTO users (username, password_hash, role, auth_provider, external_id)
+		VALUES ($1, '!', $2, $3, $4)
+		RETURNING id, username, role
+	`, identity.Username, identity.Role, authProviderOIDC, identity.Subject).Scan(&user.id, &user.username, &user.role)
+	if isUniqueViolation(err) {
+		return user, ErrOIDCUserConflict
+	}
+	identity.UserID = user.id
+	return user, err
+}
+
+// sanitizeUsername maps an IdP username onto the characters allowed for local usernames
+func sanitizeUsername(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if invalidUsernameRune(r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	for len(name) < 3 {
+		name += "_"
+	}
+	return name
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// claimStrings reads a claim that may be a list of strings or a single space-separated string
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"interactive-scraper/internal/config"
+)
+
+/*Bu yapı (PasswordPolicy), yeni şifrelerin uyması gereken kuralları tutar. Şifre en az
+MinLength karakter olmalı ve RequiredClasses içindeki her karakter sınıfından (upper,
+lower, digit, symbol) en az bir karakter içermelidir. Şifre kullanıcı adını içeremez.
+*/
+type PasswordPolicy struct {
+	MinLength       int
+	RequiredClasses []string
+}
+
+var passwordClasses = map[string]func(rune) bool{
+	"upper":  unicode.IsUpper,
+	"lower":  unicode.IsLower,
+	"digit":  unicode.IsDigit,
+	"symbol": func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r) },
+}
+
+var passwordClassNames = map[string]string{
+	"upper":  "an uppercase letter",
+	"lower":  "a lowercase letter",
+	"digit":  "a digit",
+	"symbol": "a symbol",
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: 12, RequiredClasses: []string{"upper", "lower", "digit"}}
+}
+
+/*Bu fonksiyon, şifre politikasını merkezi yapılandırmadaki auth.password_min_length
+(PASSWORD_MIN_LENGTH) ve auth.password_require (PASSWORD_REQUIRE; virgülle ayrılmış sınıf
+listesi, ör. "upper,lower,digit,symbol"; "none" hiçbir sınıf istemez) değerlerinden
+oluşturur. Değerler yapılandırma yüklenirken doğrulanır.
+*/
+func PasswordPolicyFrom(settings config.AuthConfig) PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:       settings.PasswordMinLength,
+		RequiredClasses: append([]string(nil), settings.PasswordRequire...),
+	}
+}
+
+// Validate returns an ErrInvalidUser-wrapped error describing every rule the password breaks
+func (p PasswordPolicy) Validate(username, password string) error {
+	var problems []string
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("be at least %d characters long", p.MinLength))
+	}
+	for _, class := range p.RequiredClasses {
+		if !strings.ContainsFunc(password, passwordClasses[class]) {
+			problems = append(problems, "contain "+passwordClassNames[class])
+		}
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "not contain the username")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: password must %s", ErrInvalidUser, strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package service
+
+const (
+	RoleViewer  = "viewer"
+	RoleAnalyst = "analyst"
+	RoleAdmin   = "admin"
+)
+
+type Permission string
+
+const (
+	PermRead          Permission = "read"           // dashboard, entries, sources, tags, saved searches
+	PermEntriesWrite  Permission = "entries:write"  // criticality, category, triage, notes, tags, bulk
+	PermSourcesManage Permission = "sources:manage" // create, update and delete sources
+	PermScraperRun    Permission = "scraper:run"    // trigger scrapes
+	PermExport        Permission = "export"
+	PermUsersManage   Permission = "users:manage"
+	PermAuditRead     Permission = "audit:read"
+	PermConfigRead    Permission = "config:read"
+)
+
+/*rolePermissions, her rolün sahip olduğu yetkileri tanımlar. Viewer yalnızca okuyabilir
+ve kendi kayıtlı aramalarını yönetebilir; analyst kayıtlar üzerinde triage yapabilir,
+tarama tetikleyebilir ve dışa aktarım yapabilir; admin ayrıca kaynakları, kullanıcıları
+yönetir, denetim kaydını ve çalışan yapılandırmayı okur. Yetkiler token'a değil role bağlı olduğu için bu tablo
+değiştiğinde mevcut oturumlar da yeni kurallara hemen tabi olur.
+*/
+var rolePermissions = map[string][]Permission{
+	RoleViewer:  {PermRead},
+	RoleAnalyst: {PermRead, PermEntriesWrite, PermScraperRun, PermExport},
+	RoleAdmin: {PermRead, PermEntriesWrite, PermSourcesManage, PermScraperRun, PermExport,
+		PermUsersManage, PermAuditRead, PermConfigRead},
+}
+
+// roleRank orders roles by privilege, e.g. to pick the strongest of several mapped roles
+var roleRank = map[string]int{RoleViewer: 1, RoleAnalyst: 2, RoleAdmin: 3}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func RolePermissions(role string) []Permission {
+	perms := rolePermissions[role]
+	out := make([]Permission, len(perms))
+	copy(out, perms)
+	return out
+}
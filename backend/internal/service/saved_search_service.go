@@ -0,0 +1,457 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/siem"
+)
+
+var savedSearchLogger = logging.For("saved_search")
+
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// minScheduleMinutes is the shortest allowed re-execution interval
+const minScheduleMinutes = 5
+
+type SavedSearchService struct {
+	db          *sql.DB
+	dataService *DataService
+	emitter     *siem.Emitter
+}
+
+func NewSavedSearchService(db *sql.DB, dataService *DataService) *SavedSearchService {
+	return &SavedSearchService{db: db, dataService: dataService}
+}
+
+func (s *SavedSearchService) SetEmitter(emitter *siem.Emitter) {
+	s.emitter = emitter
+}
+
+/*Bu yapı (SavedSearch), bir kullanıcının kaydettiği filtre ve sıralama kombinasyonunu
+temsil eder. ScheduleMinutes boşsa arama yalnızca istendiğinde çalıştırılır; doluysa
+zamanlayıcı aramayı bu aralıkla yeniden çalıştırır ve son çalıştırmadan bu yana
+eşleşen yeni kayıtları saved_search_matches tablosuna yazar.
+*/
+type SavedSearch struct {
+	ID              int         `json:"id"`
+	UserID          int         `json:"user_id"`
+	Name            string      `json:"name"`
+	Filter          EntryFilter `json:"filter"`
+	ScheduleMinutes *int        `json:"schedule_minutes"`
+	LastRunAt       *time.Time  `json:"last_run_at"`
+	NextRunAt       *time.Time  `json:"next_run_at"`
+	LastRunMatches  int         `json:"last_run_matches"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+type SavedSearchInput struct {
+	Name            string      `json:"name"`
+	Filter          EntryFilter `json:"filter"`
+	ScheduleMinutes *int        `json:"schedule_minutes"`
+}
+
+type savedSearchRun struct {
+	searchID int
+	at       time.Time
+}
+
+const savedSearchColumns = `ss.id, ss.user_id, ss.name, ss.filter, ss.schedule_minutes,
+		       ss.last_run_at, ss.next_run_at, ss.created_at, ss.updated_at,
+		       (SELECT COUNT(*) FROM saved_search_matches m
+		        WHERE m.saved_search_id = ss.id AND m.matched_at = ss.last_run_at)`
+
+func scanSavedSearch(row rowScanner) (*SavedSearch, error) {
+	var ss SavedSearch
+	var filter []byte
+	var schedule sql.NullInt64
+	var lastRun, nextRun sql.NullTime
+	err := row.Scan(&ss.ID, &ss.UserID, &ss.Name, &filter, &schedule,
+		&lastRun, &nextRun, &ss.CreatedAt, &ss.UpdatedAt, &ss.LastRunMatches)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filter, &ss.Filter); err != nil {
+		return nil, fmt.Errorf("decode saved search filter: %w", err)
+	}
+	if schedule.Valid {
+		minutes := int(schedule.Int64)
+		ss.ScheduleMinutes = &minutes
+	}
+	if lastRun.Valid {
+		ss.LastRunAt = &lastRun.Time
+	}
+	if nextRun.Valid {
+		ss.NextRunAt = &nextRun.Time
+	}
+	return &ss, nil
+}
+
+/*Bu fonksiyon, kaydedilmek istenen aramayı doğrular. İsim zorunludur; filtre, liste
+endpoint'inin kullandığı aynı kurallarla (buildEntryQuery ve resolveSort) kontrol edilir,
+böylece kaydedilen bir arama çalıştırıldığında hata vermez. Zamanlama verilmişse en az
+minScheduleMinutes dakika olmalıdır.
+*/
+func validateSavedSearch(in *SavedSearchInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidFilter)
+	}
+	if len(in.Name) > 255 {
+		return fmt.Errorf("%w: name is too long", ErrInvalidFilter)
+	}
+	if in.ScheduleMinutes != nil && *in.ScheduleMinutes < minScheduleMinutes {
+		return fmt.Errorf("%w: schedule_minutes must be at least %d", ErrInvalidFilter, minScheduleMinutes)
+	}
+	q, err := buildEntryQuery(in.Filter)
+	if err != nil {
+		return err
+	}
+	_, _, err = resolveSort(in.Filter, q.tsQuery != "")
+	return err
+}
+
+func (s *SavedSearchService) List(userID int) ([]SavedSearch, error) {
+	rows, err := s.db.Query(`
+		SELECT `+savedSearchColumns+`
+		FROM saved_searches ss
+		WHERE ss.user_id = $1
+		ORDER BY ss.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		ss, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, *ss)
+	}
+	return searches, rows.Err()
+}
+
+func (s *SavedSearchService) Get(userID, id int) (*SavedSearch, error) {
+	ss, err := scanSavedSearch(s.db.QueryRow(`
+		SELECT `+savedSearchColumns+`
+		FROM saved_searches ss
+		WHERE ss.id = $1 AND ss.user_id = $2
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSavedSearchNotFound
+	}
+	return ss, err
+}
+
+/*Bu fonksiyon, kullanıcı için yeni bir kayıtlı arama oluşturur. Zamanlanmış aramalarda
+next_run_at hemen şimdiye ayarlanır; zamanlayıcının ilk çalıştırması bir başlangıç
+noktası oluşturur ve o anda eşleşen kayıtlar "yeni" sayılmaz. Aynı kullanıcıda aynı
+isimde ikinci bir arama oluşturulamaz.
+*/
+func (s *SavedSearchService) Create(userID int, in SavedSearchInput) (*SavedSearch, error) {
+	if err := validateSavedSearch(&in); err != nil {
+		return nil, err
+	}
+	filter, err := json.Marshal(in.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = s.db.QueryRow(`
+		INSERT INTO saved_searches (user_id, name, filter, schedule_minutes, next_run_at)
+		VALUES ($1, $2, $3, $4::integer, CASE WHEN $4::integer IS NULL THEN NULL ELSE NOW() END)
+		RETURNING id
+	`, userID, in.Name, string(filter), nullableInt(in.ScheduleMinutes)).Scan(&id)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: a saved search named %q already exists", ErrInvalidFilter, in.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(userID, id)
+}
+
+/*Bu fonksiyon, kayıtlı bir aramanın adını, filtresini ve zamanlamasını günceller.
+Filtre değiştiğinde önceki eşleşmeler artık yeni filtreyi yansıtmadığı için silinir ve
+son çalıştırma bilgisi sıfırlanır; bir sonraki çalıştırma yeniden başlangıç noktası olur.
+*/
+func (s *SavedSearchService) Update(userID, id int, in SavedSearchInput) (*SavedSearch, error) {
+	if err := validateSavedSearch(&in); err != nil {
+		return nil, err
+	}
+	filter, err := json.Marshal(in.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var filterChanged bool
+	err = tx.QueryRow(`
+		UPDATE saved_searches ss
+		SET name = $1,
+		    filter = $2,
+		    schedule_minutes = $3::integer,
+		    last_run_at = CASE WHEN old.filter = $2::jsonb THEN ss.last_run_at ELSE NULL END,
+		    next_run_at = CASE
+		        WHEN $3::integer IS NULL THEN NULL
+		        WHEN old.filter <> $2::jsonb OR ss.last_run_at IS NULL THEN NOW()
+		        ELSE ss.last_run_at + make_interval(mins => $3::integer)
+		    END,
+		    updated_at = NOW()
+		FROM (SELECT id, filter FROM saved_searches WHERE id = $4 AND user_id = $5 FOR UPDATE) old
+		WHERE ss.id = old.id
+		RETURNING old.filter <> $2::jsonb
+	`, in.Name, string(filter), nullableInt(in.ScheduleMinutes), id, userID).Scan(&filterChanged)
+	if err == sql.ErrNoRows {
+		return nil, ErrSavedSearchNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: a saved search named %q already exists", ErrInvalidFilter, in.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if filterChanged {
+		if _, err := tx.Exec(`DELETE FROM saved_search_matches WHERE saved_search_id = $1`, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.Get(userID, id)
+}
+
+func (s *SavedSearchService) Delete(userID, id int) error {
+	result, err := s.db.Exec(`DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+/*Bu fonksiyon, kayıtlı aramayı kayıt listesi ile aynı şekilde çalıştırır ve sayfalı
+sonucu döndürür. newOnly true ise sonuçlar, son zamanlanmış (veya elle tetiklenmiş)
+çalıştırmada yeni olarak işaretlenen kayıtlarla sınırlandırılır; arama hiç
+çalıştırılmamışsa boş sayfa döner.
+*/
+func (s *SavedSearchService) Results(userID, id int, page PageRequest, newOnly bool) (*EntryPage, error) {
+	ss, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := ss.Filter
+	if newOnly {
+		if ss.LastRunAt == nil {
+			return &EntryPage{Entries: []DataEntry{}}, nil
+		}
+		filter.matchedIn = &savedSearchRun{searchID: ss.ID, at: *ss.LastRunAt}
+	}
+	return s.dataService.GetAllEntries(filter, page)
+}
+
+/*Bu fonksiyon, kayıtlı aramayı çalıştırır ve filtreye uyan ancak bu arama için daha
+önce kaydedilmemiş kayıtları saved_search_matches tablosuna ekler. Ekleme ve
+last_run_at güncellemesi aynı transaction içinde NOW() ile yapıldığı için bir
+çalıştırmada bulunan kayıtlar matched_at = last_run_at koşuluyla ayırt edilebilir.
+İlk çalıştırma yalnızca başlangıç noktasıdır: o ana kadar eşleşen kayıtlar
+kaydedilir ancak bildirim üretilmez. Sonraki çalıştırmalarda her yeni kayıt için
+SIEM'e saved_search_match olayı gönderilir. Eklenen yeni kayıt sayısı döndürülür.
+*/
+func (s *SavedSearchService) Run(userID, id int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var name, username string
+	var filterData []byte
+	var schedule sql.NullInt64
+	var lastRun sql.NullTime
+	err = tx.QueryRow(`
+		SELECT ss.name, ss.filter, ss.schedule_minutes, ss.last_run_at, u.username
+		FROM saved_searches ss
+		JOIN users u ON u.id = ss.user_id
+		WHERE ss.id = $1 AND ss.user_id = $2
+		FOR UPDATE OF ss
+	`, id, userID).Scan(&name, &filterData, &schedule, &lastRun, &username)
+	if err == sql.ErrNoRows {
+		return 0, ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var filter EntryFilter
+	if err := json.Unmarshal(filterData, &filter); err != nil {
+		return 0, fmt.Errorf("decode saved search filter: %w", err)
+	}
+	q, err := buildEntryQuery(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`
+		INSERT INTO saved_search_matches (saved_search_id, entry_id, matched_at)
+		SELECT %s, e.id, NOW()%s%s
+		ON CONFLICT DO NOTHING
+		RETURNING entry_id
+	`, q.arg(id), q.from, q.whereClause()), q.args...)
+	if err != nil {
+		return 0, err
+	}
+	var matched []int
+	for rows.Next() {
+		var entryID int
+		if err := rows.Scan(&entryID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		matched = append(matched, entryID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var nextRun interface{}
+	if schedule.Valid {
+		nextRun = time.Now().Add(time.Duration(schedule.Int64) * time.Minute)
+	}
+	if _, err := tx.Exec(`
+		UPDATE saved_searches SET last_run_at = NOW(), next_run_at = $1 WHERE id = $2
+	`, nextRun, id); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if lastRun.Valid && len(matched) > 0 {
+		s.notify(id, name, username, matched)
+	}
+	return len(matched), nil
+}
+
+func (s *SavedSearchService) notify(searchID int, name, username string, entryIDs []int) {
+	if s.emitter == nil {
+		return
+	}
+	ids := make([]int64, len(entryIDs))
+	for i, id := range entryIDs {
+		ids[i] = int64(id)
+	}
+	rows, err := s.db.Query(`
+		SELECT `+entryColumns+entryFrom+`
+		WHERE e.id = ANY($1::int[])
+	`, pq.Array(ids))
+	if err != nil {
+		savedSearchLogger.Error("failed to load matches for notification", "search_id", searchID, logging.Err(err))
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			savedSearchLogger.Error("failed to read match", "search_id", searchID, logging.Err(err))
+			return
+		}
+		s.emitter.Emit(siem.Event{
+			Type:             siem.EventSavedSearchMatch,
+			EntryID:          entry.ID,
+			SourceID:         entry.SourceID,
+			SourceName:       entry.SourceName,
+			SourceURL:        entry.SourceURL,
+			Title:            entry.Title,
+			Category:         entry.Category,
+			CriticalityScore: entry.CriticalityScore,
+			Actor:            username,
+			Message:          fmt.Sprintf("Saved search %q matched a new entry", name),
+		})
+	}
+}
+
+/*Bu fonksiyon, zamanlanmış kayıtlı aramaları çalıştıran döngüyü başlatır. Her interval'de
+(scheduler.saved_search_interval, varsayılan bir dakika) next_run_at zamanı gelmiş
+aramalar bulunur ve sırayla Run ile çalıştırılır; bir aramadaki hata loglanır ve
+diğerlerinin çalışmasını engellemez. Scraper servisi gibi bloklayıcıdır ve ayrı bir
+goroutine içinde başlatılmalıdır.
+*/
+func (s *SavedSearchService) StartScheduler(interval time.Duration) {
+	savedSearchLogger.Info("scheduler starting", "interval", interval.String())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue()
+		<-ticker.C
+	}
+}
+
+func (s *SavedSearchService) runDue() {
+	rows, err := s.db.Query(`
+		SELECT id, user_id FROM saved_searches
+		WHERE schedule_minutes IS NOT NULL AND next_run_at <= NOW()
+		ORDER BY next_run_at
+	`)
+	if err != nil {
+		savedSearchLogger.Error("failed to load due searches", logging.Err(err))
+		return
+	}
+	type dueSearch struct{ id, userID int }
+	var due []dueSearch
+	for rows.Next() {
+		var d dueSearch
+		if err := rows.Scan(&d.id, &d.userID); err == nil {
+			due = append(due, d)
+		}
+	}
+	rows.Close()
+
+	for _, d := range due {
+		count, err := s.Run(d.userID, d.id)
+		if err != nil {
+			savedSearchLogger.Warn("scheduled run failed", "search_id", d.id, logging.Err(err))
+			continue
+		}
+		if count > 0 {
+			savedSearchLogger.Info("scheduled run recorded new matches", "search_id", d.id, "matches", count)
+		}
+	}
+}
+
+func nullableInt(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+/*Bu dosya, kullanıcıların arama kutusuna yazdığı sorguyu PostgreSQL'in to_tsquery
+fonksiyonunun anlayacağı metne dönüştürür. Desteklenen sözdizimi:
+
+	ransomware leak          -> iki kelime de geçmeli (AND)
+	ransomware OR wiper      -> kelimelerden biri geçmeli
+	"initial access broker"  -> kelimeler yan yana ve bu sırayla geçmeli (phrase)
+	-forum / NOT forum       -> kelime geçmemeli
+	title:lockbit            -> kelime yalnızca başlıkta aranır (content: içerik için)
+	exploit*                 -> önek araması
+	(a OR b) c               -> gruplama
+
+Kullanıcıdan gelen her kelime harf ve rakam dışındaki karakterlerden arındırılır; bu
+sayede tsquery sözdizimi hataları veya enjeksiyon mümkün olmaz. Kelimelerin kök
+bulma (stemming) işlemi PostgreSQL tarafında, seçili dil yapılandırmasıyla yapılır.
+*/
+
+type searchTokenKind int
+
+const (
+	tokenWord searchTokenKind = iota
+	tokenPhrase
+	tokenOr
+	tokenAnd
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type searchToken struct {
+	kind   searchTokenKind
+	text   string
+	field  string
+	prefix bool
+}
+
+// searchFieldWeights maps field prefixes to the tsvector weights set by the schema trigger
+var searchFieldWeights = map[string]string{
+	"title":   "A",
+	"content": "B",
+}
+
+/*Bu fonksiyon, arama metnini to_tsquery'e verilecek sorgu metnine çevirir. Sorgu
+anlamlı bir terim içermiyorsa (ör. yalnızca noktalama işaretleri) boş string döner;
+çağıran taraf bu durumda arama filtresini uygulamaz. Dengesiz parantez gibi hatalar
+kullanıcıya gösterilebilecek bir hata mesajıyla döndürülür.
+*/
+func ParseSearchQuery(input string) (string, error) {
+	tokens := tokenizeSearch(input)
+	p := &searchParser{tokens: tokens}
+	query, err := p.parseOr()
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.tokens) {
+		return "", fmt.Errorf("%w: unexpected ')'", ErrInvalidSearchQuery)
+	}
+	return query, nil
+}
+
+func tokenizeSearch(input string) []searchToken {
+	var tokens []searchToken
+	runes := []rune(input)
+	i := 0
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, searchToken{kind: tokenLParen})
+			i++
+		case r == ')':
+			tokens = append(tokens, searchToken{kind: tokenRParen})
+			i++
+		case r == '-' || r == '!':
+			tokens = append(tokens, searchToken{kind: tokenNot})
+			i++
+		default:
+			field := ""
+			if name, ok := fieldPrefixAt(runes, i); ok {
+				field = name
+				i += len([]rune(name)) + 1
+				if i >= len(runes) {
+					continue
+				}
+			}
+
+			if runes[i] == '"' {
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				tokens = append(tokens, searchToken{kind: tokenPhrase, text: string(runes[i+1 : end]), field: field})
+				i = end + 1
+				continue
+			}
+
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			word := string(runes[start:i])
+			if word == "" {
+				i++
+				continue
+			}
+
+			if field == "" {
+				switch word {
+				case "OR", "|":
+					tokens = append(tokens, searchToken{kind: tokenOr})
+					continue
+				case "AND", "&":
+					tokens = append(tokens, searchToken{kind: tokenAnd})
+					continue
+				case "NOT":
+					tokens = append(tokens, searchToken{kind: tokenNot})
+					continue
+				}
+			}
+
+			prefix := strings.HasSuffix(word, "*")
+			tokens = append(tokens, searchToken{
+				kind:   tokenWord,
+				text:   strings.TrimRight(word, "*"),
+				field:  field,
+				prefix: prefix,
+			})
+		}
+	}
+
+	return tokens
+}
+
+func fieldPrefixAt(runes []rune, i int) (string, bool) {
+	for name := range searchFieldWeights {
+		n := []rune(name + ":")
+		if i+len(n) <= len(runes) && strings.EqualFold(string(runes[i:i+len(n)]), string(n)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+}
+
+func (p *searchParser) peek() *searchToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *searchParser) parseOr() (string, error) {
+	var parts []string
+	for {
+		part, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+		if t := p.peek(); t != nil && t.kind == tokenOr {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return joinSearchParts(parts, " | "), nil
+}
+
+func (p *searchParser) parseAnd() (string, error) {
+	var parts []string
+	for {
+		t := p.peek()
+		if t == nil || t.kind == tokenOr || t.kind == tokenRParen {
+			break
+		}
+		if t.kind == tokenAnd {
+			p.pos++
+			continue
+		}
+		part, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return joinSearchParts(parts, " & "), nil
+}
+
+func (p *searchParser) parseUnary() (string, error) {
+	t := p.peek()
+	if t != nil && t.kind == tokenNot {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil || operand == "" {
+			return "", err
+		}
+		return "!" + operand, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *searchParser) parsePrimary() (string, error) {
+	t := p.peek()
+	if t == nil {
+		return "", nil
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != tokenRParen {
+			return "", fmt.Errorf("%w: missing ')'", ErrInvalidSearchQuery)
+		}
+		p.pos++
+		if inner == "" {
+			return "", nil
+		}
+		return "(" + inner + ")", nil
+	case tokenWord, tokenPhrase:
+		return buildLexemes(t.text, t.field, t.prefix && t.kind == tokenWord), nil
+	}
+	return "", nil
+}
+
+/*Bu fonksiyon, bir kelimeyi veya tırnak içindeki ifadeyi tsquery lexeme'lerine çevirir.
+Metin harf/rakam olmayan karakterlerden bölünür ve parçalar "<->" (hemen ardından gelir)
+operatörüyle bağlanır; böylece "cve-2024-1234" veya "initial access" gibi ifadeler sırası
+korunarak aranır. Alan öneki varsa her lexeme'e ilgili ağırlık etiketi eklenir.
+*/
+func buildLexemes(text, field string, prefix bool) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	weight := searchFieldWeights[field]
+	for i, w := range words {
+		label := weight
+		if prefix && i == len(words)-1 {
+			label = "*" + weight
+		}
+		if label != "" {
+			w += ":" + label
+		}
+		words[i] = w
+	}
+
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
+
+func joinSearchParts(parts []string, op string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	joined := strings.Join(parts, op)
+	if op == " | " {
+		return "(" + joined + ")"
+	}
+	return joined
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"implicit and", "ransomware leak", "ransomware & leak"},
+		{"explicit and", "a AND b", "a & b"},
+		{"or", "lockbit OR blackcat", "(lockbit | blackcat)"},
+		{"and binds tighter than or", "a & b | c", "(a & b | c)"},
+		{"phrase", `"initial access"`, "(initial <-> access)"},
+		{"unterminated phrase", `"initial access`, "(initial <-> access)"},
+		{"minus", "ransomware -forum", "ransomware & !forum"},
+		{"not keyword", "NOT forum", "!forum"},
+		{"title prefix", "title:lockbit", "lockbit:A"},
+		{"field prefix is case insensitive", "TITLE:lockbit", "lockbit:A"},
+		{"content phrase", `content:"access broker"`, "(access:B <-> broker:B)"},
+		{"prefix search", "exploit*", "exploit:*"},
+		{"prefix search in title", "title:lock*", "lock:*A"},
+		{"grouping", "(a OR b) c", "((a | b)) & c"},
+		{"punctuation splits into a phrase", "cve-2024-1234", "(cve <-> 2024 <-> 1234)"},
+		{"unknown field is text", "a:b", "(a <-> b)"},
+		{"non ascii letters", "ürün şifre", "ürün & şifre"},
+		{"only punctuation", "!!!", ""},
+		{"empty group", "()", ""},
+		{"dangling not", "NOT", ""},
+		{"dangling or", "x OR", "x"},
+		{"field without term", "title:", ""},
+		{"tsquery syntax is stripped", "'; DROP TABLE entries; --", "DROP & TABLE & entries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSearchQuery(tt.input)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q) error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSearchQuery(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	for _, input := range []string{"(a", "a)", "(a OR (b c)", "a) OR (b"} {
+		if _, err := ParseSearchQuery(input); !errors.Is(err, ErrInvalidSearchQuery) {
+			t.Errorf("ParseSearchQuery(%q) error = %v, want ErrInvalidSearchQuery", input, err)
+		}
+	}
+}
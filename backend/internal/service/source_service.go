@@ -2,10 +2,30 @@ package service
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 )
 
+// ErrInvalidSource is returned when a source's name, URL or transport settings are rejected
+var ErrInvalidSource = errors.New("invalid source")
+
+// Source transports: how the scraper reaches a source
+const (
+	TransportTor    = "tor"
+	TransportI2P    = "i2p"
+	TransportProxy  = "proxy"
+	TransportDirect = "direct"
+)
+
+var sourceTransports = map[string]bool{TransportTor: true, TransportI2P: true, TransportProxy: true, TransportDirect: true}
+
+// proxySchemes are the proxy URL schemes net/http can dial through
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true, "socks5h": true}
+
 type SourceService struct {
 	db *sql.DB
 }
@@ -15,17 +35,59 @@ func NewSourceService(db *sql.DB) *SourceService {
 }
 
 type Source struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	URL       string    `json:"url"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int         `json:"id"`
+	Name      string      `json:"name"`
+	URL       string      `json:"url"`
+	Transport string      `json:"transport"`
+	ProxyURL  string      `json:"proxy_url,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	Tags      []SourceTag `json:"tags"`
+
+	// rawProxyURL is the stored proxy URL; ProxyURL shows it with the password masked
+	rawProxyURL string
+}
+
+// RawProxyURL returns the proxy URL with its password, for sources export
+func (s *Source) RawProxyURL() string {
+	return s.rawProxyURL
+}
+
+// setProxyURL keeps the stored proxy URL and exposes it with the password masked
+func (s *Source) setProxyURL(raw string) {
+	s.rawProxyURL = raw
+	s.ProxyURL = redactProxyURL(raw)
+}
+
+// redactProxyURL masks the password of a proxy URL the way url.URL.Redacted does
+func redactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	return u.Redacted()
+}
+
+const sourceColumns = `src.id, src.name, src.url, src.transport, COALESCE(src.proxy_url, ''), src.created_at, (` + sourceTagsSubquery + `)`
+
+func scanSource(row rowScanner) (*Source, error) {
+	var source Source
+	var tags []byte
+	var proxyURL string
+	if err := row.Scan(&source.ID, &source.Name, &source.URL, &source.Transport, &proxyURL, &source.CreatedAt, &tags); err != nil {
+		return nil, err
+	}
+	source.setProxyURL(proxyURL)
+	if err := json.Unmarshal(tags, &source.Tags); err != nil {
+		return nil, fmt.Errorf("decode source tags: %w", err)
+	}
+	return &source, nil
 }
 
 func (s *SourceService) GetAllSources() ([]Source, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, url, created_at 
-		FROM sources 
-		ORDER BY created_at DESC
+		SELECT ` + sourceColumns + `
+		FROM sources src
+		ORDER BY src.created_at DESC
 	`)
 	if err != nil {
 		return nil, err
@@ -34,66 +96,126 @@ func (s *SourceService) GetAllSources() ([]Source, error) {
 
 	var sources []Source
 	for rows.Next() {
-		var source Source
-		if err := rows.Scan(&source.ID, &source.Name, &source.URL, &source.CreatedAt); err != nil {
+		source, err := scanSource(rows)
+		if err != nil {
 			continue
 		}
-		sources = append(sources, source)
+		sources = append(sources, *source)
 	}
 
 	return sources, nil
 }
 
 func (s *SourceService) GetSourceByID(id int) (*Source, error) {
-	var source Source
-	err := s.db.QueryRow(`
-		SELECT id, name, url, created_at 
-		FROM sources 
-		WHERE id = $1
-	`, id).Scan(&source.ID, &source.Name, &source.URL, &source.CreatedAt)
+	return scanSource(s.db.QueryRow(`
+		SELECT `+sourceColumns+`
+		FROM sources src
+		WHERE src.id = $1
+	`, id))
+}
 
-	if err != nil {
-		return nil, err
+/*Bu fonksiyon, bir adresin hangi taşıma (transport) ile çekileceğini belirler. .onion
+adresleri yalnızca Tor üzerinden, .i2p adresleri yalnızca I2P üzerinden erişilebildiği
+için kaynakta seçilen taşıma ne olursa olsun bunlara zorlanır; diğer adreslerde seçilen
+taşıma kullanılır, boşsa varsayılan Tor'dur. Hem kaynak kaydedilirken hem de scraper
+fetch öncesinde bu kuralı uygular.
+*/
+func SourceTransport(rawURL, transport string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		switch {
+		case strings.HasSuffix(host, ".onion"):
+			return TransportTor
+		case strings.HasSuffix(host, ".i2p"):
+			return TransportI2P
+		}
 	}
-
-	return &source, nil
+	if transport == "" {
+		return TransportTor
+	}
+	return transport
 }
 
-func (s *SourceService) CreateSource(name, url string) (*Source, error) {
-	if name == "" {
-		return nil, fmt.Errorf("source name is required")
+// normalizeSourceSpec trims and validates a source, applying the .onion/.i2p transport rules
+func normalizeSourceSpec(spec SourceSpec) (SourceSpec, error) {
+	spec.Name = strings.TrimSpace(spec.Name)
+	spec.URL = strings.TrimSpace(spec.URL)
+	spec.ProxyURL = strings.TrimSpace(spec.ProxyURL)
+	if spec.Name == "" {
+		return spec, fmt.Errorf("%w: source name is required", ErrInvalidSource)
 	}
-	if url == "" {
-		return nil, fmt.Errorf("source URL is required")
+	if spec.URL == "" {
+		return spec, fmt.Errorf("%w: source URL is required", ErrInvalidSource)
+	}
+
+	transport := strings.ToLower(strings.TrimSpace(spec.Transport))
+	if transport != "" && !sourceTransports[transport] {
+		return spec, fmt.Errorf("%w: transport must be tor, i2p, proxy or direct, got %q", ErrInvalidSource, spec.Transport)
+	}
+	spec.Transport = SourceTransport(spec.URL, transport)
+	if spec.Transport != TransportProxy {
+		// The proxy URL only applies to the proxy transport; drop it when .onion/.i2p forced another one
+		spec.ProxyURL = ""
+		return spec, nil
+	}
+	u, err := url.Parse(spec.ProxyURL)
+	if spec.ProxyURL == "" || err != nil || u.Host == "" || !proxySchemes[u.Scheme] {
+		return spec, fmt.Errorf("%w: transport proxy needs a proxy_url like http://host:3128 or socks5h://host:1080", ErrInvalidSource)
+	}
+	return spec, nil
+}
+
+/*Bu fonksiyon, yeni bir kaynak ekler. Kaynağın taşıması (transport) verilmezse Tor
+kullanılır; .onion ve .i2p adresleri SourceTransport kuralına göre Tor'a ve I2P'ye
+zorlanır. proxy taşıması için http, https, socks5 veya socks5h şemalı bir proxy_url
+gerekir. Geçersiz girdilerde ErrInvalidSource döner.
+*/
+func (s *SourceService) CreateSource(spec SourceSpec) (*Source, error) {
+	spec, err := normalizeSourceSpec(spec)
+	if err != nil {
+		return nil, err
 	}
 
 	var source Source
-	err := s.db.QueryRow(`
-		INSERT INTO sources (name, url) 
-		VALUES ($1, $2) 
-		RETURNING id, name, url, created_at
-	`, name, url).Scan(&source.ID, &source.Name, &source.URL, &source.CreatedAt)
+	err = s.db.QueryRow(`
+		INSERT INTO sources (name, url, transport, proxy_url) 
+		VALUES ($1, $2, $3, NULLIF($4, '')) 
+		RETURNING id, name, url, transport, created_at
+	`, spec.Name, spec.URL, spec.Transport, spec.ProxyURL).Scan(&source.ID, &source.Name, &source.URL, &source.Transport, &source.CreatedAt)
+	source.setProxyURL(spec.ProxyURL)
 
 	if err != nil {
 		return nil, err
 	}
+	source.Tags = []SourceTag{}
 
 	return &source, nil
 }
 
-func (s *SourceService) UpdateSource(id int, name, url string) error {
-	if name == "" {
-		return fmt.Errorf("source name is required")
+/*Bu fonksiyon, bir kaynağı günceller. API kaynakları proxy parolası maskelenmiş olarak
+döndürdüğü için parola yalnızca yazılabilir kabul edilir: istek, kayıtlı proxy_url'in
+maskelenmiş halini aynen geri gönderirse kayıtlı adres (parolasıyla) korunur.
+*/
+func (s *SourceService) UpdateSource(id int, spec SourceSpec) error {
+	var stored string
+	err := s.db.QueryRow(`SELECT COALESCE(proxy_url, '') FROM sources WHERE id = $1`, id).Scan(&stored)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if stored != "" && strings.TrimSpace(spec.ProxyURL) == redactProxyURL(stored) {
+		spec.ProxyURL = stored
 	}
-	if url == "" {
-		return fmt.Errorf("source URL is required")
+
+	spec, err = normalizeSourceSpec(spec)
+	if err != nil {
+		return err
 	}
 
-	_, err := s.db.Exec(`
+	_, err = s.db.Exec(`
 		UPDATE sources 
-		SET name = $1, url = $2 
-		WHERE id = $3
-	`, name, url, id)
+		SET name = $1, url = $2, transport = $3, proxy_url = NULLIF($4, '')
+		WHERE id = $5
+	`, spec.Name, spec.URL, spec.Transport, spec.ProxyURL, id)
 
 	return err
 }
@@ -103,3 +225,61 @@ func (s *SourceService) DeleteSource(id int) error {
 	return err
 }
 
+
+// SourceSpec is the portable form of a source used by sources import and export
+type SourceSpec struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Transport string `json:"transport,omitempty"`
+	ProxyURL  string `json:"proxy_url,omitempty"`
+}
+
+/*Bu fonksiyon, verilen kaynak listesini içe aktarır. Adresi zaten kayıtlı olan kaynaklar
+atlanır; böylece aynı dosya birden fazla kez içe aktarılsa da kopya oluşmaz. Tüm liste
+tek bir transaction içinde eklenir; geçersiz bir kayıt varsa hiçbir kaynak eklenmez.
+Oluşturulan kaynaklar ve atlanan kayıt sayısı döner.
+*/
+func (s *SourceService) ImportSources(specs []SourceSpec) ([]Source, int, error) {
+	for i, spec := range specs {
+		if strings.TrimSpace(spec.Name) == "" || strings.TrimSpace(spec.URL) == "" {
+			return nil, 0, fmt.Errorf("source %d: name and url are required", i+1)
+		}
+		normalized, err := normalizeSourceSpec(spec)
+		if err != nil {
+			return nil, 0, fmt.Errorf("source %d: %w", i+1, err)
+		}
+		specs[i] = normalized
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	created := []Source{}
+	skipped := 0
+	for _, spec := range specs {
+		var source Source
+		err := tx.QueryRow(`
+			INSERT INTO sources (name, url, transport, proxy_url)
+			SELECT $1::varchar, $2::varchar, $3::varchar, NULLIF($4::varchar, '')
+			WHERE NOT EXISTS (SELECT 1 FROM sources WHERE url = $2::varchar)
+			RETURNING id, name, url, transport, created_at
+		`, spec.Name, spec.URL, spec.Transport, spec.ProxyURL).Scan(&source.ID, &source.Name, &source.URL, &source.Transport, &source.CreatedAt)
+		if err == sql.ErrNoRows {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		source.setProxyURL(spec.ProxyURL)
+		source.Tags = []SourceTag{}
+		created = append(created, source)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+	return created, skipped, nil
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrInvalidTag  = errors.New("invalid tag")
+)
+
+const defaultTagColor = "#6b7280"
+
+var tagColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// entryTagsSubquery selects the tags of entry e as a JSON array of TagRef
+const entryTagsSubquery = `SELECT COALESCE(json_agg(json_build_object('id', t.id, 'name', t.name, 'color', t.color) ORDER BY t.name), '[]')
+		        FROM entry_tags et JOIN tags t ON t.id = et.tag_id
+		        WHERE et.entry_id = e.id`
+
+// sourceTagsSubquery selects the tags of source src as a JSON array of SourceTag
+const sourceTagsSubquery = `SELECT COALESCE(json_agg(json_build_object('id', t.id, 'name', t.name, 'color', t.color,
+		                                          'auto_apply', st.auto_apply) ORDER BY t.name), '[]')
+		        FROM source_tags st JOIN tags t ON t.id = st.tag_id
+		        WHERE st.source_id = src.id`
+
+type Tag struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	Description string    `json:"description"`
+	EntryCount  int       `json:"entry_count"`
+	SourceCount int       `json:"source_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TagRef is the short form of a tag embedded in entries
+type TagRef struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+/*Bu yapı (SourceTag), bir kaynağa eklenmiş etiketi temsil eder. AutoApply true ise
+kaynaktan yeni bir kayıt eklendiğinde etiket veritabanı tetikleyicisi tarafından kayda
+otomatik olarak eklenir; false ise etiket yalnızca kaynağı sınıflandırmak için kullanılır.
+*/
+type SourceTag struct {
+	TagRef
+	AutoApply bool `json:"auto_apply"`
+}
+
+type TagInput struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+/*Bu yapı (TagBulkRequest), etiketlerin kayıtlara ve kaynaklara toplu olarak eklenmesi
+veya kaldırılması isteğini temsil eder. Etiketler ID ya da isimle verilebilir; ekleme
+işleminde var olmayan isimler varsayılan renkle oluşturulur. AutoApply yalnızca
+kaynaklara eklenen etiketler için kullanılır ve verilmezse true kabul edilir.
+*/
+type TagBulkRequest struct {
+	Action    string   `json:"action"` // add, remove
+	TagIDs    []int    `json:"tag_ids"`
+	TagNames  []string `json:"tag_names"`
+	EntryIDs  []int    `json:"entry_ids"`
+	SourceIDs []int    `json:"source_ids"`
+	AutoApply *bool    `json:"auto_apply"`
+}
+
+type TagBulkResult struct {
+	EntriesAffected int `json:"entries_affected"`
+	SourcesAffected int `json:"sources_affected"`
+}
+
+type TagService struct {
+	db *sql.DB
+}
+
+func NewTagService(db *sql.DB) *TagService {
+	return &TagService{db: db}
+}
+
+func validateTag(in *TagInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || len(in.Name) > 100 {
+		return fmt.Errorf("%w: name must be between 1 and 100 characters", ErrInvalidTag)
+	}
+	if in.Color == "" {
+		in.Color = defaultTagColor
+	}
+	if !tagColorRegex.MatchString(in.Color) {
+		return fmt.Errorf("%w: color must be a hex value like #ff0000", ErrInvalidTag)
+	}
+	return nil
+}
+
+const tagColumns = `t.id, t.name, t.color, t.description, t.created_at,
+		       (SELECT COUNT(*) FROM entry_tags et WHERE et.tag_id = t.id),
+		       (SELECT COUNT(*) FROM source_tags st WHERE st.tag_id = t.id)`
+
+func scanTag(row rowScanner) (*Tag, error) {
+	var tag Tag
+	err := row.Scan(&tag.ID, &tag.Name, &tag.Color, &tag.Description, &tag.CreatedAt, &tag.EntryCount, &tag.SourceCount)
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (s *TagService) GetAllTags() ([]Tag, error) {
+	rows, err := s.db.Query(`SELECT ` + tagColumns + ` FROM tags t ORDER BY LOWER(t.name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, rows.Err()
+}
+
+func (s *TagService) GetTagByID(id int) (*Tag, error) {
+	tag, err := scanTag(s.db.QueryRow(`SELECT `+tagColumns+` FROM tags t WHERE t.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTagNotFound
+	}
+	return tag, err
+}
+
+func (s *TagService) CreateTag(in TagInput) (*Tag, error) {
+	if err := validateTag(&in); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err := s.db.QueryRow(`
+		INSERT INTO tags (name, color, description)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, in.Name, in.Color, in.Description).Scan(&id)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: a tag named %q already exists", ErrInvalidTag, in.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTagByID(id)
+}
+
+func (s *TagService) UpdateTag(id int, in TagInput) (*Tag, error) {
+	if err := validateTag(&in); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE tags SET name = $1, color = $2, description = $3 WHERE id = $4
+	`, in.Name, in.Color, in.Description, id)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: a tag named %q already exists", ErrInvalidTag, in.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrTagNotFound
+	}
+	return s.GetTagByID(id)
+}
+
+func (s *TagService) DeleteTag(id int) error {
+	result, err := s.db.Exec(`DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+/*Bu fonksiyon, etiketleri verilen kayıtlara ve kaynaklara toplu olarak ekler veya
+onlardan kaldırır. Tüm işlem tek bir transaction içinde yapılır; bir hata olursa hiçbir
+değişiklik kalıcı olmaz. Etkilenen (yeni eklenen veya silinen) ilişki sayıları döner.
+*/
+func (s *TagService) BulkApply(req TagBulkRequest) (*TagBulkResult, error) {
+	if req.Action != "add" && req.Action != "remove" {
+		return nil, fmt.Errorf("%w: action must be add or remove", ErrInvalidTag)
+	}
+	if len(req.EntryIDs) == 0 && len(req.SourceIDs) == 0 {
+		return nil, fmt.Errorf("%w: entry_ids or source_ids is required", ErrInvalidTag)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tagIDs, err := resolveTagIDs(tx, req.TagIDs, req.TagNames, req.Action == "add")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TagBulkResult{}
+	if req.Action == "add" {
+		autoApply := true
+		if req.AutoApply != nil {
+			autoApply = *req.AutoApply
+		}
+		if result.EntriesAffected, err = addEntryTags(tx, req.EntryIDs, tagIDs); err != nil {
+			return nil, err
+		}
+		if result.SourcesAffected, err = addSourceTags(tx, req.SourceIDs, tagIDs, autoApply); err != nil {
+			return nil, err
+		}
+	} else {
+		if result.EntriesAffected, err = removeEntryTags(tx, req.EntryIDs, tagIDs); err != nil {
+			return nil, err
+		}
+		if result.SourcesAffected, err = removeSourceTags(tx, req.SourceIDs, tagIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+/*Bu fonksiyon, ID ve isimle verilen etiketleri tek bir ID listesine çevirir. Bilinmeyen
+bir ID ErrTagNotFound döner. create true ise bilinmeyen isimler varsayılan renkle
+oluşturulur; false ise (ör. kaldırma işleminde) yok sayılır. İsim karşılaştırması büyük/
+küçük harfe duyarsızdır.
+*/
+func resolveTagIDs(tx *sql.Tx, ids []int, names []string, create bool) ([]int64, error) {
+	var resolved []int64
+	if len(ids) > 0 {
+		want := toInt64s(ids)
+		rows, err := tx.Query(`SELECT id FROM tags WHERE id = ANY($1::int[])`, pq.Array(want))
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[int64]bool)
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			found[id] = true
+		}
+		rows.Close()
+		for _, id := range want {
+			if !found[id] {
+				return nil, fmt.Errorf("%w: %d", ErrTagNotFound, id)
+			}
+		}
+		resolved = append(resolved, want...)
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		var id int64
+		err := tx.QueryRow(`SELECT id FROM tags WHERE LOWER(name) = LOWER($1)`, name).Scan(&id)
+		if err == sql.ErrNoRows && create {
+			in := TagInput{Name: name}
+			if err := validateTag(&in); err != nil {
+				return nil, err
+			}
+			err = tx.QueryRow(`
+				INSERT INTO tags (name, color) VALUES ($1, $2) RETURNING id
+			`, in.Name, in.Color).Scan(&id)
+		}
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, id)
+	}
+
+	if len(resolved) == 0 && create {
+		return nil, fmt.Errorf("%w: tag_ids or tag_names is required", ErrInvalidTag)
+	}
+	return resolved, nil
+}
+
+func addEntryTags(tx *sql.Tx, entryIDs []int, tagIDs []int64) (int, error) {
+	if len(entryIDs) == 0 || len(tagIDs) == 0 {
+		return 0, nil
+	}
+	result, err := tx.Exec(`
+		INSERT INTO entry_tags (entry_id, tag_id)
+		SELECT e.id, t.id
+		FROM data_entries e, tags t
+		WHERE e.id = ANY($1::int[]) AND t.id = ANY($2::int[])
+		ON CONFLICT DO NOTHING
+	`, pq.Array(toInt64s(entryIDs)), pq.Array(tagIDs))
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+func removeEntryTags(tx *sql.Tx, entryIDs []int, tagIDs []int64) (int, error) {
+	if len(entryIDs) == 0 || len(tagIDs) == 0 {
+		return 0, nil
+	}
+	result, err := tx.Exec(`
+		DELETE FROM entry_tags WHERE entry_id = ANY($1::int[]) AND tag_id = ANY($2::int[])
+	`, pq.Array(toInt64s(entryIDs)), pq.Array(tagIDs))
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+func addSourceTags(tx *sql.Tx, sourceIDs []int, tagIDs []int64, autoApply bool) (int, error) {
+	if len(sourceIDs) == 0 || len(tagIDs) == 0 {
+		return 0, nil
+	}
+	result, err := tx.Exec(`
+		INSERT INTO source_tags (source_id, tag_id, auto_apply)
+		SELECT src.id, t.id, $3
+		FROM sources src, tags t
+		WHERE src.id = ANY($1::int[]) AND t.id = ANY($2::int[])
+		ON CONFLICT (source_id, tag_id) DO UPDATE SET auto_apply = EXCLUDED.auto_apply
+	`, pq.Array(toInt64s(sourceIDs)), pq.Array(tagIDs), autoApply)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+func removeSourceTags(tx *sql.Tx, sourceIDs []int, tagIDs []int64) (int, error) {
+	if len(sourceIDs) == 0 || len(tagIDs) == 0 {
+		return 0, nil
+	}
+	result, err := tx.Exec(`
+		DELETE FROM source_tags WHERE source_id = ANY($1::int[]) AND tag_id = ANY($2::int[])
+	`, pq.Array(toInt64s(sourceIDs)), pq.Array(tagIDs))
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+func toInt64s(ids []int) []int64 {
+	out := make([]int64, len(ids))
+	for i, id := range ids {
+		out[i] = int64(id)
+	}
+	return out
+}
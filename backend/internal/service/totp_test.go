@@ -0,0 +1,116 @@
+package service
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; a 6-digit code is their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("totpCode at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+	code := func(step int64) string {
+		c, err := totpCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfc6238Secret, code(current), current, true},
+		{"previous step within skew", rfc6238Secret, code(current - 1), current - 1, true},
+		{"next step within skew", rfc6238Secret, code(current + 1), current + 1, true},
+		{"two steps old", rfc6238Secret, code(current - 2), 0, false},
+		{"spaces are ignored", rfc6238Secret, " " + code(current)[:3] + " " + code(current)[3:] + " ", current, true},
+		{"lower case secret", strings.ToLower(rfc6238Secret), code(current), current, true},
+		{"too short", rfc6238Secret, code(current)[:5], 0, false},
+		{"too long", rfc6238Secret, code(current) + "0", 0, false},
+		{"wrong code", rfc6238Secret, "000000", 0, false},
+		{"invalid secret", "not base32!", "123456", 0, false},
+		{"no secret", "", code(current), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := verifyTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("verifyTOTP = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPStepReplayed(t *testing.T) {
+	tests := []struct {
+		name     string
+		lastStep sql.NullInt64
+		step     int64
+		want     bool
+	}{
+		{"first use", sql.NullInt64{}, 100, false},
+		{"later step", sql.NullInt64{Int64: 100, Valid: true}, 101, false},
+		{"same step again", sql.NullInt64{Int64: 100, Valid: true}, 100, true},
+		{"older step within skew", sql.NullInt64{Int64: 100, Valid: true}, 99, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := totpStepReplayed(tt.lastStep, tt.step); got != tt.want {
+				t.Errorf("totpStepReplayed(%v, %d) = %v, want %v", tt.lastStep, tt.step, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("Interactive Scraper", "alice", "ABC")
+	for _, part := range []string{"otpauth://totp/Interactive%20Scraper:alice?", "issuer=Interactive%20Scraper", "secret=ABC", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("provisioning URI %q is missing %q", uri, part)
+		}
+	}
+	if strings.Contains(uri, "+") {
+		t.Errorf("provisioning URI %q encodes spaces as +", uri)
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	for _, input := range []string{"abcde-12345", " ABCDE-12345 ", "abcde 12345", "abcde12345"} {
+		if got := normalizeRecoveryCode(input); got != "abcde12345" {
+			t.Errorf("normalizeRecoveryCode(%q) = %q", input, got)
+		}
+	}
+}
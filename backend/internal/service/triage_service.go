@@ -0,0 +1,346 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"interactive-scraper/internal/siem"
+)
+
+const (
+	TriageNew           = "new"
+	TriageInReview      = "in_review"
+	TriageEscalated     = "escalated"
+	TriageFalsePositive = "false_positive"
+	TriageClosed        = "closed"
+)
+
+var (
+	ErrEntryNotFound     = errors.New("entry not found")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrNoteNotFound      = errors.New("note not found")
+	ErrInvalidTransition = errors.New("invalid triage transition")
+	ErrEmptyNote         = errors.New("note body is required")
+)
+
+/*triageTransitions, triage yaşam döngüsünde izin verilen geçişleri tanımlar. Yeni bir
+kayıt önce incelemeye alınır; incelemedeki kayıt yükseltilir (escalated), yanlış pozitif
+olarak işaretlenir veya kapatılır. Yükseltilmiş, yanlış pozitif ya da kapatılmış kayıtlar
+gerektiğinde tekrar incelemeye açılabilir.
+*/
+var triageTransitions = map[string][]string{
+	TriageNew:           {TriageInReview, TriageFalsePositive, TriageClosed},
+	TriageInReview:      {TriageEscalated, TriageFalsePositive, TriageClosed, TriageNew},
+	TriageEscalated:     {TriageInReview, TriageClosed},
+	TriageFalsePositive: {TriageInReview},
+	TriageClosed:        {TriageInReview},
+}
+
+// OpenTriageStatuses are the statuses that still need analyst work
+var OpenTriageStatuses = []string{TriageNew, TriageInReview, TriageEscalated}
+
+func IsValidTriageStatus(status string) bool {
+	_, ok := triageTransitions[status]
+	return ok
+}
+
+func canTransition(from, to string) bool {
+	for _, allowed := range triageTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+type TriageService struct {
+	db          *sql.DB
+	dataService *DataService
+	emitter     *siem.Emitter
+}
+
+func NewTriageService(db *sql.DB, dataService *DataService) *TriageService {
+	return &TriageService{db: db, dataService: dataService}
+}
+
+func (s *TriageService) SetEmitter(emitter *siem.Emitter) {
+	s.emitter = emitter
+}
+
+/*Bu yapı (EntryNote), bir kayıt üzerine analistlerin yazdığı notu temsil eder. ParentID
+dolu olan notlar başka bir nota verilen yanıtlardır; GetNotes notları bu ilişkiye göre
+Replies alanında iç içe döndürür. Yazan kullanıcı silinmişse Author boş kalır.
+*/
+type EntryNote struct {
+	ID        int          `json:"id"`
+	EntryID   int          `json:"entry_id"`
+	ParentID  *int         `json:"parent_id,omitempty"`
+	UserID    *int         `json:"user_id"`
+	Author    string       `json:"author"`
+	Body      string       `json:"body"`
+	CreatedAt time.Time    `json:"created_at"`
+	Replies   []*EntryNote `json:"replies"`
+}
+
+/*Bu fonksiyon, kaydın triage durumunu değiştirir. Geçiş triageTransitions tablosuna
+uymuyorsa ErrInvalidTransition döner. Atanmamış bir kayıt incelemeye alındığında
+işlemi yapan analist otomatik olarak kayda atanır; böylece ekip içinde aynı kaydı iki
+kişinin incelemesi önlenir. Satır FOR UPDATE ile kilitlendiği için eşzamanlı iki geçiş
+birbirinin sonucunu ezmez.
+*/
+func (s *TriageService) TransitionStatus(entryID int, status string, actorID int, actor string) (*DataEntry, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	previous, claimed, err := transitionStatus(tx, entryID, status, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.dataService.GetEntryByID(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if previous != status {
+		s.emitter.Emit(triageEvent(siem.EventStatusChange, entry, previous, status, actor))
+	}
+	if claimed {
+		s.emitter.Emit(triageEvent(siem.EventAssignmentChange, entry, "", actor, actor))
+	}
+	return entry, nil
+}
+
+/*Bu fonksiyon, durum geçişini verilen transaction içinde uygular ve kaydın önceki
+durumunu döndürür. claimed, kaydın bu geçişle işlemi yapan analiste atanıp
+atanmadığını belirtir. Kayıt zaten istenen durumdaysa hiçbir değişiklik yapılmaz.
+*/
+func transitionStatus(tx *sql.Tx, entryID int, status string, actorID int) (string, bool, error) {
+	if !IsValidTriageStatus(status) {
+		return "", false, fmt.Errorf("%w: unknown status %s", ErrInvalidTransition, status)
+	}
+
+	var current string
+	var assigneeID sql.NullInt64
+	err := tx.QueryRow(`
+		SELECT triage_status, assignee_id FROM data_entries WHERE id = $1 FOR UPDATE
+	`, entryID).Scan(&current, &assigneeID)
+	if err == sql.ErrNoRows {
+		return "", false, ErrEntryNotFound
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	if current == status {
+		return current, false, nil
+	}
+	if !canTransition(current, status) {
+		return "", false, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current, status)
+	}
+
+	claimed := status == TriageInReview && !assigneeID.Valid && actorID != 0
+	var newAssignee interface{}
+	if assigneeID.Valid {
+		newAssignee = assigneeID.Int64
+	} else if claimed {
+		newAssignee = actorID
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE data_entries
+		SET triage_status = $1, assignee_id = $2, triage_updated_at = NOW()
+		WHERE id = $3
+	`, status, newAssignee, entryID); err != nil {
+		return "", false, err
+	}
+	return current, claimed, nil
+}
+
+/*Bu fonksiyon, kaydı bir analiste atar veya assigneeID nil ise atamayı kaldırır.
+Atanmak istenen kullanıcı yoksa ErrUserNotFound döner. Atama triage durumunu
+değiştirmez; durum geçişleri TransitionStatus ile yapılır.
+*/
+func (s *TriageService) Assign(entryID int, assigneeID *int, actor string) (*DataEntry, error) {
+	var oldAssignee, newAssignee sql.NullString
+	err := s.db.QueryRow(`
+		UPDATE data_entries e
+		SET assignee_id = $1, triage_updated_at = NOW()
+		FROM (SELECT id, assignee_id FROM data_entries WHERE id = $2 FOR UPDATE) old
+		LEFT JOIN users ou ON ou.id = old.assignee_id
+		WHERE e.id = old.id
+		RETURNING ou.username, (SELECT username FROM users WHERE id = $1)
+	`, nullableInt(assigneeID), entryID).Scan(&oldAssignee, &newAssignee)
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if isForeignKeyViolation(err) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.dataService.GetEntryByID(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if oldAssignee != newAssignee {
+		s.emitter.Emit(triageEvent(siem.EventAssignmentChange, entry, oldAssignee.String, newAssignee.String, actor))
+	}
+	return entry, nil
+}
+
+/*Bu fonksiyon, analistin kuyruğunu döndürür: kendisine atanmış ve verilen durumlarda
+olan kayıtlar. Durum verilmezse açık durumlar (new, in_review, escalated) kullanılır.
+Varsayılan sıralama kritiklik puanına göre azalandır; böylece en acil kayıtlar başta gelir.
+*/
+func (s *TriageService) Queue(userID int, filter EntryFilter, page PageRequest) (*EntryPage, error) {
+	filter.AssigneeID = &userID
+	if len(filter.TriageStatuses) == 0 {
+		filter.TriageStatuses = OpenTriageStatuses
+	}
+	if filter.Sort == "" && filter.Search == "" {
+		filter.Sort = "criticality"
+	}
+	return s.dataService.GetAllEntries(filter, page)
+}
+
+/*Bu fonksiyon, bir kaydın tüm notlarını oluşturulma sırasına göre okur ve parent_id
+ilişkisine göre ağaç yapısına dönüştürür. Üst seviyedeki notlar ve her notun yanıtları
+eskiden yeniye sıralıdır.
+*/
+func (s *TriageService) GetNotes(entryID int) ([]*EntryNote, error) {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM data_entries WHERE id = $1)`, entryID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrEntryNotFound
+	}
+
+	rows, err := s.db.Query(`
+		SELECT n.id, n.entry_id, n.parent_id, n.user_id, COALESCE(u.username, ''), n.body, n.created_at
+		FROM entry_notes n
+		LEFT JOIN users u ON u.id = n.user_id
+		WHERE n.entry_id = $1
+		ORDER BY n.created_at, n.id
+	`, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []*EntryNote
+	byID := make(map[int]*EntryNote)
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, note)
+		byID[note.ID] = note
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	roots := []*EntryNote{}
+	for _, note := range all {
+		if note.ParentID != nil {
+			if parent, ok := byID[*note.ParentID]; ok {
+				parent.Replies = append(parent.Replies, note)
+				continue
+			}
+		}
+		roots = append(roots, note)
+	}
+	return roots, nil
+}
+
+/*Bu fonksiyon, kayda yeni bir not ekler. parentID verilirse not, aynı kayda ait başka
+bir nota yanıt olarak eklenir; üst not bulunamazsa veya başka bir kayda aitse
+ErrNoteNotFound döner. Boş notlar kabul edilmez.
+*/
+func (s *TriageService) AddNote(entryID int, parentID *int, userID int, body string) (*EntryNote, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyNote
+	}
+
+	if parentID != nil {
+		var parentEntry int
+		err := s.db.QueryRow(`SELECT entry_id FROM entry_notes WHERE id = $1`, *parentID).Scan(&parentEntry)
+		if err == sql.ErrNoRows || (err == nil && parentEntry != entryID) {
+			return nil, ErrNoteNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	note, err := scanNote(s.db.QueryRow(`
+		WITH inserted AS (
+			INSERT INTO entry_notes (entry_id, parent_id, user_id, body)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, entry_id, parent_id, user_id, body, created_at
+		)
+		SELECT i.id, i.entry_id, i.parent_id, i.user_id, COALESCE(u.username, ''), i.body, i.created_at
+		FROM inserted i
+		LEFT JOIN users u ON u.id = i.user_id
+	`, entryID, nullableInt(parentID), userID, body))
+	if isForeignKeyViolation(err) {
+		return nil, ErrEntryNotFound
+	}
+	return note, err
+}
+
+func scanNote(row rowScanner) (*EntryNote, error) {
+	var note EntryNote
+	var parentID, userID sql.NullInt64
+	if err := row.Scan(&note.ID, &note.EntryID, &parentID, &userID, &note.Author, &note.Body, &note.CreatedAt); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		note.ParentID = &id
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		note.UserID = &id
+	}
+	note.Replies = []*EntryNote{}
+	return &note, nil
+}
+
+func triageEvent(eventType siem.EventType, entry *DataEntry, oldValue, newValue, actor string) siem.Event {
+	return siem.Event{
+		Type:             eventType,
+		EntryID:          entry.ID,
+		SourceID:         entry.SourceID,
+		SourceName:       entry.SourceName,
+		SourceURL:        entry.SourceURL,
+		Title:            entry.Title,
+		Category:         entry.Category,
+		CriticalityScore: entry.CriticalityScore,
+		OldValue:         oldValue,
+		NewValue:         newValue,
+		Actor:            actor,
+	}
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
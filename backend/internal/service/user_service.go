@@ -0,0 +1,325 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"interactive-scraper/internal/database"
+)
+
+var (
+	ErrInvalidUser   = errors.New("invalid user")
+	ErrLastAdmin     = errors.New("cannot remove the last admin")
+	ErrWrongPassword = errors.New("current password is incorrect")
+)
+
+type User struct {
+	ID                     int          `json:"id"`
+	Username               string       `json:"username"`
+	Role                   string       `json:"role"`
+	AuthProvider           string       `json:"auth_provider"`
+	Permissions            []Permission `json:"permissions"`
+	PasswordChangeRequired bool         `json:"password_change_required"`
+	CreatedAt              time.Time    `json:"created_at"`
+	UpdatedAt              *time.Time   `json:"updated_at,omitempty"`
+}
+
+type CreateUserInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// UpdateUserInput only changes the fields that are set
+type UpdateUserInput struct {
+	Role     *string `json:"role"`
+	Password *string `json:"password"`
+}
+
+// ChangePasswordInput is the body of POST /api/users/me/password
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type UserService struct {
+	db     *sql.DB
+	policy PasswordPolicy
+}
+
+func NewUserService(db *sql.DB) *UserService {
+	return &UserService{db: db, policy: DefaultPasswordPolicy()}
+}
+
+func (s *UserService) SetPasswordPolicy(policy PasswordPolicy) {
+	s.policy = policy
+}
+
+const userColumns = `id, username, role, auth_provider, must_change_password, created_at, updated_at`
+
+func scanUser(row rowScanner) (*User, error) {
+	var user User
+	var updatedAt sql.NullTime
+	if err := row.Scan(&user.ID, &user.Username, &user.Role, &user.AuthProvider, &user.PasswordChangeRequired, &user.CreatedAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if updatedAt.Valid {
+		user.UpdatedAt = &updatedAt.Time
+	}
+	user.Permissions = RolePermissions(user.Role)
+	return &user, nil
+}
+
+func (s *UserService) GetAllUsers() ([]User, error) {
+	rows, err := s.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (s *UserService) GetUserByID(id int) (*User, error) {
+	user, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+func (s *UserService) GetUserByUsername(username string) (*User, error) {
+	user, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = $1`, username))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+/*Bu fonksiyon, yeni bir kullanıcı oluşturur. Kullanıcı adı 3-100 karakter olmalı ve
+yalnızca harf, rakam, nokta, tire, alt çizgi veya @ içermelidir. Rol verilmezse
+analyst kabul edilir. Şifre politikaya uymalıdır ve bcrypt ile hash'lenerek saklanır;
+şifreyi yönetici belirlediği için kullanıcıdan ilk girişte şifresini değiştirmesi istenir.
+Aynı kullanıcı adı zaten varsa ErrInvalidUser döner.
+*/
+func (s *UserService) CreateUser(in CreateUserInput) (*User, error) {
+	in.Username = strings.TrimSpace(in.Username)
+	if len(in.Username) < 3 || len(in.Username) > 100 || strings.IndexFunc(in.Username, invalidUsernameRune) >= 0 {
+		return nil, fmt.Errorf("%w: username must be 3-100 characters of letters, digits, '.', '-', '_' or '@'", ErrInvalidUser)
+	}
+	if in.Role == "" {
+		in.Role = RoleAnalyst
+	}
+	if !IsValidRole(in.Role) {
+		return nil, fmt.Errorf("%w: unknown role %s", ErrInvalidUser, in.Role)
+	}
+	if err := s.policy.Validate(in.Username, in.Password); err != nil {
+		return nil, err
+	}
+
+	hash, err := database.HashPassword(in.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := scanUser(s.db.QueryRow(`
+		INSERT INTO users (username, password_hash, role, must_change_password)
+		VALUES ($1, $2, $3, TRUE)
+		RETURNING `+userColumns, in.Username, hash, in.Role))
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: username %s is already taken", ErrInvalidUser, in.Username)
+	}
+	return user, err
+}
+
+func invalidUsernameRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case r == '.' || r == '-' || r == '_' || r == '@':
+		return false
+	}
+	return true
+}
+
+/*Bu fonksiyon, kullanıcının rolünü ve/veya şifresini günceller. Son admin kullanıcısının
+rolü düşürülemez; aksi halde sistemde kullanıcı yönetebilecek kimse kalmaz. Kontrol ve
+güncelleme, admin satırları kilitlenerek aynı transaction içinde yapılır. Yönetici
+şifreyi değiştirdiğinde kullanıcının tüm açık oturumları ve API anahtarları aynı
+transaction içinde iptal edilir ve kullanıcıdan bir sonraki girişte şifresini
+değiştirmesi istenir.
+*/
+func (s *UserService) UpdateUser(id int, in UpdateUserInput) (*User, error) {
+	if in.Role != nil && !IsValidRole(*in.Role) {
+		return nil, fmt.Errorf("%w: unknown role %s", ErrInvalidUser, *in.Role)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var currentRole, username string
+	err = tx.QueryRow(`SELECT role, username FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&currentRole, &username)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hash interface{}
+	if in.Password != nil {
+		if err := s.policy.Validate(username, *in.Password); err != nil {
+			return nil, err
+		}
+		h, err := database.HashPassword(*in.Password)
+		if err != nil {
+			return nil, err
+		}
+		hash = h
+	}
+
+	var role interface{}
+	if in.Role != nil {
+		role = *in.Role
+		if currentRole == RoleAdmin && *in.Role != RoleAdmin {
+			if err := ensureAnotherAdmin(tx, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	user, err := scanUser(tx.QueryRow(`
+		UPDATE users
+		SET role = COALESCE($1, role),
+		    password_hash = COALESCE($2, password_hash),
+		    must_change_password = must_change_password OR $2 IS NOT NULL,
+		    updated_at = NOW()
+		WHERE id = $3
+		RETURNING `+userColumns, role, hash, id))
+	if err != nil {
+		return nil, err
+	}
+	if hash != nil {
+		if _, err := revokeUserSessions(tx, id); err != nil {
+			return nil, err
+		}
+		if _, err := revokeUserAPIKeys(tx, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+/*Bu fonksiyon, kullanıcının kendi şifresini değiştirmesini sağlar. Mevcut şifre doğru
+olmalı, yeni şifre politikaya uymalı ve mevcut şifreyle aynı olmamalıdır. Başarılı
+değişiklikte şifre değiştirme zorunluluğu kalkar ve keepSessionID dışındaki tüm oturumlar
+iptal edilir; böylece kullanıcı mevcut oturumunda kalırken diğer cihazlardan çıkarılır.
+*/
+func (s *UserService) ChangePassword(id int, in ChangePasswordInput, keepSessionID string) error {
+	var username, currentHash string
+	err := s.db.QueryRow(`SELECT username, password_hash FROM users WHERE id = $1`, id).Scan(&username, &currentHash)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !database.CheckPasswordHash(in.CurrentPassword, currentHash) {
+		return ErrWrongPassword
+	}
+	if in.NewPassword == in.CurrentPassword {
+		return fmt.Errorf("%w: new password must differ from the current password", ErrInvalidUser)
+	}
+	if err := s.policy.Validate(username, in.NewPassword); err != nil {
+		return err
+	}
+	hash, err := database.HashPassword(in.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET password_hash = $1, must_change_password = FALSE, updated_at = NOW()
+		WHERE id = $2 AND password_hash = $3
+	`, hash, id, currentHash)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Changed concurrently since it was read
+		return ErrWrongPassword
+	}
+	if _, err := tx.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL AND id != $2
+	`, id, keepSessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *UserService) DeleteUser(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow(`SELECT role FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&role)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if role == RoleAdmin {
+		if err := ensureAnotherAdmin(tx, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM users WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func ensureAnotherAdmin(tx *sql.Tx, exceptID int) error {
+	var admins int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM (SELECT id FROM users WHERE role = 'admin' AND id != $1 FOR UPDATE) a
+	`, exceptID).Scan(&admins)
+	if err != nil {
+		return err
+	}
+	if admins == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
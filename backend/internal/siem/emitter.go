@@ -0,0 +1,238 @@
+package siem
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
+)
+
+var logger = logging.For("siem")
+
+/*Bu yapı (Config), SIEM çıktı alt sisteminin ayarlarını tutar. Format "cef" veya "json",
+Transport ise "udp", "tcp", "tls" (syslog) ya da "file" (döndürülen yerel dosya) olabilir.
+FieldMap, kanonik alan adlarını SIEM'in beklediği anahtarlara eşler. AlertThreshold,
+bu puan ve üzerindeki kayıtlar için ek bir "alert" olayı üretilmesini sağlar (0 ise kapalı).
+*/
+type Config struct {
+	Enabled        bool
+	Format         string
+	Transport      string
+	Address        string
+	Facility       int
+	TLSCAFile      string
+	TLSInsecure    bool
+	FilePath       string
+	FileMaxSizeMB  int64
+	FileMaxBackups int
+	FieldMap       map[string]string
+	AlertThreshold int
+	QueueSize      int
+}
+
+/*Bu fonksiyon, config.SIEMConfig bölümünü emitter ayarlarına çevirir: syslog facility
+adı sayıya, siem.field_map ("title:msg,category:cs5,actor:-" biçimindeki alan:anahtar
+çiftleri) ise eşleme tablosuna dönüştürülür. siem.enabled false ise diğer alanlar
+yorumlanmaz.
+*/
+func ConfigFrom(settings config.SIEMConfig) (Config, error) {
+	cfg := Config{
+		Enabled:        settings.Enabled,
+		Format:         strings.ToLower(settings.Format),
+		Transport:      strings.ToLower(settings.Transport),
+		Address:        settings.Address,
+		TLSCAFile:      settings.TLSCAFile,
+		TLSInsecure:    settings.TLSInsecure,
+		FilePath:       settings.FilePath,
+		FieldMap:       map[string]string{},
+		FileMaxSizeMB:  int64(settings.FileMaxSizeMB),
+		FileMaxBackups: settings.FileMaxBackups,
+		AlertThreshold: settings.AlertThreshold,
+		QueueSize:      1000,
+	}
+	if !cfg.Enabled {
+		return cfg, nil
+	}
+
+	facility, err := parseFacility(settings.Facility)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Facility = facility
+
+	fieldMap, err := ParseFieldMap(settings.FieldMap)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.FieldMap = fieldMap
+
+	return cfg, nil
+}
+
+// ParseFieldMap parses "field:key,field:key" pairs
+func ParseFieldMap(s string) (map[string]string, error) {
+	fieldMap := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid SIEM field mapping: %q (expected field:key)", pair)
+		}
+		fieldMap[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return fieldMap, nil
+}
+
+/*Bu yapı (Emitter), servislerden gelen olayları bir kuyruk üzerinden arka plandaki tek
+bir goroutine'e aktarır; goroutine olayları formatlayıp seçilen taşıma katmanına yazar.
+Emit çağrısı asla bloklamaz: kuyruk doluysa olay düşürülür ve loglanır, böylece SIEM
+tarafındaki bir yavaşlık scraper'ı veya API isteklerini bekletmez. nil bir Emitter
+üzerinde yapılan çağrılar hiçbir şey yapmaz; SIEM kapalıyken servisler ek kontrol
+yazmak zorunda kalmaz.
+*/
+type Emitter struct {
+	formatter      Formatter
+	writer         writer
+	alertThreshold int
+	events         chan Event
+	done           chan struct{}
+	mu             sync.RWMutex
+	closed         bool
+}
+
+func NewEmitter(cfg Config) (*Emitter, error) {
+	formatter, err := NewFormatter(cfg.Format, cfg.FieldMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var w writer
+	switch cfg.Transport {
+	case "udp", "tcp", "tls":
+		w, err = newSyslogWriter(cfg)
+	case "file":
+		w, err = newFileWriter(cfg)
+	default:
+		err = fmt.Errorf("unsupported SIEM transport: %s (expected udp, tcp, tls or file)", cfg.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+
+	em := &Emitter{
+		formatter:      formatter,
+		writer:         w,
+		alertThreshold: cfg.AlertThreshold,
+		events:         make(chan Event, queueSize),
+		done:           make(chan struct{}),
+	}
+	go em.run()
+
+	logger.Info("SIEM output enabled", "format", cfg.Format, "transport", cfg.Transport)
+	return em, nil
+}
+
+// NewEmitterFrom returns nil (and no error) when SIEM output is disabled
+func NewEmitterFrom(settings config.SIEMConfig) (*Emitter, error) {
+	cfg, err := ConfigFrom(settings)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return NewEmitter(cfg)
+}
+
+/*Bu fonksiyon, bir olayı kuyruğa ekler. Olay yeni bir kayıt veya kritiklik değişikliği
+ise ve alarm eşiği aşılıyorsa, aynı bilgilerle ek bir "alert" olayı da üretilir. Kritiklik
+değişikliğinde alarm yalnızca eşiğin altından üstüne geçişte tetiklenir; zaten kritik
+olan bir kaydın puanının 90'dan 95'e çıkması yeni bir alarm oluşturmaz.
+*/
+func (em *Emitter) Emit(e Event) {
+	if em == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	em.enqueue(e)
+
+	if em.shouldAlert(e) {
+		alert := e
+		alert.Type = EventAlert
+		alert.Message = fmt.Sprintf("Criticality %d reached alert threshold %d", e.CriticalityScore, em.alertThreshold)
+		em.enqueue(alert)
+	}
+}
+
+func (em *Emitter) shouldAlert(e Event) bool {
+	if em.alertThreshold <= 0 || e.CriticalityScore < em.alertThreshold {
+		return false
+	}
+	switch e.Type {
+	case EventNewEntry:
+		return true
+	case EventCriticalityChange:
+		previous, err := strconv.Atoi(e.OldValue)
+		return err != nil || previous < em.alertThreshold
+	}
+	return false
+}
+
+func (em *Emitter) enqueue(e Event) {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+	if em.closed {
+		return
+	}
+	select {
+	case em.events <- e:
+	default:
+		logger.Warn("event queue full, dropping event", "event_type", e.Type, "entry_id", e.EntryID)
+	}
+}
+
+func (em *Emitter) run() {
+	defer close(em.done)
+	for e := range em.events {
+		payload, err := em.formatter.Format(e)
+		if err != nil {
+			logger.Error("failed to format event", "event_type", e.Type, logging.Err(err))
+			continue
+		}
+		if err := em.writer.Write(e, payload); err != nil {
+			logger.Error("failed to write event", "event_type", e.Type, "entry_id", e.EntryID, logging.Err(err))
+		}
+	}
+}
+
+// Close drains the queue and closes the underlying writer
+func (em *Emitter) Close() error {
+	if em == nil {
+		return nil
+	}
+	em.mu.Lock()
+	if em.closed {
+		em.mu.Unlock()
+		return nil
+	}
+	em.closed = true
+	close(em.events)
+	em.mu.Unlock()
+
+	<-em.done
+	return em.writer.Close()
+}
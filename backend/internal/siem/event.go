@@ -0,0 +1,72 @@
+package siem
+
+import (
+	"time"
+)
+
+type EventType string
+
+const (
+	EventNewEntry          EventType = "new_entry"
+	EventCriticalityChange EventType = "criticality_change"
+	EventCategoryChange    EventType = "category_change"
+	EventAlert             EventType = "alert"
+	EventSavedSearchMatch  EventType = "saved_search_match"
+	EventStatusChange      EventType = "status_change"
+	EventAssignmentChange  EventType = "assignment_change"
+)
+
+/*Bu yapı (Event), SIEM'e gönderilecek tek bir olayı temsil eder. Yeni bir kayıt
+eklendiğinde, bir kaydın kritiklik puanı, kategorisi, triage durumu veya atanan
+analisti değiştiğinde ya da alarm eşiği aşıldığında doldurulur. OldValue ve NewValue alanları yalnızca değişiklik
+olaylarında kullanılır; Actor ise değişikliği yapan kullanıcıyı (veya "system") tutar.
+*/
+type Event struct {
+	Type             EventType
+	Time             time.Time
+	EntryID          int
+	SourceID         int
+	SourceName       string
+	SourceURL        string
+	Title            string
+	Category         string
+	CriticalityScore int
+	OldValue         string
+	NewValue         string
+	Actor            string
+	Message          string
+}
+
+/*Bu fonksiyon, olayı formatlayıcıların kullandığı düz alan haritasına dönüştürür.
+Anahtarlar kanonik alan adlarıdır (entry_id, title, criticality...); SIEM tarafındaki
+isimlere dönüşüm, formatlayıcıdaki alan eşleme tablosu üzerinden yapılır. Boş
+alanlar haritaya eklenmez, böylece çıktıda gereksiz anahtarlar yer almaz.
+*/
+func (e Event) fields() map[string]interface{} {
+	f := map[string]interface{}{
+		"event_type":  string(e.Type),
+		"timestamp":   e.Time.UTC().Format(time.RFC3339),
+		"criticality": e.CriticalityScore,
+	}
+	if e.EntryID != 0 {
+		f["entry_id"] = e.EntryID
+	}
+	if e.SourceID != 0 {
+		f["source_id"] = e.SourceID
+	}
+	setIfNotEmpty(f, "source_name", e.SourceName)
+	setIfNotEmpty(f, "url", e.SourceURL)
+	setIfNotEmpty(f, "title", e.Title)
+	setIfNotEmpty(f, "category", e.Category)
+	setIfNotEmpty(f, "old_value", e.OldValue)
+	setIfNotEmpty(f, "new_value", e.NewValue)
+	setIfNotEmpty(f, "actor", e.Actor)
+	setIfNotEmpty(f, "message", e.Message)
+	return f
+}
+
+func setIfNotEmpty(f map[string]interface{}, key, value string) {
+	if value != "" {
+		f[key] = value
+	}
+}
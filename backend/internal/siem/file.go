@@ -0,0 +1,103 @@
+package siem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+/*Bu yapı (fileWriter), olayları yerel bir dosyaya satır satır (JSON-lines veya CEF)
+yazar ve dosya belirlenen boyutu aştığında döndürür (rotate). Eski dosyalar
+path.1, path.2 ... şeklinde kaydırılır ve maxBackups sayısını aşanlar silinir. Böylece
+SIEM ajanı (Filebeat, NXLog vb.) dosyanın sonunu takip ederek olayları toplayabilir.
+*/
+type fileWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileWriter(cfg Config) (*fileWriter, error) {
+	if cfg.FilePath == "" {
+		return nil, fmt.Errorf("SIEM_FILE_PATH is required for file transport")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.FilePath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create SIEM output directory: %v", err)
+	}
+
+	w := &fileWriter{
+		path:       cfg.FilePath,
+		maxSize:    cfg.FileMaxSizeMB * 1024 * 1024,
+		maxBackups: cfg.FileMaxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *fileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open SIEM output file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat SIEM output file: %v", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *fileWriter) Write(e Event, payload []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line := append(payload, '\n')
+	if w.maxSize > 0 && w.size+int64(len(line)) > w.maxSize && w.size > 0 {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	return err
+}
+
+func (w *fileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close SIEM output file: %v", err)
+	}
+
+	if w.maxBackups <= 0 {
+		os.Remove(w.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", w.path, w.maxBackups))
+		for i := w.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate SIEM output file: %v", err)
+		}
+	}
+
+	return w.open()
+}
+
+func (w *fileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,173 @@
+package siem
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	cefVendor  = "Interactive Scraper"
+	cefProduct = "CTI Dashboard"
+	cefVersion = "1.0"
+)
+
+type Formatter interface {
+	Format(e Event) ([]byte, error)
+}
+
+// defaultCEFFieldMap maps canonical event fields to CEF extension keys
+var defaultCEFFieldMap = map[string]string{
+	"event_type":  "act",
+	"timestamp":   "rt",
+	"entry_id":    "externalId",
+	"source_id":   "cn2",
+	"source_name": "cs1",
+	"url":         "request",
+	"title":       "msg",
+	"category":    "cat",
+	"criticality": "cn1",
+	"old_value":   "cs2",
+	"new_value":   "cs3",
+	"actor":       "suser",
+	"message":     "cs4",
+}
+
+var cefCustomKeyRegex = regexp.MustCompile(`^(cs|cn|cfp|flexString|flexNumber)\d+$`)
+
+/*Bu fonksiyon, yapılandırılan formata göre uygun formatlayıcıyı oluşturur. "cef" için
+ArcSight CEF, "json" için tek satırlık JSON üretilir. fieldMap, kanonik alan adlarını
+SIEM tarafındaki anahtarlara eşler; bir alan "-" değerine eşlenirse çıktıdan çıkarılır.
+CEF için kullanıcı eşlemesi varsayılan eşlemenin üzerine yazılır.
+*/
+func NewFormatter(format string, fieldMap map[string]string) (Formatter, error) {
+	switch strings.ToLower(format) {
+	case "cef":
+		mapping := make(map[string]string, len(defaultCEFFieldMap))
+		for k, v := range defaultCEFFieldMap {
+			mapping[k] = v
+		}
+		for k, v := range fieldMap {
+			mapping[k] = v
+		}
+		return &cefFormatter{fieldMap: mapping}, nil
+	case "json", "":
+		return &jsonFormatter{fieldMap: fieldMap}, nil
+	default:
+		return nil, fmt.Errorf("unsupported SIEM format: %s (expected cef or json)", format)
+	}
+}
+
+type jsonFormatter struct {
+	fieldMap map[string]string
+}
+
+func (f *jsonFormatter) Format(e Event) ([]byte, error) {
+	out := make(map[string]interface{})
+	for key, value := range e.fields() {
+		target := key
+		if mapped, ok := f.fieldMap[key]; ok {
+			target = mapped
+		}
+		if target == "-" {
+			continue
+		}
+		out[target] = value
+	}
+	return json.Marshal(out)
+}
+
+type cefFormatter struct {
+	fieldMap map[string]string
+}
+
+/*Bu fonksiyon, olayı CEF:0 formatında tek satıra dönüştürür. Başlıktaki imza kimliği
+olay tipidir, önem derecesi ise kritiklik puanının 0-10 aralığına indirgenmiş halidir.
+Uzantı alanları eşleme tablosuna göre adlandırılır ve alfabetik sırayla yazılır; csN/cnN
+gibi özel alanlar için SIEM'in anlamlandırabilmesi için ilgili Label alanı da eklenir.
+*/
+func (f *cefFormatter) Format(e Event) ([]byte, error) {
+	fields := e.fields()
+	fields["timestamp"] = e.Time.UnixMilli()
+
+	ext := make(map[string]string)
+	for key, value := range fields {
+		target, ok := f.fieldMap[key]
+		if !ok {
+			target = key
+		}
+		if target == "-" {
+			continue
+		}
+		ext[target] = fmt.Sprint(value)
+		if cefCustomKeyRegex.MatchString(target) {
+			ext[target+"Label"] = key
+		}
+	}
+
+	keys := make([]string, 0, len(ext))
+	for k := range ext {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+escapeCEFExtension(ext[k]))
+	}
+
+	severity := e.CriticalityScore / 10
+	if severity > 10 {
+		severity = 10
+	}
+
+	line := fmt.Sprintf("CEF:0|%s|%s|%s|%s|%s|%d|%s",
+		escapeCEFHeader(cefVendor),
+		escapeCEFHeader(cefProduct),
+		escapeCEFHeader(cefVersion),
+		escapeCEFHeader(string(e.Type)),
+		escapeCEFHeader(eventName(e)),
+		severity,
+		strings.Join(parts, " "),
+	)
+	return []byte(line), nil
+}
+
+func eventName(e Event) string {
+	if e.Message != "" {
+		return e.Message
+	}
+	switch e.Type {
+	case EventNewEntry:
+		return "New entry collected"
+	case EventCriticalityChange:
+		return "Entry criticality changed"
+	case EventCategoryChange:
+		return "Entry category changed"
+	case EventAlert:
+		return "High criticality entry"
+	case EventSavedSearchMatch:
+		return "Saved search matched new entry"
+	case EventStatusChange:
+		return "Entry triage status changed"
+	case EventAssignmentChange:
+		return "Entry assignee changed"
+	}
+	return string(e.Type)
+}
+
+func escapeCEFHeader(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+func escapeCEFExtension(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "=", `\=`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return strings.ReplaceAll(s, "\r", `\r`)
+}
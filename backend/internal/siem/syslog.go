@@ -0,0 +1,191 @@
+package siem
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const syslogAppName = "interactive-scraper"
+
+// Syslog severities (RFC 5424 section 6.2.1)
+const (
+	severityAlert    = 1
+	severityCritical = 2
+	severityWarning  = 4
+	severityNotice   = 5
+	severityInfo     = 6
+)
+
+type writer interface {
+	Write(e Event, payload []byte) error
+	Close() error
+}
+
+/*Bu yapı (syslogWriter), olayları RFC 5424 formatında bir syslog sunucusuna gönderir.
+UDP'de her mesaj tek bir datagram olarak, TCP ve TLS'te ise RFC 6587 octet-counting
+çerçevelemesiyle iletilir. Bağlantı koptuğunda bir sonraki yazmada yeniden kurulur;
+böylece SIEM tarafındaki geçici kesintiler servisin kalıcı olarak susmasına yol açmaz.
+*/
+type syslogWriter struct {
+	mu        sync.Mutex
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	facility  int
+	hostname  string
+	conn      net.Conn
+}
+
+func newSyslogWriter(cfg Config) (*syslogWriter, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("SIEM_ADDRESS is required for %s syslog transport", cfg.Transport)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	w := &syslogWriter{
+		network:  cfg.Transport,
+		address:  cfg.Address,
+		facility: cfg.Facility,
+		hostname: hostname,
+	}
+
+	if cfg.Transport == "tls" {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecure}
+		if cfg.TLSCAFile != "" {
+			pem, err := os.ReadFile(cfg.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read SIEM CA file: %v", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in SIEM CA file %s", cfg.TLSCAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if host, _, err := net.SplitHostPort(cfg.Address); err == nil {
+			tlsConfig.ServerName = host
+		}
+		w.tlsConfig = tlsConfig
+	}
+
+	return w, nil
+}
+
+func (w *syslogWriter) connect() error {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var conn net.Conn
+	var err error
+	switch w.network {
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	default:
+		conn, err = dialer.Dial(w.network, w.address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog %s://%s: %v", w.network, w.address, err)
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *syslogWriter) Write(e Event, payload []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msg := w.frame(e, payload)
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err := w.connect(); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		w.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := w.conn.Write(msg); err != nil {
+			lastErr = err
+			w.conn.Close()
+			w.conn = nil
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
+
+/*Bu fonksiyon, olay yükünü RFC 5424 başlığıyla sarar: <PRI>1 ZAMAN HOST UYGULAMA
+PID MSGID - MESAJ. PRI değeri tesis (facility) ve olayın kritikliğinden türetilen önem
+derecesinden hesaplanır; MSGID olarak olay tipi kullanılır. Akış tabanlı taşımalarda
+mesajın başına bayt uzunluğu eklenir.
+*/
+func (w *syslogWriter) frame(e Event, payload []byte) []byte {
+	pri := w.facility*8 + syslogSeverity(e)
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ",
+		pri,
+		e.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.hostname,
+		syslogAppName,
+		os.Getpid(),
+		string(e.Type),
+	)
+	msg := append([]byte(header), payload...)
+
+	if w.network == "udp" {
+		return msg
+	}
+	return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func syslogSeverity(e Event) int {
+	if e.Type == EventAlert {
+		return severityAlert
+	}
+	switch {
+	case e.CriticalityScore >= 80:
+		return severityCritical
+	case e.CriticalityScore >= 60:
+		return severityWarning
+	case e.Type != EventNewEntry:
+		return severityNotice
+	}
+	return severityInfo
+}
+
+// facility names as used by most syslog daemons
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func parseFacility(name string) (int, error) {
+	facility, ok := syslogFacilities[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility: %s", name)
+	}
+	return facility, nil
+}
@@ -0,0 +1,245 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/metrics"
+)
+
+const (
+	queueSize     = 2048
+	batchSize     = 512
+	flushInterval = 5 * time.Second
+)
+
+var logger = logging.For("tracing")
+
+var spansDroppedTotal = metrics.NewCounterVec("tracing_spans_dropped_total",
+	"Spans that were not exported, by reason (queue_full or export_failed).", "reason")
+
+/*Bu yapı (exporter), bitmiş span'leri bir kuyrukta toplayıp arka plandaki tek bir
+goroutine ile collector'a gönderir. Kuyruk batchSize span'e ulaştığında veya her
+flushInterval'de bir gönderim yapılır. Kuyruk doluysa ya da collector'a ulaşılamıyorsa
+span'ler atılır ve tracing_spans_dropped_total sayacına yazılır; izleme hiçbir zaman
+isteklerin veya taramanın önünde beklemez. Collector hataları log'u doldurmasın diye
+yalnızca ilk hata ve düzelme loglanır.
+*/
+type exporter struct {
+	url      string
+	resource []Attr
+	client   *http.Client
+	queue    chan *Span
+	done     chan struct{}
+	failing  bool
+	// mu guards closed so that no span is sent on the queue after shutdown closes it
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newExporter(url, serviceName string) *exporter {
+	e := &exporter{
+		url:      url,
+		resource: []Attr{String("service.name", serviceName), String("telemetry.sdk.language", "go")},
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan *Span, queueSize),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	logger.Info("tracing enabled", "endpoint", url, "service_name", serviceName)
+	return e
+}
+
+func (e *exporter) enqueue(s *Span) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		// A span ending after Shutdown is dropped
+		return
+	}
+	select {
+	case e.queue <- s:
+	default:
+		spansDroppedTotal.Inc("queue_full")
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	for {
+		select {
+		case s, ok := <-e.queue:
+			if !ok {
+				e.export(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		e.export(batch)
+		batch = batch[:0]
+	}
+}
+
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *exporter) export(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	err := e.post(batch)
+	if err != nil {
+		spansDroppedTotal.Add(float64(len(batch)), "export_failed")
+		if !e.failing {
+			logger.Warn("failed to export spans, dropping them until the collector is reachable",
+				"endpoint", e.url, logging.Err(err))
+		}
+	} else if e.failing {
+		logger.Info("span export recovered", "endpoint", e.url)
+	}
+	e.failing = err != nil
+}
+
+func (e *exporter) post(batch []*Span) error {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = s.otlp()
+	}
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(e.resource)},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "interactive-scraper"}, Spans: spans}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// OTLP/HTTP JSON encoding, see opentelemetry-proto's trace service
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	// Code 0 is unset, 2 is error
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.sc.traceID[:]),
+		SpanID:            hex.EncodeToString(s.sc.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attrs),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if s.errorMsg != "" {
+		span.Status = otlpStatus{Code: 2, Message: s.errorMsg}
+	}
+	return span
+}
+
+func otlpAttributes(attrs []Attr) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value map[string]interface{}
+		switch v := attr.Value.(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case int64:
+			// OTLP JSON encodes 64-bit integers as strings
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, otlpKeyValue{Key: attr.Key, Value: value})
+	}
+	return out
+}
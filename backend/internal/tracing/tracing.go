@@ -0,0 +1,232 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"interactive-scraper/internal/config"
+)
+
+// SpanKind follows the OTLP numbering
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Attr is a span attribute; use String, Int, Float64 or Bool to build one
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attr          { return Attr{key, value} }
+func Int(key string, value int) Attr         { return Attr{key, int64(value)} }
+func Float64(key string, value float64) Attr { return Attr{key, value} }
+func Bool(key string, value bool) Attr       { return Attr{key, value} }
+
+// spanContext identifies a span across process boundaries
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool
+}
+
+/*Bu yapı (Span), izlenen tek bir işlemi (bir HTTP isteği, bir tarama aşaması, bir AI
+çağrısı) temsil eder. Start ile başlatılır, End ile bitirilir ve yalnızca örnekleme
+kararı olumluysa dışa aktarılır. İzleme kapalıyken Start nil döner; tüm metotlar nil
+span üzerinde hiçbir şey yapmaz, bu yüzden çağıranların izlemenin açık olup olmadığını
+kontrol etmesine gerek yoktur.
+*/
+type Span struct {
+	sc       spanContext
+	parentID [8]byte
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu       sync.Mutex
+	end      time.Time
+	attrs    []Attr
+	errorMsg string
+	ended    bool
+}
+
+// tracer is the active configuration, nil while tracing is disabled
+type tracer struct {
+	sampleRatio float64
+	exporter    *exporter
+}
+
+var active atomic.Pointer[tracer]
+
+/*Bu fonksiyon, izlemeyi merkezi yapılandırmaya göre başlatır. tracing.enabled kapalıysa
+(varsayılan) hiçbir şey yapılmaz ve Start her yerde nil span döner, yani izlemenin
+maliyeti bir atomic okumadan ibarettir. Açıksa span'ler bellekte kuyruğa alınır ve arka
+planda toplu olarak OTLP/HTTP (JSON) ile tracing.endpoint adresindeki collector'a
+gönderilir. Süreç kapanmadan önce Shutdown çağrılarak kuyruktaki span'ler gönderilmelidir.
+*/
+func Setup(cfg config.TracingConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	active.Store(&tracer{
+		sampleRatio: cfg.SampleRatio,
+		exporter:    newExporter(strings.TrimRight(cfg.Endpoint, "/")+"/v1/traces", cfg.ServiceName),
+	})
+}
+
+// Shutdown exports the queued spans and stops tracing
+func Shutdown(ctx context.Context) error {
+	t := active.Swap(nil)
+	if t == nil {
+		return nil
+	}
+	return t.exporter.shutdown(ctx)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+/*Bu fonksiyon, context'teki span'in (veya Extract ile alınan uzak üst span'in) altında
+yeni bir span başlatır ve span'i taşıyan context'i döndürür. Üst span yoksa yeni bir iz
+(trace) başlar ve tracing.sample_ratio oranında kaydedilir; üst span varsa onun örnekleme
+kararı izlenir, böylece bir iz ya bütünüyle kaydedilir ya da hiç kaydedilmez.
+*/
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...Attr) (context.Context, *Span) {
+	t := active.Load()
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, kind: kind, start: time.Now(), attrs: attrs}
+	if parent, ok := parentContext(ctx); ok {
+		span.sc.traceID = parent.traceID
+		span.sc.sampled = parent.sampled
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.sc.traceID[:])
+		// The low 8 bytes of a random trace ID are uniform, so the decision is stable per trace
+		span.sc.sampled = float64(binary.BigEndian.Uint64(span.sc.traceID[8:])>>11)/(1<<53) < t.sampleRatio
+	}
+	rand.Read(span.sc.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span started in ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func parentContext(ctx context.Context) (spanContext, bool) {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok && span != nil {
+		return span.sc, true
+	}
+	sc, ok := ctx.Value(remoteKey{}).(spanContext)
+	return sc, ok
+}
+
+// SetName renames the span, e.g. once the HTTP route is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed; a nil error is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errorMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export; later calls do nothing
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if t := active.Load(); t != nil && s.sc.sampled {
+		t.exporter.enqueue(s)
+	}
+}
+
+// TraceID returns the hex trace ID of the span in ctx, or ""
+func TraceID(ctx context.Context) string {
+	if sc, ok := parentContext(ctx); ok && active.Load() != nil {
+		return hex.EncodeToString(sc.traceID[:])
+	}
+	return ""
+}
+
+/*Bu fonksiyon, context'teki span'i W3C Trace Context standardındaki traceparent
+başlığı olarak giden bir HTTP isteğine ekler; böylece AI servisi gibi izleme destekleyen
+alt servislerin span'leri aynı izin altında görünür.
+*/
+func Inject(ctx context.Context, header http.Header) {
+	sc, ok := parentContext(ctx)
+	if !ok || active.Load() == nil {
+		return
+	}
+	flags := "00"
+	if sc.sampled {
+		flags = "01"
+	}
+	header.Set("traceparent", fmt.Sprintf("00-%x-%x-%s", sc.traceID, sc.spanID, flags))
+}
+
+// Extract returns ctx carrying the caller's span from a traceparent header, if valid
+func Extract(ctx context.Context, header http.Header) context.Context {
+	parts := strings.Split(header.Get("traceparent"), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+	var sc spanContext
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(sc.traceID[:], []byte(parts[1])); err != nil || sc.traceID == [16]byte{} {
+		return ctx
+	}
+	if _, err := hex.Decode(sc.spanID[:], []byte(parts[2])); err != nil || sc.spanID == [8]byte{} {
+		return ctx
+	}
+	sc.sampled = flags[0]&1 == 1
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
@@ -1,43 +1,197 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"interactive-scraper/internal/ai"
 	"interactive-scraper/internal/api"
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/config"
 	"interactive-scraper/internal/database"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/metrics"
 	"interactive-scraper/internal/scraper"
 	"interactive-scraper/internal/service"
+	"interactive-scraper/internal/siem"
+	"interactive-scraper/internal/tracing"
 )
 
+const usage = `usage: interactive-scraper [command] [flags]
+
+commands:
+  serve                      run the API server and the scraper (default)
+  migrate up|down|status     manage the database schema
+  scrape --source N          scrape one source now and print the result
+  user create|reset-password|set-role
+                             manage accounts without an HTTP session
+  export --format F          export entries as json, csv or stix
+  sources import|export      move sources between installations
+
+Run "interactive-scraper <command> -h" to see a command's flags.`
+
+/*Bu fonksiyon, uygulamanın giriş noktasıdır ve ilk argümana göre alt komutu seçer.
+Argüman verilmezse sunucu başlatılır; böylece mevcut kurulumlar değişiklik olmadan
+çalışmaya devam eder. Yapılandırma alt komuttan önce bir kez yüklenir ve doğrulanır;
+geçersiz bir ayar varsa tüm sorunlar listelenerek program durur. Diğer alt komutlar
+sunucuyla aynı binary ve aynı yapılandırmayla çalışır; betiklerde ve acil durum
+kurtarmada HTTP oturumu gerektirmez.
+*/
 func main() {
-	db, err := database.Connect()
+	command, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
+	}
+	if command == "help" || command == "-h" || command == "--help" {
+		fmt.Println(usage)
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	switch command {
+	case "serve":
+		runServe(cfg)
+	case "migrate":
+		runMigrate(cfg, args)
+	case "scrape":
+		// runScrape returns instead of exiting so its deferred SIEM flush and DB close run first
+		if err := runScrape(cfg, args); err != nil {
+			fmt.Fprintf(os.Stderr, "scrape: %v\n", err)
+			os.Exit(1)
+		}
+	case "user":
+		runUser(cfg, args)
+	case "export":
+		runExport(cfg, args)
+	case "sources":
+		runSources(cfg, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", command, usage)
+		os.Exit(2)
+	}
+}
+
+// shutdownTimeout bounds how long open requests and queued spans may delay the exit after SIGINT or SIGTERM
+const shutdownTimeout = 15 * time.Second
+
+/*Bu fonksiyon, "serve" komutunu çalıştırır: servisleri kurar, scraper ile zamanlanmış
+aramaları başlatır ve HTTP sunucusunu açar. SIGINT veya SIGTERM geldiğinde sunucu yeni
+bağlantı kabul etmeyi bırakır, açık istekler en fazla shutdownTimeout kadar beklenir ve
+ardından kuyruktaki span'ler collector'a gönderilir; SIEM emitter'ı ve veritabanı
+bağlantısı da ertelenmiş çağrılarla kapatılır.
+*/
+func runServe(cfg *config.Config) {
+	logging.Setup(cfg.Logging)
+	tracing.Setup(cfg.Tracing)
+	logger := logging.For("main")
+	if cfg.File() != "" {
+		logger.Info("configuration loaded", "file", cfg.File())
+	}
+
+	db, err := database.Connect(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	if err := database.InitSchema(db); err != nil {
+	if err := database.InitSchema(db, cfg.Database); err != nil {
 		log.Fatalf("Failed to initialize database schema: %v", err)
 	}
+	metrics.RegisterDBStats(db)
+
+	emitter, err := siem.NewEmitterFrom(cfg.SIEM)
+	if err != nil {
+		log.Fatalf("Failed to initialize SIEM output: %v", err)
+	}
+	defer emitter.Close()
+
+	recorder := audit.NewRecorder(db)
 
 	dataService := service.NewDataService(db)
-	authService := service.NewAuthService()
+	dataService.SetEmitter(emitter)
+	authConfig, err := service.AuthConfigFrom(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to load auth configuration: %v", err)
+	}
+	authService, err := service.NewAuthService(authConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize auth service: %v", err)
+	}
 	authService.SetDB(db)
 
-	scraperService := scraper.NewScraperService(db)
+	loginThrottle := service.NewLoginThrottle(service.ThrottleConfigFrom(cfg.Limits))
+
+	scraper.Configure(cfg.Tor)
+	scraper.ConfigureTransports(cfg.Transport)
+	go scraper.StartTorHealthChecks()
+	scraperService := scraper.NewScraperService(db, cfg.Scheduler, ai.NewAIService(cfg.AI))
+	scraperService.SetEmitter(emitter)
+	scraperService.SetRecorder(recorder)
 	go scraperService.Start()
 
-	router := api.SetupRouter(dataService, authService, scraperService)
+	savedSearchService := service.NewSavedSearchService(db, dataService)
+	savedSearchService.SetEmitter(emitter)
+	go savedSearchService.StartScheduler(cfg.Scheduler.SavedSearchInterval.Duration)
+
+	triageService := service.NewTriageService(db, dataService)
+	triageService.SetEmitter(emitter)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	tagService := service.NewTagService(db)
+	userService := service.NewUserService(db)
+	userService.SetPasswordPolicy(service.PasswordPolicyFrom(cfg.Auth))
+	apiKeyService := service.NewAPIKeyService(db)
+	oidcConfig, err := service.OIDCConfigFrom(cfg.OIDC)
+	if err != nil {
+		log.Fatalf("Failed to load OIDC configuration: %v", err)
 	}
+	oidcService := service.NewOIDCService(db, authService, oidcConfig)
+
+	router := api.SetupRouter(api.RouterDeps{
+		Data:        dataService,
+		Auth:        authService,
+		Throttle:    loginThrottle,
+		Scraper:     scraperService,
+		SavedSearch: savedSearchService,
+		Triage:      triageService,
+		Tags:        tagService,
+		Users:       userService,
+		APIKeys:     apiKeyService,
+		OIDC:        oidcService,
+		Recorder:    recorder,
+	}, cfg)
 
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Server.Port), Handler: router}
+	go func() {
+		logger.Info("server starting", "port", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	logger.Info("shutting down", "timeout", shutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("open requests did not finish before shutdown", logging.Err(err))
+	}
+	if err := tracing.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("failed to export queued spans", logging.Err(err))
 	}
 }
 
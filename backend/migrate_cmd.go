@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/database"
+)
+
+const migrateUsage = `usage: interactive-scraper migrate <command>
+
+commands:
+  up [N]      apply all pending migrations, or the next N
+  down [N]    roll back the last N applied migrations (default 1)
+  status      list migrations and whether they are applied`
+
+/*Bu fonksiyon, "migrate" alt komutunu çalıştırır. up bekleyen migration'ları uygular,
+down son uygulanan migration'ları geri alır, status ise her migration'ın durumunu
+listeler. Komut, sunucuyla aynı database ayarlarıyla veritabanına bağlanır.
+*/
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			log.Fatalf("invalid step count: %s", args[1])
+		}
+		steps = n
+	}
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(db, steps)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		if _, err := database.MigrateDown(db, steps); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range states {
+			status, appliedAt := "pending", ""
+			if s.Applied {
+				status = "applied"
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				status += " (modified)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+		}
+		w.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"interactive-scraper/internal/ai"
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/logging"
+	"interactive-scraper/internal/scraper"
+	"interactive-scraper/internal/service"
+	"interactive-scraper/internal/siem"
+	"interactive-scraper/internal/tracing"
+)
+
+/*Bu fonksiyon, "scrape" alt komutunu çalıştırır. --source ile verilen kaynak, sunucudaki
+periyodik taramayla aynı kod yoluyla ve senkron olarak taranır; scraper'ın ayrıntılı
+logları stderr'e akar, sonuç özeti stdout'a yazılır. Yeni kayıtlar için SIEM olayları ve
+denetim kaydı sunucudaki gibi üretilir; AI analizi açıksa komut analizler bitene kadar
+bekler. Tarama başarısız olursa hata döner ve main komutu 1 koduyla bitirir; çıkış,
+ertelenmiş kapanışlar çalıştıktan sonra yapıldığı için kuyruktaki SIEM olayları da
+gönderilir.
+*/
+func runScrape(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("scrape", flag.ExitOnError)
+	sourceID := flags.Int("source", 0, "ID of the source to scrape")
+	flags.Parse(args)
+	if *sourceID < 1 {
+		fmt.Fprintln(os.Stderr, "scrape: --source is required")
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	logging.Setup(cfg.Logging)
+	tracing.Setup(cfg.Tracing)
+	db := openDB(cfg)
+	defer db.Close()
+
+	source, err := service.NewSourceService(db).GetSourceByID(*sourceID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("source %d not found", *sourceID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load source %d: %w", *sourceID, err)
+	}
+
+	emitter, err := siem.NewEmitterFrom(cfg.SIEM)
+	if err != nil {
+		return fmt.Errorf("failed to initialize SIEM output: %w", err)
+	}
+	defer emitter.Close()
+
+	scraper.Configure(cfg.Tor)
+	scraper.ConfigureTransports(cfg.Transport)
+	scraperService := scraper.NewScraperService(db, cfg.Scheduler, ai.NewAIService(cfg.AI))
+	scraperService.SetEmitter(emitter)
+	scraperService.SetRecorder(audit.NewRecorder(db))
+
+	started := time.Now()
+	scraperService.ScrapeSource(context.Background(), source.ID)
+	scraperService.WaitForAnalyses()
+	flushSpans()
+
+	state := scraper.LastScrape(source.ID)
+	if state == nil {
+		return fmt.Errorf("scrape of source %d did not record a result", source.ID)
+	}
+	if state.Status != "completed" {
+		return fmt.Errorf("source %d (%s) failed after %s: %s", source.ID, source.Name,
+			time.Since(started).Round(time.Millisecond), state.Error)
+	}
+	fmt.Printf("Source %d (%s) scraped in %s: %d entries found, %d inserted, %d already known\n",
+		source.ID, source.Name, time.Since(started).Round(time.Millisecond),
+		state.EntriesFound, state.EntriesInserted, state.EntriesFound-state.EntriesInserted)
+	return nil
+}
+
+// flushSpans sends the run's spans before the command exits
+func flushSpans() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export trace spans: %v\n", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/service"
+)
+
+const sourcesUsage = `usage: interactive-scraper sources <command>
+
+commands:
+  export [FILE]    write all sources as a JSON list of {"name", "url", "transport", "proxy_url"} (default stdout)
+  import FILE      add the sources listed in FILE ("-" for stdin); URLs that already exist are skipped`
+
+/*Bu fonksiyon, "sources" alt komutunu çalıştırır. export tüm kaynakları ad, adres ve
+taşıma (transport) ayarlarını içeren taşınabilir bir JSON listesi olarak yazar; import aynı
+biçimdeki bir dosyayı okur ve adresi henüz kayıtlı olmayan kaynakları ekler; transport
+verilmeyen kayıtlar Tor üzerinden çekilir. Böylece kaynak listesi kurulumlar
+arasında taşınabilir veya sürüm kontrolünde tutulabilir.
+*/
+func runSources(cfg *config.Config, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		fatalUsage(sourcesUsage)
+	}
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	}
+
+	switch args[0] {
+	case "export":
+		db := openDB(cfg)
+		defer db.Close()
+
+		sources, err := service.NewSourceService(db).GetAllSources()
+		if err != nil {
+			log.Fatalf("Failed to list sources: %v", err)
+		}
+		specs := make([]service.SourceSpec, 0, len(sources))
+		for i := len(sources) - 1; i >= 0; i-- {
+			// GetAllSources is newest first; export in creation order so imports keep it
+			specs = append(specs, service.SourceSpec{Name: sources[i].Name, URL: sources[i].URL,
+				Transport: sources[i].Transport, ProxyURL: sources[i].RawProxyURL()})
+		}
+
+		out := openOutput(path)
+		err = printJSON(out, specs)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.Fatalf("Failed to write sources: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d sources\n", len(specs))
+
+	case "import":
+		if path == "" {
+			fatalUsage(sourcesUsage)
+		}
+		var in io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				log.Fatalf("Failed to open %s: %v", path, err)
+			}
+			defer f.Close()
+			in = f
+		}
+		var specs []service.SourceSpec
+		decoder := json.NewDecoder(in)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&specs); err != nil {
+			log.Fatalf("Failed to parse %s: expected a JSON list of {\"name\", \"url\"}: %v", path, err)
+		}
+
+		db := openDB(cfg)
+		defer db.Close()
+
+		created, skipped, err := service.NewSourceService(db).ImportSources(specs)
+		if err != nil {
+			log.Fatalf("Import failed, no sources were added: %v", err)
+		}
+		recorder := audit.NewRecorder(db)
+		for _, source := range created {
+			recordCLI(recorder, "source_import", audit.TargetSource, fmt.Sprint(source.ID),
+				map[string]interface{}{"name": source.Name, "url": source.URL, "transport": source.Transport})
+			fmt.Printf("Added source %d: %s (%s via %s)\n", source.ID, source.Name, source.URL, source.Transport)
+		}
+		fmt.Printf("Imported %d sources, skipped %d already present\n", len(created), skipped)
+
+	default:
+		fatalUsage(sourcesUsage)
+	}
+}
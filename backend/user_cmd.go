@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"interactive-scraper/internal/audit"
+	"interactive-scraper/internal/config"
+	"interactive-scraper/internal/service"
+)
+
+const userUsage = `usage: interactive-scraper user <command> [flags]
+
+commands:
+  create --username NAME [--role ROLE] [--password-stdin]
+        create a local account; without --password-stdin a random password is printed
+  reset-password --username NAME [--password-stdin] [--reset-2fa]
+        set a new password, sign the user out everywhere and require a change at next login
+  set-role --username NAME --role viewer|analyst|admin
+        change a user's role
+
+Roles: viewer, analyst, admin. New passwords must satisfy the PASSWORD_* policy.`
+
+/*Bu fonksiyon, "user" alt komutunu çalıştırır. Kullanıcı oluşturma, şifre sıfırlama ve rol
+değiştirme, API ile aynı servis fonksiyonları üzerinden yapılır; bu sayede şifre
+politikası, son admin koruması ve oturum iptali gibi kurallar komut satırında da geçerlidir.
+Şifre --password-stdin ile stdin'den okunur ya da rastgele üretilip bir kez yazdırılır;
+kullanıcıdan ilk girişte şifresini değiştirmesi istenir. Tüm değişiklikler denetim kaydına
+"cli" aktörüyle yazılır. Bu komut, tüm adminlerin hesabına erişemediği durumlarda acil
+kurtarma yolu olarak da kullanılır.
+*/
+func runUser(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		fatalUsage(userUsage)
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("user "+command, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, userUsage) }
+	username := flags.String("username", "", "account name")
+	role := flags.String("role", "", "viewer, analyst or admin")
+	passwordStdin := flags.Bool("password-stdin", false, "read the new password from the first line of stdin")
+	resetMFA := flags.Bool("reset-2fa", false, "also remove the user's two-factor enrollment (reset-password only)")
+	flags.Parse(args[1:])
+	if *username == "" {
+		fmt.Fprintln(os.Stderr, "user: --username is required")
+		fatalUsage(userUsage)
+	}
+
+	policy := service.PasswordPolicyFrom(cfg.Auth)
+
+	db := openDB(cfg)
+	defer db.Close()
+	userService := service.NewUserService(db)
+	userService.SetPasswordPolicy(policy)
+	recorder := audit.NewRecorder(db)
+
+	password := func() (string, bool) {
+		if *passwordStdin {
+			return readPasswordLine(), false
+		}
+		return generatePassword(policy, *username), true
+	}
+
+	switch command {
+	case "create":
+		pw, generated := password()
+		created, err := userService.CreateUser(service.CreateUserInput{Username: *username, Password: pw, Role: *role})
+		if err != nil {
+			log.Fatalf("Failed to create user: %v", err)
+		}
+		recordCLI(recorder, "user_create", audit.TargetUser, strconv.Itoa(created.ID),
+			map[string]interface{}{"username": created.Username, "role": created.Role})
+		fmt.Printf("Created %s user %s (id %d)\n", created.Role, created.Username, created.ID)
+		if generated {
+			fmt.Printf("Temporary password: %s\n", pw)
+		}
+
+	case "reset-password":
+		target := lookupUser(userService, *username)
+		if target.AuthProvider != "local" {
+			log.Fatalf("%s signs in with %s; the password is managed by the identity provider", target.Username, target.AuthProvider)
+		}
+		pw, generated := password()
+		if _, err := userService.UpdateUser(target.ID, service.UpdateUserInput{Password: &pw}); err != nil {
+			log.Fatalf("Failed to reset password: %v", err)
+		}
+		if *resetMFA {
+			authConfig, err := service.AuthConfigFrom(cfg.Auth)
+			if err != nil {
+				log.Fatalf("Failed to load auth configuration: %v", err)
+			}
+			authService, err := service.NewAuthService(authConfig)
+			if err != nil {
+				log.Fatalf("Failed to initialize auth service: %v", err)
+			}
+			authService.SetDB(db)
+			if err := authService.ResetMFA(target.ID); err != nil {
+				log.Fatalf("Password was reset but removing two-factor authentication failed: %v", err)
+			}
+		}
+		recordCLI(recorder, "user_reset_password", audit.TargetUser, strconv.Itoa(target.ID),
+			map[string]interface{}{"username": target.Username, "reset_2fa": *resetMFA})
+		fmt.Printf("Password of %s reset; all sessions were signed out and a change is required at next login\n", target.Username)
+		if *resetMFA {
+			fmt.Println("Two-factor authentication was removed")
+		}
+		if generated {
+			fmt.Printf("Temporary password: %s\n", pw)
+		}
+
+	case "set-role":
+		if *role == "" {
+			fmt.Fprintln(os.Stderr, "user set-role: --role is required")
+			fatalUsage(userUsage)
+		}
+		target := lookupUser(userService, *username)
+		updated, err := userService.UpdateUser(target.ID, service.UpdateUserInput{Role: role})
+		if err != nil {
+			log.Fatalf("Failed to change role: %v", err)
+		}
+		recordCLI(recorder, "user_set_role", audit.TargetUser, strconv.Itoa(target.ID),
+			map[string]interface{}{"username": target.Username, "old_role": target.Role, "role": updated.Role})
+		fmt.Printf("%s is now %s (was %s)\n", updated.Username, updated.Role, target.Role)
+
+	default:
+		fatalUsage(userUsage)
+	}
+}
+
+func lookupUser(userService *service.UserService, username string) *service.User {
+	user, err := userService.GetUserByUsername(username)
+	if errors.Is(err, service.ErrUserNotFound) {
+		log.Fatalf("user %s not found", username)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load user %s: %v", username, err)
+	}
+	return user
+}
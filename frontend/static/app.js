@@ -3,6 +3,10 @@ let currentPage = 1;
 const pageSize = 20;
 let currentEntryId = null;
 let authToken = null;
+let refreshToken = null;
+let refreshTimer = null;
+let passwordChangePending = false;
+let mfaSetupPending = false;
 let currentTab = 'overview';
 let entriesTabPage = 1;
 let criticalityTabPage = 1;
@@ -133,8 +137,16 @@ function initMatrixRain() {
     }
 }
 
-document.addEventListener('DOMContentLoaded', () => {
+document.addEventListener('DOMContentLoaded', async () => {
     authToken = localStorage.getItem('authToken');
+    refreshToken = localStorage.getItem('refreshToken');
+    if (!(await completeSSOLogin()) && refreshToken) {
+        await refreshAuthToken();
+    }
+    if (passwordChangePending || mfaSetupPending) {
+        // The password change and 2FA setup are asked for at login, so start over
+        clearTokens();
+    }
     if (authToken) {
         showDashboard();
         loadDashboard();
@@ -172,6 +184,7 @@ document.addEventListener('DOMContentLoaded', () => {
 
 function setupEventListeners() {
     document.getElementById('loginForm').addEventListener('submit', handleLogin);
+    setupSSOButton();
     
     document.getElementById('logoutBtn').addEventListener('click', handleLogout);
     
@@ -268,6 +281,7 @@ function setupEventListeners() {
     document.getElementById('closeSourceModal').addEventListener('click', closeSourceModal);
     document.getElementById('cancelSource').addEventListener('click', closeSourceModal);
     document.getElementById('saveSource').addEventListener('click', saveSource);
+    document.getElementById('sourceTransport').addEventListener('change', updateSourceProxyField);
     
     document.getElementById('chatToggleBtn').addEventListener('click', toggleChatPanel);
     document.getElementById('chatSendBtn').addEventListener('click', sendChatMessage);
@@ -336,15 +350,31 @@ async function handleLogin(e) {
             body: JSON.stringify({ username, password }),
         });
         
-        const data = await response.json();
+        let data = await response.json();
         
         if (!response.ok) {
             errorDiv.textContent = data.error || 'Login failed';
             return;
         }
         
-        authToken = data.token;
-        localStorage.setItem('authToken', authToken);
+        if (data.mfa_required) {
+            data = await promptMFACode(data.mfa_token);
+            if (!data) {
+                errorDiv.textContent = 'Two-factor authentication failed.';
+                return;
+            }
+        }
+        storeTokens(data);
+        if (data.password_change_required && !(await promptPasswordChange(password))) {
+            handleLogout();
+            errorDiv.textContent = 'You must change your password before continuing.';
+            return;
+        }
+        if (mfaSetupPending && !(await promptMFAEnrollment())) {
+            handleLogout();
+            errorDiv.textContent = 'You must set up two-factor authentication before continuing.';
+            return;
+        }
         showDashboard();
         loadDashboard();
     } catch (error) {
@@ -352,9 +382,204 @@ async function handleLogin(e) {
     }
 }
 
-function handleLogout() {
+// Shows the SSO button when the server has an identity provider configured
+async function setupSSOButton() {
+    try {
+        const response = await fetch(`${API_BASE}/auth/oidc`);
+        const config = await response.json();
+        if (!config.enabled) {
+            return;
+        }
+        const button = document.getElementById('ssoLoginButton');
+        button.textContent = `Sign in with ${config.provider_name}`;
+        button.style.display = '';
+        button.addEventListener('click', () => {
+            window.location.href = config.login_url;
+        });
+    } catch (error) {
+        console.error('Failed to load SSO configuration:', error);
+    }
+}
+
+// Finishes an SSO login that came back as #sso_code=... or #sso_error=...; returns true if tokens were stored
+async function completeSSOLogin() {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const code = params.get('sso_code');
+    const error = params.get('sso_error');
+    if (!code && !error) {
+        return false;
+    }
+    history.replaceState(null, '', window.location.pathname);
+
+    const errorDiv = document.getElementById('loginError');
+    if (error) {
+        errorDiv.textContent = error;
+        return false;
+    }
+    try {
+        const response = await fetch(`${API_BASE}/auth/oidc/exchange`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ code }),
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            errorDiv.textContent = data.error || 'Single sign-on failed';
+            return false;
+        }
+        storeTokens(data);
+        return true;
+    } catch (err) {
+        errorDiv.textContent = 'Connection error. Please try again.';
+        return false;
+    }
+}
+
+// Second login step; returns the token response or null if the user cancels
+async function promptMFACode(mfaToken) {
+    let message = 'Enter the 6-digit code from your authenticator app (or a recovery code):';
+    while (true) {
+        const code = window.prompt(message);
+        if (code === null) {
+            return null;
+        }
+        const response = await fetch(`${API_BASE}/login/verify`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ mfa_token: mfaToken, code }),
+        });
+        const data = await response.json();
+        if (response.ok) {
+            return data;
+        }
+        if (response.status === 429 || data.error?.includes('token')) {
+            window.alert(data.error);
+            return null;
+        }
+        message = `${data.error}\nEnter the code again:`;
+    }
+}
+
+// Walks through required TOTP enrollment; returns false if the user cancels
+async function promptMFAEnrollment() {
+    const enrollResponse = await fetch(`${API_BASE}/users/me/2fa/enroll`, {
+        method: 'POST',
+        headers: getAuthHeaders(),
+    });
+    const enrollment = await enrollResponse.json();
+    if (!enrollResponse.ok) {
+        window.alert(enrollment.error || 'Could not start two-factor enrollment');
+        return false;
+    }
+
+    let message = 'Two-factor authentication is required.\n' +
+        `Add this key to your authenticator app: ${enrollment.secret}\n` +
+        `(or open: ${enrollment.provisioning_uri})\n\nThen enter the 6-digit code:`;
+    while (true) {
+        const code = window.prompt(message);
+        if (code === null) {
+            return false;
+        }
+        const response = await fetch(`${API_BASE}/users/me/2fa/verify`, {
+            method: 'POST',
+            headers: getAuthHeaders(),
+            body: JSON.stringify({ code }),
+        });
+        const data = await response.json();
+        if (response.ok) {
+            window.alert('Save these recovery codes, they are shown only once:\n\n' + data.recovery_codes.join('\n'));
+            if (data.session) {
+                storeTokens(data.session);
+            }
+            return true;
+        }
+        message = `${data.error}\nEnter the code again:`;
+    }
+}
+
+// Asks for a new password until the change succeeds; returns false if the user cancels
+async function promptPasswordChange(currentPassword) {
+    let message = 'Your password must be changed before continuing.\nNew password:';
+    while (true) {
+        const newPassword = window.prompt(message);
+        if (newPassword === null) {
+            return false;
+        }
+        if (window.prompt('Repeat the new password:') !== newPassword) {
+            message = 'Passwords did not match.\nNew password:';
+            continue;
+        }
+        const response = await fetch(`${API_BASE}/users/me/password`, {
+            method: 'POST',
+            headers: getAuthHeaders(),
+            body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+        });
+        const data = await response.json();
+        if (response.ok) {
+            if (data.token) {
+                storeTokens(data);
+            }
+            return true;
+        }
+        message = `${data.error || 'Password change failed'}\nNew password:`;
+    }
+}
+
+function storeTokens(data) {
+    authToken = data.token;
+    refreshToken = data.refresh_token;
+    localStorage.setItem('authToken', authToken);
+    localStorage.setItem('refreshToken', refreshToken);
+    passwordChangePending = Boolean(data.password_change_required);
+    mfaSetupPending = Boolean(data.mfa_setup_required);
+
+    // Refresh one minute before the access token expires
+    clearTimeout(refreshTimer);
+    const delay = Math.max((data.expires_in - 60) * 1000, 30000);
+    refreshTimer = setTimeout(refreshAuthToken, delay);
+}
+
+function clearTokens() {
+    clearTimeout(refreshTimer);
     authToken = null;
+    refreshToken = null;
     localStorage.removeItem('authToken');
+    localStorage.removeItem('refreshToken');
+}
+
+async function refreshAuthToken() {
+    if (!refreshToken) {
+        return false;
+    }
+    try {
+        const response = await fetch(`${API_BASE}/token/refresh`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+        if (!response.ok) {
+            if (response.status === 401) {
+                clearTokens();
+            }
+            return false;
+        }
+        storeTokens(await response.json());
+        return true;
+    } catch (error) {
+        console.error('[AUTH] Token refresh failed:', error);
+        return false;
+    }
+}
+
+function handleLogout() {
+    if (refreshToken || authToken) {
+        fetch(`${API_BASE}/logout`, {
+            method: 'POST',
+            headers: getAuthHeaders(),
+            body: JSON.stringify({ refresh_token: refreshToken }),
+        }).catch(() => {});
+    }
+    clearTokens();
     showLogin();
     document.getElementById('loginForm').reset();
 }
@@ -1526,6 +1751,8 @@ async function displaySources(stats) {
         
         const data = await response.json();
         const sources = data.sources || [];
+        loadedSources = {};
+        sources.forEach(source => { loadedSources[source.id] = source; });
         
         const grid = document.getElementById('sourcesGrid');
         document.getElementById('sourcesCount').textContent = `${sources.length} active sources`;
@@ -1579,12 +1806,16 @@ async function displaySources(stats) {
                         <span>📅</span>
                         <span>Added: ${formatDate(source.created_at)}</span>
                     </div>
+                    <div class="source-stat">
+                        <span>🔀</span>
+                        <span>Via: ${escapeHtml(SOURCE_TRANSPORT_LABELS[source.transport] || source.transport || 'Tor')}</span>
+                    </div>
                 </div>
                 <div class="source-actions" style="margin-top: 12px; display: flex; gap: 8px; flex-wrap: wrap;">
                     <button class="category-btn scrape-btn" onclick="scrapeSource(${source.id})" ${buttonState} style="background: var(--primary-color); color: white; border: none;">
                         🔄 Tarama
                     </button>
-                    <button class="category-btn" onclick="editSource(${source.id}, '${escapeHtml(source.name)}', '${escapeHtml(source.url)}')">
+                    <button class="category-btn" onclick="editSource(${source.id})">
                         Edit
                     </button>
                     <button class="category-btn" onclick="deleteSource(${source.id})" style="background: var(--danger); color: white; border: none;">
@@ -2134,8 +2365,12 @@ async function updateAIBadge() {
 
 // Source Management Functions
 let currentSourceId = null;
+// Sources from the last load, by id; the edit form reads them instead of inlined strings
+let loadedSources = {};
+
+const SOURCE_TRANSPORT_LABELS = { tor: 'Tor', i2p: 'I2P', proxy: 'Proxy', direct: 'Direct' };
 
-function openSourceModal(sourceId = null, name = '', url = '') {
+function openSourceModal(sourceId = null, name = '', url = '', transport = 'tor', proxyURL = '') {
     currentSourceId = sourceId;
     const modal = document.getElementById('sourceModal');
     const title = document.getElementById('sourceModalTitle');
@@ -2152,11 +2387,20 @@ function openSourceModal(sourceId = null, name = '', url = '') {
         nameInput.value = '';
         urlInput.value = '';
     }
+    document.getElementById('sourceTransport').value = transport;
+    document.getElementById('sourceProxyURL').value = proxyURL;
+    updateSourceProxyField();
     
     errorDiv.textContent = '';
     modal.classList.remove('hidden');
 }
 
+// The proxy URL is only asked for when the source uses its own proxy
+function updateSourceProxyField() {
+    const useProxy = document.getElementById('sourceTransport').value === 'proxy';
+    document.getElementById('sourceProxyGroup').classList.toggle('hidden', !useProxy);
+}
+
 function closeSourceModal() {
     document.getElementById('sourceModal').classList.add('hidden');
     currentSourceId = null;
@@ -2167,11 +2411,13 @@ function closeSourceModal() {
 async function saveSource() {
     const name = document.getElementById('sourceName').value.trim();
     const url = document.getElementById('sourceURL').value.trim();
+    const transport = document.getElementById('sourceTransport').value;
+    const proxy_url = transport === 'proxy' ? document.getElementById('sourceProxyURL').value.trim() : '';
     const errorDiv = document.getElementById('sourceError');
     
     errorDiv.textContent = '';
     
-    if (!name || !url) {
+    if (!name || !url || (transport === 'proxy' && !proxy_url)) {
         errorDiv.textContent = 'Please fill in all fields';
         return;
     }
@@ -2189,7 +2435,7 @@ async function saveSource() {
         const response = await fetch(apiUrl, {
             method: method,
             headers: getAuthHeaders(),
-            body: JSON.stringify({ name, url }),
+            body: JSON.stringify({ name, url, transport, proxy_url }),
         });
         
         const data = await response.json();
@@ -2207,8 +2453,10 @@ async function saveSource() {
     }
 }
 
-function editSource(id, name, url) {
-    openSourceModal(id, name, url);
+function editSource(id) {
+    const source = loadedSources[id];
+    if (!source) return;
+    openSourceModal(id, source.name, source.url, source.transport || 'tor', source.proxy_url || '');
 }
 
 async function deleteSource(id) {